@@ -0,0 +1,97 @@
+// Command import creates and updates products from a CSV or XLSX
+// spreadsheet, the same way POST /product/import does.
+//
+//	go run ./cmd/import -map name=Product -map price=Preço -dry-run prices.xlsx
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nimbo1999/go-apis-go-expert/configs"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/importer"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/spreadsheet"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// pairs collects the values of a flag given more than once.
+type pairs []string
+
+func (p *pairs) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pairs) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+func main() {
+	var mapping pairs
+	flag.Var(&mapping, "map", "column a field is read from, as field=Column (repeatable)")
+	dryRun := flag.Bool("dry-run", false, "check every row without importing anything")
+	currency := flag.String("currency", "", "currency of prices when there is no currency column (DEFAULT_CURRENCY by default)")
+	actor := flag.String("actor", "import", "who the changes are recorded as made by")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file.csv|file.xlsx\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), mapping, *dryRun, *currency, *actor); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, mapping []string, dryRun bool, currency, actor string) error {
+	config, err := configs.LoadConfig("./")
+	if err != nil {
+		return err
+	}
+	options := importer.Options{Currency: config.DefaultCurrency, DryRun: dryRun, Source: entity.AuditSource{Actor: actor}}
+	if currency != "" {
+		options.Currency = strings.ToUpper(currency)
+	}
+	if options.Mapping, err = importer.ParseMapping(mapping); err != nil {
+		return err
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	contentType := spreadsheet.CSVType
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		contentType = spreadsheet.XLSXType
+	}
+	rows, err := spreadsheet.Read(body, contentType)
+	if err != nil {
+		return err
+	}
+	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	if err = database.Migrate(db, config.DefaultCurrency); err != nil {
+		return err
+	}
+	report, importErr := importer.NewImporter(database.NewProduct(db)).Import(rows, options)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(report); err != nil {
+			return err
+		}
+	}
+	return importErr
+}
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Nimbo1999/go-apis-go-expert/configs"
 	_ "github.com/Nimbo1999/go-apis-go-expert/docs"
 	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
 	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/events"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
 	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/webserver/handlers"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/webserver/middlewares"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/worker"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth"
+	"github.com/nats-io/nats.go"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -43,32 +51,323 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	db.AutoMigrate(&entity.Product{}, &entity.User{})
+	if err = database.Migrate(db, config.DefaultCurrency); err != nil {
+		panic(err)
+	}
 
 	productDB := database.NewProduct(db)
 	userDB := database.NewUser(db)
-	productHandler := handlers.NewProductHandler(productDB)
+	clientDB := database.NewClient(db)
+	categoryDB := database.NewCategory(db)
+	tagDB := database.NewTag(db)
+	stockMovementDB := database.NewStockMovement(db)
+	exchangeRateDB := database.NewExchangeRate(db)
+	priceChangeDB := database.NewPriceChange(db)
+	scheduledPriceDB := database.NewScheduledPrice(db)
+	variantDB := database.NewVariant(db)
+	attributeDefinitionDB := database.NewAttributeDefinition(db)
+	productImageDB := database.NewProductImage(db)
+	productStatusChangeDB := database.NewProductStatusChange(db)
+	jobDB := database.NewJob(db)
+	idempotencyKeyDB := database.NewIdempotencyKey(db)
+	auditDB := database.NewAudit(db)
+	eventDB := database.NewEvent(db)
+	webhookDB := database.NewWebhook(db)
+	webhookDeliveryDB := database.NewWebhookDelivery(db)
+	var store storage.Storage
+	switch config.StorageDriver {
+	case "local":
+		store = storage.NewLocal(config.StorageDir, config.StorageBaseURL)
+	case "s3":
+		store, err = storage.NewS3(config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKey, config.S3SecretKey, config.S3PublicURL)
+		if err != nil {
+			panic(err)
+		}
+	default:
+		panic(fmt.Sprintf("unknown storage driver %q", config.StorageDriver))
+	}
+	bus := events.NewBus()
+	// Each consumer of the outbox is relayed to on its own.
+	consumers := map[string]events.Publisher{"bus": bus}
+	switch config.EventPublisher {
+	case "inprocess":
+	case "nats":
+		var options []nats.Option
+		if config.NATSCredentials != "" {
+			options = append(options, nats.UserCredentials(config.NATSCredentials))
+		}
+		if config.NATSRootCA != "" {
+			options = append(options, nats.RootCAs(config.NATSRootCA))
+		}
+		natsPublisher, err := events.NewNATS(config.NATSURL, config.NATSSubject, 10*time.Second, options...)
+		if err != nil {
+			panic(err)
+		}
+		consumers["nats"] = natsPublisher
+	default:
+		panic(fmt.Sprintf("unknown event publisher %q", config.EventPublisher))
+	}
+	productEvents := events.NewBuffer(config.ProductStreamBuffer, entity.EventProductCreated, entity.EventProductUpdated, entity.EventProductDeleted)
+	bus.Subscribe(productEvents.Add)
+	webhookSender := worker.NewWebhookSender(
+		webhookDB,
+		webhookDeliveryDB,
+		config.WebhookTimeout,
+		config.WebhookMaxAttempts,
+		config.WebhookBackoff,
+		config.WebhookMaxBackoff,
+		config.WebhookPollInterval,
+		config.WebhookDeliveryRetention,
+		config.WebhookAllowPrivateNetworks,
+	)
+	consumers["webhooks"] = webhookSender
+	if err = ensureAdmin(userDB, config.AdminEmail, config.AdminPassword); err != nil {
+		panic(err)
+	}
+	productHandler := handlers.NewProductHandler(productDB, categoryDB, exchangeRateDB, attributeDefinitionDB, store, !config.IfMatchOptional)
+	productStreamHandler := handlers.NewProductStreamHandler(productHandler, productEvents)
+	liveHandler := handlers.NewLiveHandler(productDB, categoryDB, productEvents, config.WSMaxSubscriptions, config.WSPingInterval)
+	categoryHandler := handlers.NewCategoryHandler(categoryDB)
+	tagHandler := handlers.NewTagHandler(tagDB)
+	stockHandler := handlers.NewStockHandler(productDB, stockMovementDB)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateDB)
+	priceHandler := handlers.NewPriceHandler(productDB, priceChangeDB, scheduledPriceDB)
+	variantHandler := handlers.NewVariantHandler(productDB, variantDB)
+	attributeHandler := handlers.NewAttributeHandler(attributeDefinitionDB)
+	imageHandler := handlers.NewImageHandler(productDB, productImageDB, store, config.ImageMaxSize)
+	statusHandler := handlers.NewStatusHandler(productDB, productStatusChangeDB, store)
+	importHandler := handlers.NewImportHandler(productDB, config.ImportMaxSize, config.DefaultCurrency)
+	jobFiles := storage.NewFileStore(config.JobDir)
+	jobRunner := worker.NewJobRunner(jobDB, productDB, jobFiles, store, config.JobWorkers, config.JobPollInterval, config.JobRetention)
+	jobHandler := handlers.NewJobHandler(jobDB, jobFiles, jobRunner, productHandler, config.JobImportMaxSize, config.DefaultCurrency)
+	auditHandler := handlers.NewAuditHandler(auditDB)
+	webhookHandler := handlers.NewWebhookHandler(webhookDB, webhookDeliveryDB, webhookSender)
+
+	go worker.NewPriceScheduler(scheduledPriceDB, config.PriceSchedulerInterval).Run(context.Background())
+	go worker.NewTrashPurger(productDB, store, config.TrashRetention, config.TrashPurgeInterval).Run(context.Background())
+	go jobRunner.Run(context.Background())
+	go worker.NewIdempotencyKeyPurger(idempotencyKeyDB, time.Hour).Run(context.Background())
+	go worker.NewOutboxRelay(eventDB, consumers, config.OutboxPollInterval, config.OutboxRetention).Run(context.Background())
+	go webhookSender.Run(context.Background())
 	userHandler := handlers.NewUserHandler(userDB, config.TokenAuth, config.JWTExpiresIn)
+	oauthHandler := handlers.NewOAuthHandler(clientDB, config.TokenAuth, config.JWTExpiresIn)
 
 	r := chi.NewRouter()
 	// r.Use(LogRequest)
-	r.Use(middleware.Logger)
+	r.Use(middleware.RequestID)
+	// Keep the tokens sent in the query to /ws out of the logs.
+	r.Use(middlewares.RedactQuery(middleware.Logger, "jwt"))
 	r.Use(middleware.SetHeader("Content-Type", "application/json"))
 	r.Use(middleware.Recoverer)
 
+	// Idempotency runs after the scopes and roles are checked, so refusals
+	// aren't replayed.
+	idempotency := middlewares.Idempotency(idempotencyKeyDB, config.IdempotencyKeyTTL, config.IdempotencyMaxBodySize)
+
 	r.Route("/product", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(config.TokenAuth))
 		r.Use(jwtauth.Authenticator)
-		r.Post("/", productHandler.Create)
-		r.Get("/{id}", productHandler.GetProduct)
-		r.Get("/", productHandler.GetProducts)
-		r.Put("/{id}", productHandler.UpdateProduct)
-		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScope(entity.ScopeProductsRead))
+			r.Get("/{id}", productHandler.GetProduct)
+			r.Get("/", productHandler.GetProducts)
+			r.Get("/stream", productStreamHandler.StreamProducts)
+			r.Get("/export", productHandler.ExportProducts)
+			r.Get("/{id}/stock/movements", stockHandler.GetMovements)
+			r.Get("/{id}/prices", priceHandler.GetPriceHistory)
+			r.Get("/{id}/scheduled-prices", priceHandler.GetScheduledPrices)
+			r.Get("/{id}/variants", variantHandler.GetVariants)
+			r.Get("/{id}/variants/{variantId}", variantHandler.GetVariant)
+			r.Get("/{id}/images", imageHandler.GetImages)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScope(entity.ScopeProductsWrite))
+			r.Use(idempotency)
+			r.Post("/", productHandler.Create)
+			r.Post("/batch", productHandler.Batch)
+			r.Post("/import", importHandler.ImportProducts)
+			r.Put("/{id}", productHandler.UpdateProduct)
+			r.Patch("/{id}", productHandler.PatchProduct)
+			r.Delete("/{id}", productHandler.DeleteProduct)
+			r.Post("/{id}/tags", productHandler.AddTags)
+			r.Delete("/{id}/tags/{tag}", productHandler.RemoveTag)
+			r.Put("/{id}/prices/{currency}", productHandler.SetPrice)
+			r.Delete("/{id}/prices/{currency}", productHandler.DeletePrice)
+			r.Post("/{id}/scheduled-prices", priceHandler.CreateScheduledPrice)
+			r.Delete("/{id}/scheduled-prices/{scheduleId}", priceHandler.CancelScheduledPrice)
+			r.Post("/{id}/variants", variantHandler.Create)
+			r.Put("/{id}/variants/{variantId}", variantHandler.UpdateVariant)
+			r.Delete("/{id}/variants/{variantId}", variantHandler.DeleteVariant)
+			r.Post("/{id}/stock/movements", stockHandler.CreateMovement)
+			r.Post("/{id}/images", imageHandler.Upload)
+			r.Put("/{id}/images/order", imageHandler.ReorderImages)
+			r.Delete("/{id}/images/{imageId}", imageHandler.DeleteImage)
+			r.Post("/{id}/publish", statusHandler.Publish)
+			r.Post("/{id}/unpublish", statusHandler.Unpublish)
+			r.Post("/{id}/archive", statusHandler.Archive)
+			r.Post("/{id}/unarchive", statusHandler.Unarchive)
+			r.Post("/{id}/restore", productHandler.RestoreProduct)
+			r.Get("/{id}/status-history", statusHandler.GetStatusHistory)
+		})
+	})
+
+	r.Route("/category", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScope(entity.ScopeProductsRead))
+			r.Get("/", categoryHandler.GetCategories)
+			r.Get("/tree", categoryHandler.GetCategoryTree)
+			r.Get("/{id}", categoryHandler.GetCategory)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScope(entity.ScopeProductsWrite))
+			r.Use(idempotency)
+			r.Post("/", categoryHandler.Create)
+			r.Put("/{id}", categoryHandler.UpdateCategory)
+			r.Delete("/{id}", categoryHandler.DeleteCategory)
+		})
+	})
+
+	r.Route("/attributes", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScope(entity.ScopeProductsRead))
+			r.Get("/", attributeHandler.GetAttributes)
+			r.Get("/{code}", attributeHandler.GetAttribute)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireRole(entity.RoleAdmin))
+			r.Use(idempotency)
+			r.Post("/", attributeHandler.Create)
+			r.Put("/{code}", attributeHandler.UpdateAttribute)
+			r.Delete("/{code}", attributeHandler.DeleteAttribute)
+		})
+	})
+
+	r.Route("/tags", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireScope(entity.ScopeProductsRead))
+		r.Get("/", tagHandler.GetTags)
+	})
+
+	r.Route("/jobs", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Get("/{id}", jobHandler.GetJob)
+		r.Get("/{id}/result", jobHandler.GetJobResult)
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScope(entity.ScopeProductsRead))
+			r.Use(idempotency)
+			r.Post("/product-export", jobHandler.CreateExportJob)
+		})
+		// Spreadsheets up to JOB_IMPORT_MAX_SIZE are streamed to disk, so
+		// they aren't buffered whole by the idempotency middleware, which
+		// caps bodies at IDEMPOTENCY_MAX_BODY_SIZE.
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScope(entity.ScopeProductsWrite))
+			r.Post("/product-import", jobHandler.CreateImportJob)
+		})
+	})
+
+	r.Group(func(r chi.Router) {
+		// Browsers can't set headers on WebSocket requests, so the token
+		// can be sent in the jwt query parameter as well.
+		r.Use(jwtauth.Verify(config.TokenAuth, jwtauth.TokenFromHeader, jwtauth.TokenFromQuery))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireScope(entity.ScopeProductsRead))
+		r.Get("/ws", liveHandler.Connect)
 	})
 
 	r.Post("/user", userHandler.Create)
 	r.Post("/user/login", userHandler.Login)
 
+	r.Post("/oauth/token", oauthHandler.Token)
+	r.Route("/oauth/clients", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireRole(entity.RoleAdmin))
+		r.Use(idempotency)
+		r.Post("/", oauthHandler.CreateClient)
+	})
+
+	r.Route("/admin/users", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireRole(entity.RoleAdmin))
+		r.Use(idempotency)
+		r.Get("/", userHandler.GetUsers)
+		r.Get("/{id}", userHandler.GetUser)
+		r.Post("/{id}/disable", userHandler.DisableUser)
+		r.Post("/{id}/enable", userHandler.EnableUser)
+		r.Put("/{id}/role", userHandler.ChangeUserRole)
+		r.Delete("/{id}", userHandler.DeleteUser)
+	})
+
+	r.Route("/audit", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireRole(entity.RoleAdmin))
+		r.Get("/", auditHandler.GetAuditLog)
+		r.Get("/verify", auditHandler.VerifyAuditLog)
+	})
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireRole(entity.RoleAdmin))
+		r.Use(idempotency)
+		r.Post("/", webhookHandler.Create)
+		r.Get("/", webhookHandler.GetWebhooks)
+		r.Get("/{id}", webhookHandler.GetWebhook)
+		r.Put("/{id}", webhookHandler.UpdateWebhook)
+		r.Delete("/{id}", webhookHandler.DeleteWebhook)
+		r.Post("/{id}/ping", webhookHandler.Ping)
+		r.Get("/{id}/deliveries", webhookHandler.GetDeliveries)
+		r.Post("/{id}/deliveries/{deliveryId}/replay", webhookHandler.ReplayDelivery)
+	})
+
+	r.Route("/admin/trash", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireRole(entity.RoleAdmin))
+		r.Get("/", productHandler.GetTrash)
+	})
+
+	r.Route("/admin/exchange-rates", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.ActiveUser(userDB))
+		r.Use(middlewares.RequireRole(entity.RoleAdmin))
+		r.Use(idempotency)
+		r.Get("/", exchangeRateHandler.GetExchangeRates)
+		r.Post("/import", exchangeRateHandler.ImportExchangeRates)
+		r.Put("/{base}/{quote}", exchangeRateHandler.SetExchangeRate)
+		r.Delete("/{base}/{quote}", exchangeRateHandler.DeleteExchangeRate)
+	})
+
+	if config.StorageDriver == "local" {
+		files := http.StripPrefix("/uploads/", http.FileServer(http.Dir(config.StorageDir)))
+		r.Get("/uploads/*", func(w http.ResponseWriter, r *http.Request) {
+			// Let the file server tell the type of the file instead of JSON.
+			w.Header().Del("Content-Type")
+			files.ServeHTTP(w, r)
+		})
+	}
+
 	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8000/docs/doc.json")))
 
 	if err = http.ListenAndServe(fmt.Sprintf(":%s", config.WebServerPort), r); err != nil {
@@ -76,6 +375,32 @@ func main() {
 	}
 }
 
+// ensureAdmin makes sure the account configured through ADMIN_EMAIL exists and
+// holds the admin role, creating it with ADMIN_PASSWORD when needed.
+func ensureAdmin(userDB database.UserInterface, email, password string) error {
+	if email == "" {
+		return nil
+	}
+	user, err := userDB.FindByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user, err = entity.NewUser("Administrator", email, password)
+		if err != nil {
+			return err
+		}
+		user.Role = entity.RoleAdmin
+		return userDB.Create(user)
+	}
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin() && !user.Disabled {
+		return nil
+	}
+	user.Role = entity.RoleAdmin
+	user.Enable()
+	return userDB.Update(user)
+}
+
 // Creating a middleware
 // func LogRequest(next http.Handler) http.Handler {
 // 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1,6 +1,8 @@
 package configs
 
 import (
+	"time"
+
 	"github.com/go-chi/jwtauth"
 	"github.com/spf13/viper"
 )
@@ -17,7 +19,99 @@ type conf struct {
 	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
 	JWTSecret     string `mapstructure:"JWT_SECRET"`
 	JWTExpiresIn  int    `mapstructure:"JWT_EXPIRES_IN"`
-	TokenAuth     *jwtauth.JWTAuth
+	AdminEmail    string `mapstructure:"ADMIN_EMAIL"`
+	AdminPassword string `mapstructure:"ADMIN_PASSWORD"`
+	// DefaultCurrency is used to convert prices stored before products had a currency.
+	DefaultCurrency string `mapstructure:"DEFAULT_CURRENCY"`
+	// PriceSchedulerInterval is how often scheduled prices are applied, e.g. "30s".
+	PriceSchedulerInterval time.Duration `mapstructure:"PRICE_SCHEDULER_INTERVAL"`
+	// StorageDriver picks where uploaded images are kept: "local" (default)
+	// or "s3" for any S3 compatible object storage.
+	StorageDriver  string `mapstructure:"STORAGE_DRIVER"`
+	StorageDir     string `mapstructure:"STORAGE_DIR"`
+	StorageBaseURL string `mapstructure:"STORAGE_BASE_URL"`
+	S3Endpoint     string `mapstructure:"S3_ENDPOINT"`
+	S3Region       string `mapstructure:"S3_REGION"`
+	S3Bucket       string `mapstructure:"S3_BUCKET"`
+	S3AccessKey    string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey    string `mapstructure:"S3_SECRET_KEY"`
+	S3PublicURL    string `mapstructure:"S3_PUBLIC_URL"`
+	// TrashRetention is how long deleted products can be restored before
+	// they are purged, e.g. "720h", and TrashPurgeInterval how often expired
+	// ones are looked for.
+	TrashRetention     time.Duration `mapstructure:"TRASH_RETENTION"`
+	TrashPurgeInterval time.Duration `mapstructure:"TRASH_PURGE_INTERVAL"`
+	// IfMatchOptional lets clients update and delete products without sending
+	// the ETag they read in If-Match, overwriting concurrent changes.
+	IfMatchOptional bool `mapstructure:"IF_MATCH_OPTIONAL"`
+	// ImageMaxSize is the largest image upload accepted, in bytes.
+	ImageMaxSize int64 `mapstructure:"IMAGE_MAX_SIZE"`
+	// ImportMaxSize is the largest spreadsheet of products accepted, in bytes.
+	ImportMaxSize int64 `mapstructure:"IMPORT_MAX_SIZE"`
+	// JobWorkers is how many background jobs run at once, and JobDir the
+	// directory their files are kept in.
+	JobWorkers int    `mapstructure:"JOB_WORKERS"`
+	JobDir     string `mapstructure:"JOB_DIR"`
+	// JobPollInterval is how often workers look for queued jobs they weren't
+	// told about, e.g. "5s", and JobRetention how long finished jobs and
+	// their results are kept.
+	JobPollInterval time.Duration `mapstructure:"JOB_POLL_INTERVAL"`
+	JobRetention    time.Duration `mapstructure:"JOB_RETENTION"`
+	// JobImportMaxSize is the largest spreadsheet accepted by import jobs,
+	// in bytes. It may exceed IdempotencyMaxBodySize, since import jobs
+	// stream the spreadsheet and don't take an Idempotency-Key.
+	JobImportMaxSize int64 `mapstructure:"JOB_IMPORT_MAX_SIZE"`
+	// IdempotencyKeyTTL is how long the response to a request sent with an
+	// Idempotency-Key is replayed for, e.g. "24h".
+	IdempotencyKeyTTL time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	// IdempotencyMaxBodySize is the largest body of a request sent with an
+	// Idempotency-Key, in bytes, since it is read whole to be fingerprinted.
+	IdempotencyMaxBodySize int64 `mapstructure:"IDEMPOTENCY_MAX_BODY_SIZE"`
+	// EventPublisher picks where domain events are published besides the
+	// subscribers in the API itself, such as product streams: nowhere else
+	// with "inprocess" (default), or "nats" to the JetStream stream of the
+	// NATS servers at NATSURL under the NATSSubject subjects. NATSURL may
+	// use tls:// and hold a user and password, NATSCredentials is the path
+	// of a credentials file for JWT authentication and NATSRootCA the path
+	// of the certificates the servers are verified with.
+	EventPublisher  string `mapstructure:"EVENT_PUBLISHER"`
+	NATSURL         string `mapstructure:"NATS_URL"`
+	NATSSubject     string `mapstructure:"NATS_SUBJECT"`
+	NATSCredentials string `mapstructure:"NATS_CREDENTIALS"`
+	NATSRootCA      string `mapstructure:"NATS_ROOT_CA"`
+	// OutboxPollInterval is how often the outbox is checked for events to
+	// publish, e.g. "1s", and OutboxRetention how long published events are
+	// kept.
+	OutboxPollInterval time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	OutboxRetention    time.Duration `mapstructure:"OUTBOX_RETENTION"`
+	// WebhookMaxAttempts is how many times a webhook delivery is tried
+	// before it fails for good, waiting WebhookBackoff after the first
+	// failure and twice as long after each of the next ones, up to
+	// WebhookMaxBackoff. WebhookTimeout is how long receivers have to
+	// answer each attempt.
+	WebhookMaxAttempts int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookBackoff     time.Duration `mapstructure:"WEBHOOK_BACKOFF"`
+	WebhookMaxBackoff  time.Duration `mapstructure:"WEBHOOK_MAX_BACKOFF"`
+	WebhookTimeout     time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	// WebhookPollInterval is how often deliveries due for a retry are looked
+	// for, e.g. "5s", and WebhookDeliveryRetention how long finished
+	// deliveries are kept in the delivery log.
+	WebhookPollInterval      time.Duration `mapstructure:"WEBHOOK_POLL_INTERVAL"`
+	WebhookDeliveryRetention time.Duration `mapstructure:"WEBHOOK_DELIVERY_RETENTION"`
+	// WebhookAllowPrivateNetworks lets webhooks be sent to loopback, private,
+	// link-local, carrier-grade NAT and other non-public addresses, which are
+	// refused otherwise so webhooks can't be used to reach the internal
+	// network, e.g. in development.
+	WebhookAllowPrivateNetworks bool `mapstructure:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+	// ProductStreamBuffer is how many of the last product events are kept
+	// for clients of the product stream to resume from.
+	ProductStreamBuffer int `mapstructure:"PRODUCT_STREAM_BUFFER"`
+	// WSMaxSubscriptions is how many products and categories each
+	// connection to the live channel can subscribe to, and WSPingInterval
+	// how often its clients are pinged, e.g. "30s".
+	WSMaxSubscriptions int           `mapstructure:"WS_MAX_SUBSCRIPTIONS"`
+	WSPingInterval     time.Duration `mapstructure:"WS_PING_INTERVAL"`
+	TokenAuth          *jwtauth.JWTAuth
 }
 
 func LoadConfig(path string) (*conf, error) {
@@ -35,6 +129,99 @@ func LoadConfig(path string) (*conf, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.DefaultCurrency == "" {
+		cfg.DefaultCurrency = "BRL"
+	}
+	if cfg.PriceSchedulerInterval <= 0 {
+		cfg.PriceSchedulerInterval = time.Minute
+	}
+	if cfg.TrashRetention <= 0 {
+		cfg.TrashRetention = 30 * 24 * time.Hour
+	}
+	if cfg.TrashPurgeInterval <= 0 {
+		cfg.TrashPurgeInterval = time.Hour
+	}
+	if cfg.StorageDriver == "" {
+		cfg.StorageDriver = "local"
+	}
+	if cfg.StorageDir == "" {
+		cfg.StorageDir = "./uploads"
+	}
+	if cfg.StorageBaseURL == "" {
+		cfg.StorageBaseURL = "http://localhost:" + cfg.WebServerPort + "/uploads"
+	}
+	if cfg.S3Region == "" {
+		cfg.S3Region = "us-east-1"
+	}
+	if cfg.ImageMaxSize <= 0 {
+		cfg.ImageMaxSize = 5 << 20
+	}
+	if cfg.ImportMaxSize <= 0 {
+		cfg.ImportMaxSize = 10 << 20
+	}
+	if cfg.JobWorkers <= 0 {
+		cfg.JobWorkers = 2
+	}
+	if cfg.JobDir == "" {
+		cfg.JobDir = "./jobs"
+	}
+	if cfg.JobPollInterval <= 0 {
+		cfg.JobPollInterval = 5 * time.Second
+	}
+	if cfg.JobRetention <= 0 {
+		cfg.JobRetention = 7 * 24 * time.Hour
+	}
+	if cfg.JobImportMaxSize <= 0 {
+		cfg.JobImportMaxSize = 100 << 20
+	}
+	if cfg.IdempotencyKeyTTL <= 0 {
+		cfg.IdempotencyKeyTTL = 24 * time.Hour
+	}
+	if cfg.IdempotencyMaxBodySize <= 0 {
+		cfg.IdempotencyMaxBodySize = 10 << 20
+	}
+	if cfg.EventPublisher == "" {
+		cfg.EventPublisher = "inprocess"
+	}
+	if cfg.NATSURL == "" {
+		cfg.NATSURL = "nats://localhost:4222"
+	}
+	if cfg.NATSSubject == "" {
+		cfg.NATSSubject = "catalog"
+	}
+	if cfg.OutboxPollInterval <= 0 {
+		cfg.OutboxPollInterval = time.Second
+	}
+	if cfg.OutboxRetention <= 0 {
+		cfg.OutboxRetention = 7 * 24 * time.Hour
+	}
+	if cfg.WebhookMaxAttempts <= 0 {
+		cfg.WebhookMaxAttempts = 8
+	}
+	if cfg.WebhookBackoff <= 0 {
+		cfg.WebhookBackoff = time.Minute
+	}
+	if cfg.WebhookMaxBackoff <= 0 {
+		cfg.WebhookMaxBackoff = 6 * time.Hour
+	}
+	if cfg.WebhookTimeout <= 0 {
+		cfg.WebhookTimeout = 10 * time.Second
+	}
+	if cfg.WebhookPollInterval <= 0 {
+		cfg.WebhookPollInterval = 5 * time.Second
+	}
+	if cfg.WebhookDeliveryRetention <= 0 {
+		cfg.WebhookDeliveryRetention = 30 * 24 * time.Hour
+	}
+	if cfg.ProductStreamBuffer <= 0 {
+		cfg.ProductStreamBuffer = 1000
+	}
+	if cfg.WSMaxSubscriptions <= 0 {
+		cfg.WSMaxSubscriptions = 100
+	}
+	if cfg.WSPingInterval <= 0 {
+		cfg.WSPingInterval = 30 * time.Second
+	}
 	cfg.TokenAuth = jwtauth.New("HS256", []byte(cfg.JWTSecret), nil)
 	return cfg, err
 }
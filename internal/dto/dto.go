@@ -1,12 +1,39 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name              string                 `json:"name"`
+	Price             entity.Money           `json:"price"`
+	LowStockThreshold *int                   `json:"low_stock_threshold"`
+	CategoryIDs       []string               `json:"category_ids"`
+	Tags              []string               `json:"tags"`
+	Attributes        map[string]interface{} `json:"attributes"`
 }
 
 type UpdateProductInput = CreateProductInput
 
+type ProductTagsInput struct {
+	Tags []string `json:"tags"`
+}
+
+type CreateStockMovementInput struct {
+	Type     string `json:"type"`
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason"`
+}
+
+type CreateCategoryInput struct {
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+type UpdateCategoryInput = CreateCategoryInput
+
 type CreateUserInput struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
@@ -21,3 +48,190 @@ type GetJWTInput struct {
 type GetJWTOutput struct {
 	AccessToken string `json:"access_token"`
 }
+
+type ChangeUserRoleInput struct {
+	Role string `json:"role"`
+}
+
+type CreateClientInput struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+type CreateClientOutput struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Name         string `json:"name"`
+	Scope        string `json:"scope"`
+}
+
+type OAuthTokenOutput struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+type SetProductPriceInput struct {
+	Amount string `json:"amount" example:"2.19"`
+}
+
+type SetExchangeRateInput struct {
+	Rate string `json:"rate" example:"0.1987"`
+}
+
+type ImportExchangeRatesOutput struct {
+	Imported int `json:"imported"`
+}
+
+type CreateScheduledPriceInput struct {
+	Price          entity.Money `json:"price"`
+	EffectiveFrom  time.Time    `json:"effective_from"`
+	EffectiveUntil *time.Time   `json:"effective_until"`
+}
+
+type CreateVariantInput struct {
+	SKU     string            `json:"sku" example:"TSHIRT-BLUE-M"`
+	Barcode *string           `json:"barcode" example:"7891234567895"`
+	Options map[string]string `json:"options"`
+	Price   *entity.Money     `json:"price"`
+}
+
+type UpdateVariantInput = CreateVariantInput
+
+type CreateAttributeInput struct {
+	Code      string   `json:"code" example:"weight"`
+	Name      string   `json:"name" example:"Weight"`
+	Type      string   `json:"type" example:"number"`
+	Unit      string   `json:"unit" example:"kg"`
+	Options   []string `json:"options"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	MaxLength *int     `json:"max_length"`
+	Pattern   string   `json:"pattern"`
+}
+
+type UpdateAttributeInput = CreateAttributeInput
+
+type ReorderImagesInput struct {
+	ImageIDs []string `json:"image_ids"`
+}
+
+type ProductBatchInput struct {
+	// Mode is "atomic", where any failure rolls back every operation, or
+	// "best_effort", where each operation succeeds or fails on its own.
+	Mode       string                  `json:"mode" example:"atomic"`
+	Operations []ProductBatchOperation `json:"operations"`
+}
+
+type ProductBatchOperation struct {
+	Op string `json:"op" example:"update"`
+	// ID is the product to update or delete.
+	ID string `json:"id,omitempty"`
+	// Version is the version being updated or deleted, as sent in If-Match.
+	Version int                 `json:"version,omitempty"`
+	Product *UpdateProductInput `json:"product,omitempty"`
+}
+
+type ProductBatchResult struct {
+	Index   int    `json:"index"`
+	Op      string `json:"op"`
+	ID      string `json:"id,omitempty"`
+	Status  int    `json:"status"`
+	Version int    `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ProductBatchOutput struct {
+	Mode      string               `json:"mode"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Results   []ProductBatchResult `json:"results"`
+}
+
+type ProductImportRow struct {
+	// Row is the line of the row in the file, counting the header as 1.
+	Row int `json:"row"`
+	// Action is "create", "update", "unchanged" or "error".
+	Action string `json:"action" example:"update"`
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ProductImportOutput struct {
+	DryRun    bool               `json:"dry_run"`
+	Rows      int                `json:"rows"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Failed    int                `json:"failed"`
+	Results   []ProductImportRow `json:"results"`
+}
+
+type AuditVerifyOutput struct {
+	// Valid tells whether every entry of the audit log is intact.
+	Valid bool `json:"valid"`
+	// Checked is how many entries were found intact, in order.
+	Checked int64 `json:"checked"`
+	// BrokenAt is the sequence of the first entry that was changed or
+	// doesn't follow the one before it.
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
+
+type CreateWebhookInput struct {
+	URL        string   `json:"url" example:"https://partner.example.com/hooks"`
+	EventTypes []string `json:"event_types" example:"product.created,product.updated"`
+	// Secret signs the deliveries. One is generated when it is left empty.
+	Secret string `json:"secret"`
+}
+
+type UpdateWebhookInput struct {
+	URL        string   `json:"url" example:"https://partner.example.com/hooks"`
+	EventTypes []string `json:"event_types" example:"product.created,product.updated"`
+	// Secret replaces the secret deliveries are signed with, unless empty.
+	Secret string `json:"secret"`
+	Active *bool  `json:"active"`
+}
+
+type CreateWebhookOutput struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ProductRemovedEvent is the data of the removed events of the product
+// stream, sent when a changed product doesn't match the stream filters.
+type ProductRemovedEvent struct {
+	ID string `json:"id"`
+}
+
+// LiveRequest is a message clients send on the live channel.
+type LiveRequest struct {
+	// Action is "subscribe" or "unsubscribe".
+	Action     string   `json:"action" example:"subscribe"`
+	Products   []string `json:"products"`
+	Categories []string `json:"categories"`
+}
+
+// LiveMessage is a message sent to clients of the live channel.
+type LiveMessage struct {
+	// Type is "subscribed", "snapshot", "update", "removed" or "error".
+	Type      string `json:"type" example:"update"`
+	ProductID string `json:"product_id,omitempty"`
+	EventID   string `json:"event_id,omitempty"`
+	// Changes tells what an update changed: "price", "stock" or both.
+	Changes   []string       `json:"changes,omitempty"`
+	Price     *entity.Money  `json:"price,omitempty"`
+	Prices    []entity.Money `json:"prices,omitempty"`
+	Stock     *int           `json:"stock,omitempty"`
+	Reserved  *int           `json:"reserved,omitempty"`
+	Available *int           `json:"available,omitempty"`
+	// Products and Categories are what the client is subscribed to.
+	Products   []string `json:"products,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Message    string   `json:"message,omitempty"`
+}
@@ -0,0 +1,294 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Attribute types.
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+	AttributeEnum    = "enum"
+)
+
+var (
+	ErrInvalidAttributeCode    = errors.New("attribute code must start with a letter and have only lowercase letters, digits and '_', up to 50 characters")
+	ErrAttributeNameIsRequired = errors.New("attribute name is required")
+	ErrInvalidAttributeType    = errors.New("attribute type must be string, number, boolean or enum")
+	ErrEnumOptionsAreRequired  = errors.New("enum attributes need at least one option")
+	ErrInvalidAttributeRules   = errors.New("options only apply to enums, min and max to numbers and max_length and pattern to strings")
+	ErrInvalidAttributeRange   = errors.New("min can't be greater than max")
+	ErrInvalidAttributePattern = errors.New("pattern isn't a valid regular expression")
+	ErrAttributeTypeChange     = errors.New("the type of an attribute can't be changed")
+	ErrUnknownAttribute        = errors.New("unknown attribute")
+	ErrInvalidAttributeValue   = errors.New("invalid attribute value")
+)
+
+var attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// AttributeRules constrain the values of an attribute. Options list the
+// values of enums, Min and Max bound numbers and MaxLength and Pattern
+// restrict strings.
+type AttributeRules struct {
+	Options   []string `json:"options,omitempty" gorm:"serializer:json"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MaxLength *int     `json:"max_length,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+}
+
+// AttributeDefinition describes a custom product attribute, such as the
+// weight of a product in kg.
+type AttributeDefinition struct {
+	Code           string `json:"code" gorm:"primaryKey" example:"weight"`
+	Name           string `json:"name" example:"Weight"`
+	Type           string `json:"type" example:"number"`
+	Unit           string `json:"unit,omitempty" example:"kg"`
+	AttributeRules `gorm:"embedded"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func NewAttributeDefinition(code, name, attributeType, unit string, rules AttributeRules) (*AttributeDefinition, error) {
+	definition := &AttributeDefinition{
+		Code:           code,
+		Name:           name,
+		Type:           attributeType,
+		Unit:           unit,
+		AttributeRules: rules,
+		CreatedAt:      time.Now(),
+	}
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+func (d *AttributeDefinition) Validate() error {
+	if !attributeCodePattern.MatchString(d.Code) {
+		return ErrInvalidAttributeCode
+	}
+	if d.Name == "" {
+		return ErrAttributeNameIsRequired
+	}
+	rules := d.AttributeRules
+	switch d.Type {
+	case AttributeString:
+		if len(rules.Options) > 0 || rules.Min != nil || rules.Max != nil {
+			return ErrInvalidAttributeRules
+		}
+		if rules.MaxLength != nil && *rules.MaxLength <= 0 {
+			return ErrInvalidAttributeRules
+		}
+		if _, err := regexp.Compile(rules.Pattern); err != nil {
+			return ErrInvalidAttributePattern
+		}
+	case AttributeNumber:
+		if len(rules.Options) > 0 || rules.MaxLength != nil || rules.Pattern != "" {
+			return ErrInvalidAttributeRules
+		}
+		if rules.Min != nil && rules.Max != nil && *rules.Min > *rules.Max {
+			return ErrInvalidAttributeRange
+		}
+	case AttributeBoolean:
+		if len(rules.Options) > 0 || rules.Min != nil || rules.Max != nil || rules.MaxLength != nil || rules.Pattern != "" {
+			return ErrInvalidAttributeRules
+		}
+	case AttributeEnum:
+		if len(rules.Options) == 0 {
+			return ErrEnumOptionsAreRequired
+		}
+		if rules.Min != nil || rules.Max != nil || rules.MaxLength != nil || rules.Pattern != "" {
+			return ErrInvalidAttributeRules
+		}
+	default:
+		return ErrInvalidAttributeType
+	}
+	return nil
+}
+
+// Change replaces the name, unit and rules of the definition. Values that
+// were already stored aren't checked against the new rules.
+func (d *AttributeDefinition) Change(name, attributeType, unit string, rules AttributeRules) error {
+	if attributeType != d.Type {
+		return ErrAttributeTypeChange
+	}
+	changed := *d
+	changed.Name, changed.Unit, changed.AttributeRules = name, unit, rules
+	if err := changed.Validate(); err != nil {
+		return err
+	}
+	*d = changed
+	return nil
+}
+
+// NewValue checks a value decoded from JSON against the definition.
+func (d *AttributeDefinition) NewValue(productID entity.ID, value interface{}) (*AttributeValue, error) {
+	attributeValue := &AttributeValue{ProductID: productID, Code: d.Code, Type: d.Type}
+	invalid := fmt.Errorf("%w for %s", ErrInvalidAttributeValue, d.Code)
+	switch d.Type {
+	case AttributeString, AttributeEnum:
+		text, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if d.Type == AttributeEnum && !containsString(d.Options, text) {
+			return nil, invalid
+		}
+		if d.MaxLength != nil && utf8.RuneCountInString(text) > *d.MaxLength {
+			return nil, invalid
+		}
+		if d.Pattern != "" && !regexp.MustCompile(d.Pattern).MatchString(text) {
+			return nil, invalid
+		}
+		attributeValue.Value = text
+	case AttributeNumber:
+		number, ok := value.(float64)
+		if !ok || (d.Min != nil && number < *d.Min) || (d.Max != nil && number > *d.Max) {
+			return nil, invalid
+		}
+		attributeValue.Value = strconv.FormatFloat(number, 'f', -1, 64)
+		attributeValue.Number = &number
+	case AttributeBoolean:
+		boolean, ok := value.(bool)
+		if !ok {
+			return nil, invalid
+		}
+		attributeValue.Value = strconv.FormatBool(boolean)
+	default:
+		return nil, ErrInvalidAttributeType
+	}
+	return attributeValue, nil
+}
+
+// ParseValue converts text, such as a query string value, to a value of the
+// attribute.
+func (d *AttributeDefinition) ParseValue(text string) (*AttributeValue, error) {
+	var value interface{} = text
+	switch d.Type {
+	case AttributeNumber:
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s", ErrInvalidAttributeValue, d.Code)
+		}
+		value = number
+	case AttributeBoolean:
+		boolean, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s", ErrInvalidAttributeValue, d.Code)
+		}
+		value = boolean
+	}
+	return d.NewValue(entity.ID{}, value)
+}
+
+// AttributeValue is the value of a custom attribute of a product. Value
+// holds its text form and Number the value of number attributes, so they
+// can be compared in queries.
+type AttributeValue struct {
+	ProductID entity.ID `gorm:"primaryKey"`
+	Code      string    `gorm:"primaryKey"`
+	Type      string
+	Value     string
+	Number    *float64
+}
+
+// Interface returns the value as the Go type it has in JSON.
+func (v AttributeValue) Interface() interface{} {
+	switch v.Type {
+	case AttributeNumber:
+		if v.Number != nil {
+			return *v.Number
+		}
+	case AttributeBoolean:
+		return v.Value == "true"
+	}
+	return v.Value
+}
+
+// AttributeValues are encoded in JSON as an object mapping attribute codes
+// to their values.
+type AttributeValues []AttributeValue
+
+func (values AttributeValues) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{}, len(values))
+	for _, value := range values {
+		object[value.Code] = value.Interface()
+	}
+	return json.Marshal(object)
+}
+
+// UnmarshalJSON reads the values back from their object, telling their type
+// from the JSON type of each value, so enums are read as strings.
+func (values *AttributeValues) UnmarshalJSON(data []byte) error {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	decoded := make(AttributeValues, 0, len(object))
+	for code, value := range object {
+		attributeValue := AttributeValue{Code: code}
+		switch value := value.(type) {
+		case float64:
+			attributeValue.Type = AttributeNumber
+			attributeValue.Value = strconv.FormatFloat(value, 'f', -1, 64)
+			attributeValue.Number = &value
+		case bool:
+			attributeValue.Type = AttributeBoolean
+			attributeValue.Value = strconv.FormatBool(value)
+		case string:
+			attributeValue.Type = AttributeString
+			attributeValue.Value = value
+		default:
+			continue
+		}
+		decoded = append(decoded, attributeValue)
+	}
+	sort.Slice(decoded, func(i, j int) bool { return decoded[i].Code < decoded[j].Code })
+	*values = decoded
+	return nil
+}
+
+// NewAttributeValues checks the attributes sent for a product against their
+// definitions. Attributes set to null are left out.
+func NewAttributeValues(productID entity.ID, attributes map[string]interface{}, definitions []AttributeDefinition) (AttributeValues, error) {
+	byCode := make(map[string]*AttributeDefinition, len(definitions))
+	for i := range definitions {
+		byCode[definitions[i].Code] = &definitions[i]
+	}
+	values := AttributeValues{}
+	for code, value := range attributes {
+		definition, ok := byCode[code]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAttribute, code)
+		}
+		if value == nil {
+			continue
+		}
+		attributeValue, err := definition.NewValue(productID, value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, *attributeValue)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Code < values[j].Code })
+	return values, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func float(value float64) *float64 {
+	return &value
+}
+
+func TestNewAttributeDefinition(t *testing.T) {
+	d, err := NewAttributeDefinition("weight", "Weight", AttributeNumber, "kg", AttributeRules{Min: float(0), Max: float(1000)})
+	assert.Nil(t, err)
+	assert.Equal(t, "weight", d.Code)
+	assert.Equal(t, "kg", d.Unit)
+	assert.NotZero(t, d.CreatedAt)
+}
+
+func TestNewAttributeDefinition_Invalid(t *testing.T) {
+	maxLength := 0
+	for _, tc := range []struct {
+		code, name, attributeType string
+		rules                     AttributeRules
+		err                       error
+	}{
+		{"Weight", "Weight", AttributeNumber, AttributeRules{}, ErrInvalidAttributeCode},
+		{"1weight", "Weight", AttributeNumber, AttributeRules{}, ErrInvalidAttributeCode},
+		{"weight", "", AttributeNumber, AttributeRules{}, ErrAttributeNameIsRequired},
+		{"weight", "Weight", "float", AttributeRules{}, ErrInvalidAttributeType},
+		{"weight", "Weight", AttributeNumber, AttributeRules{Min: float(2), Max: float(1)}, ErrInvalidAttributeRange},
+		{"weight", "Weight", AttributeNumber, AttributeRules{Pattern: "^a"}, ErrInvalidAttributeRules},
+		{"material", "Material", AttributeEnum, AttributeRules{}, ErrEnumOptionsAreRequired},
+		{"material", "Material", AttributeString, AttributeRules{Options: []string{"cotton"}}, ErrInvalidAttributeRules},
+		{"material", "Material", AttributeString, AttributeRules{MaxLength: &maxLength}, ErrInvalidAttributeRules},
+		{"material", "Material", AttributeString, AttributeRules{Pattern: "("}, ErrInvalidAttributePattern},
+		{"fragile", "Fragile", AttributeBoolean, AttributeRules{Min: float(1)}, ErrInvalidAttributeRules},
+	} {
+		_, err := NewAttributeDefinition(tc.code, tc.name, tc.attributeType, "", tc.rules)
+		assert.Equal(t, tc.err, err, tc.code)
+	}
+}
+
+func TestAttributeDefinition_Change(t *testing.T) {
+	d, _ := NewAttributeDefinition("weight", "Weight", AttributeNumber, "kg", AttributeRules{})
+	assert.Equal(t, ErrAttributeTypeChange, d.Change("Weight", AttributeString, "", AttributeRules{}))
+	assert.Equal(t, ErrInvalidAttributeRange, d.Change("Weight", AttributeNumber, "g", AttributeRules{Min: float(2), Max: float(1)}))
+	assert.Equal(t, "kg", d.Unit)
+	assert.Nil(t, d.Change("Net weight", AttributeNumber, "g", AttributeRules{Min: float(1)}))
+	assert.Equal(t, "Net weight", d.Name)
+	assert.Equal(t, "g", d.Unit)
+}
+
+func TestAttributeDefinition_NewValue(t *testing.T) {
+	maxLength := 5
+	weight, _ := NewAttributeDefinition("weight", "Weight", AttributeNumber, "kg", AttributeRules{Min: float(0), Max: float(10)})
+	material, _ := NewAttributeDefinition("material", "Material", AttributeEnum, "", AttributeRules{Options: []string{"cotton", "wool"}})
+	color, _ := NewAttributeDefinition("color", "Color", AttributeString, "", AttributeRules{MaxLength: &maxLength, Pattern: "^[a-z]+$"})
+	fragile, _ := NewAttributeDefinition("fragile", "Fragile", AttributeBoolean, "", AttributeRules{})
+	id := entity.NewID()
+
+	value, err := weight.NewValue(id, 1.25)
+	assert.Nil(t, err)
+	assert.Equal(t, AttributeValue{ProductID: id, Code: "weight", Type: AttributeNumber, Value: "1.25", Number: float(1.25)}, *value)
+	value, err = material.NewValue(id, "wool")
+	assert.Nil(t, err)
+	assert.Equal(t, "wool", value.Value)
+	value, err = fragile.NewValue(id, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "true", value.Value)
+	_, err = color.NewValue(id, "blue")
+	assert.Nil(t, err)
+
+	for _, tc := range []struct {
+		definition *AttributeDefinition
+		value      interface{}
+	}{
+		{weight, "1.25"}, {weight, 11.0}, {weight, -1.0},
+		{material, "silk"}, {material, 1.0},
+		{color, "purple"}, {color, "Blue"},
+		{fragile, "true"},
+	} {
+		_, err = tc.definition.NewValue(id, tc.value)
+		assert.ErrorIs(t, err, ErrInvalidAttributeValue, tc.value)
+	}
+}
+
+func TestAttributeDefinition_ParseValue(t *testing.T) {
+	weight, _ := NewAttributeDefinition("weight", "Weight", AttributeNumber, "kg", AttributeRules{})
+	fragile, _ := NewAttributeDefinition("fragile", "Fragile", AttributeBoolean, "", AttributeRules{})
+	value, err := weight.ParseValue("1.50")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5", value.Value)
+	value, err = fragile.ParseValue("1")
+	assert.Nil(t, err)
+	assert.Equal(t, "true", value.Value)
+	_, err = weight.ParseValue("heavy")
+	assert.ErrorIs(t, err, ErrInvalidAttributeValue)
+}
+
+func TestNewAttributeValues(t *testing.T) {
+	weight, _ := NewAttributeDefinition("weight", "Weight", AttributeNumber, "kg", AttributeRules{})
+	fragile, _ := NewAttributeDefinition("fragile", "Fragile", AttributeBoolean, "", AttributeRules{})
+	definitions := []AttributeDefinition{*weight, *fragile}
+	id := entity.NewID()
+
+	values, err := NewAttributeValues(id, map[string]interface{}{"weight": 2.0, "fragile": false, "color": nil}, definitions)
+	assert.ErrorIs(t, err, ErrUnknownAttribute)
+	assert.Nil(t, values)
+
+	values, err = NewAttributeValues(id, map[string]interface{}{"weight": 2.0, "fragile": nil}, definitions)
+	assert.Nil(t, err)
+	assert.Len(t, values, 1)
+
+	values, err = NewAttributeValues(id, map[string]interface{}{"weight": 2.5, "fragile": false}, definitions)
+	assert.Nil(t, err)
+	data, err := json.Marshal(values)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"weight":2.5,"fragile":false}`, string(data))
+}
+
+func TestAttributeValues_UnmarshalJSON(t *testing.T) {
+	var values AttributeValues
+	assert.Nil(t, json.Unmarshal([]byte(`{"weight":2.5,"fragile":false,"color":"red"}`), &values))
+	assert.Equal(t, AttributeValues{
+		{Code: "color", Type: AttributeString, Value: "red"},
+		{Code: "fragile", Type: AttributeBoolean, Value: "false"},
+		{Code: "weight", Type: AttributeNumber, Value: "2.5", Number: float(2.5)},
+	}, values)
+
+	data, err := json.Marshal(values)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"weight":2.5,"fragile":false,"color":"red"}`, string(data))
+}
@@ -0,0 +1,192 @@
+package entity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Audit actions.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// Types of the entities whose changes are audited.
+const (
+	AuditProduct      = "product"
+	AuditVariant      = "variant"
+	AuditProductImage = "product_image"
+	AuditUser         = "user"
+)
+
+// AuditSystem is the actor of the changes the API makes on its own, like
+// emptying the trash.
+const AuditSystem = "system"
+
+// AuditSource tells who made a change and where from.
+type AuditSource struct {
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// AuditChange is the value of a field before and after a change, as JSON.
+// Before is missing for fields that were added and After for fields that
+// were removed.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditEntry records a change made to an entity: who made it, from where,
+// and the fields it changed. Entries form a hash chain: each one holds the
+// hash of the previous one, and its own hash covers that and every field
+// but itself, so an entry can't be changed or removed without breaking the
+// chain from there on.
+type AuditEntry struct {
+	ID         entity.ID       `json:"id"`
+	Sequence   int64           `json:"sequence" gorm:"uniqueIndex"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type" gorm:"index:idx_audit_entity"`
+	EntityID   string          `json:"entity_id" gorm:"index:idx_audit_entity"`
+	Actor      string          `json:"actor" gorm:"index"`
+	RequestID  string          `json:"request_id,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	Changes    json.RawMessage `json:"changes" swaggertype:"object"`
+	CreatedAt  time.Time       `json:"created_at" gorm:"index"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// AuditLock is a single row that is updated before an entry is appended,
+// so appends made at the same time, from any process, wait for each other
+// and each links to the one before it.
+type AuditLock struct {
+	ID         int `gorm:"primaryKey;autoIncrement:false"`
+	AcquiredAt time.Time
+}
+
+// NewAuditEntry records that source applied action to an entity, which went
+// from before to after. Both are compared by their JSON fields, and either
+// may be nil for creates and deletes.
+func NewAuditEntry(action, entityType, entityID string, source AuditSource, before, after interface{}) (*AuditEntry, error) {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditEntry{
+		ID:         entity.NewID(),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Actor:      source.Actor,
+		RequestID:  source.RequestID,
+		IP:         source.IP,
+		Changes:    encoded,
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// Diff compares the top level JSON fields of before and after, returning
+// the ones that differ.
+func Diff(before, after interface{}) (map[string]AuditChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := map[string]AuditChange{}
+	for name, value := range beforeFields {
+		if other, ok := afterFields[name]; !ok || !bytes.Equal(value, other) {
+			changes[name] = AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = AuditChange{After: value}
+		}
+	}
+	return changes, nil
+}
+
+// jsonFields encodes v as a JSON object and splits it in its fields, each
+// compacted so formatting doesn't count as a change.
+func jsonFields(v interface{}) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		var compacted bytes.Buffer
+		if err = json.Compact(&compacted, value); err != nil {
+			return nil, err
+		}
+		fields[name] = compacted.Bytes()
+	}
+	return fields, nil
+}
+
+// Link places the entry right after previous in the chain, or first when
+// previous is nil, and computes its hash.
+func (e *AuditEntry) Link(previous *AuditEntry) {
+	e.Sequence = 1
+	e.PrevHash = ""
+	if previous != nil {
+		e.Sequence = previous.Sequence + 1
+		e.PrevHash = previous.Hash
+	}
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash hashes every field of the entry but Hash.
+func (e *AuditEntry) ComputeHash() string {
+	fields := []string{
+		e.ID.String(),
+		strconv.FormatInt(e.Sequence, 10),
+		e.Action,
+		e.EntityType,
+		e.EntityID,
+		e.Actor,
+		e.RequestID,
+		e.IP,
+		string(e.Changes),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.PrevHash,
+	}
+	hash := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// Follows tells whether the entry is intact and comes right after previous,
+// or is the first one when previous is nil.
+func (e *AuditEntry) Follows(previous *AuditEntry) bool {
+	if previous == nil {
+		if e.Sequence != 1 || e.PrevHash != "" {
+			return false
+		}
+	} else if e.Sequence != previous.Sequence+1 || e.PrevHash != previous.Hash {
+		return false
+	}
+	return e.Hash == e.ComputeHash()
+}
@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"name": "Mouse", "price": map[string]string{"amount": "39.99"}, "tags": []string{"usb"}}
+	after := map[string]interface{}{"name": "Mouse", "price": map[string]string{"amount": "29.99"}, "stock": 3}
+	changes, err := Diff(before, after)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 3)
+	assert.JSONEq(t, `{"amount":"39.99"}`, string(changes["price"].Before))
+	assert.JSONEq(t, `{"amount":"29.99"}`, string(changes["price"].After))
+	assert.JSONEq(t, `["usb"]`, string(changes["tags"].Before))
+	assert.Nil(t, changes["tags"].After)
+	assert.Nil(t, changes["stock"].Before)
+	assert.Equal(t, "3", string(changes["stock"].After))
+
+	// A snapshot taken as JSON compares the same as the value it came from.
+	snapshot := json.RawMessage("{\n  \"name\": \"Mouse\"\n}")
+	changes, err = Diff(snapshot, map[string]string{"name": "Mouse"})
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestNewAuditEntry_Create(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	e, err := NewAuditEntry(AuditCreate, AuditProduct, p.ID.String(), AuditSource{Actor: "user-1"}, nil, p)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-1", e.Actor)
+	var changes map[string]AuditChange
+	assert.Nil(t, json.Unmarshal(e.Changes, &changes))
+	assert.Equal(t, `"Product 1"`, string(changes["name"].After))
+	assert.Nil(t, changes["name"].Before)
+}
+
+func TestAuditEntry_Chain(t *testing.T) {
+	first, _ := NewAuditEntry(AuditCreate, AuditUser, "1", AuditSource{Actor: "user-1"}, nil, map[string]string{"role": "editor"})
+	first.Link(nil)
+	assert.Equal(t, int64(1), first.Sequence)
+	assert.True(t, first.Follows(nil))
+
+	second, _ := NewAuditEntry(AuditUpdate, AuditUser, "1", AuditSource{Actor: "user-1"}, map[string]string{"role": "editor"}, map[string]string{"role": "admin"})
+	second.Link(first)
+	assert.Equal(t, int64(2), second.Sequence)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.True(t, second.Follows(first))
+	assert.False(t, second.Follows(nil))
+
+	second.Actor = "user-2"
+	assert.False(t, second.Follows(first))
+	second.Actor = "user-1"
+	first.Changes = json.RawMessage(`{}`)
+	assert.False(t, first.Follows(nil))
+}
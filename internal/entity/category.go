@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+var (
+	ErrCategoryNameIsRequired = errors.New("category name is required")
+	ErrCategoryCycle          = errors.New("a category can't be placed under itself or one of its descendants")
+	ErrCategoryHasChildren    = errors.New("category has subcategories")
+)
+
+type Category struct {
+	ID        entity.ID  `json:"id"`
+	Name      string     `json:"name"`
+	ParentID  *entity.ID `json:"parent_id"`
+	Children  []Category `json:"children,omitempty" gorm:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func NewCategory(name string, parentID *entity.ID) (*Category, error) {
+	category := &Category{
+		ID:        entity.NewID(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: time.Now(),
+	}
+	if err := category.Validate(); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (c *Category) Validate() error {
+	if c.ID.String() == "" {
+		return ErrIdIsRequired
+	}
+	if _, err := entity.ParseID(c.ID.String()); err != nil {
+		return ErrInvalidID
+	}
+	if c.Name == "" {
+		return ErrCategoryNameIsRequired
+	}
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
+// BuildCategoryTree nests a flat list of categories under their parents and
+// returns the categories found at the top of the hierarchy. When the list is
+// a subtree, its root is returned as the only element.
+func BuildCategoryTree(categories []Category) []Category {
+	children := make(map[entity.ID][]Category)
+	known := make(map[entity.ID]bool, len(categories))
+	for _, c := range categories {
+		known[c.ID] = true
+	}
+	var roots []Category
+	for _, c := range categories {
+		if c.ParentID == nil || !known[*c.ParentID] {
+			roots = append(roots, c)
+			continue
+		}
+		children[*c.ParentID] = append(children[*c.ParentID], c)
+	}
+	var attach func(c *Category)
+	attach = func(c *Category) {
+		c.Children = children[c.ID]
+		for i := range c.Children {
+			attach(&c.Children[i])
+		}
+	}
+	for i := range roots {
+		attach(&roots[i])
+	}
+	return roots
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCategory(t *testing.T) {
+	c, err := NewCategory("Electronics", nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+	assert.NotEmpty(t, c.ID)
+	assert.Nil(t, c.ParentID)
+	assert.Equal(t, "Electronics", c.Name)
+
+	child, err := NewCategory("Phones", &c.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, c.ID, *child.ParentID)
+}
+
+func TestCategoryNameIsRequired(t *testing.T) {
+	c, err := NewCategory("", nil)
+	assert.Nil(t, c)
+	assert.Equal(t, ErrCategoryNameIsRequired, err)
+}
+
+func TestCategoryCannotBeItsOwnParent(t *testing.T) {
+	c, err := NewCategory("Electronics", nil)
+	assert.Nil(t, err)
+	c.ParentID = &c.ID
+	assert.Equal(t, ErrCategoryCycle, c.Validate())
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	electronics, _ := NewCategory("Electronics", nil)
+	phones, _ := NewCategory("Phones", &electronics.ID)
+	android, _ := NewCategory("Android", &phones.ID)
+	books, _ := NewCategory("Books", nil)
+
+	tree := BuildCategoryTree([]Category{*android, *electronics, *books, *phones})
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Electronics", tree[0].Name)
+	assert.Len(t, tree[0].Children, 1)
+	assert.Equal(t, "Phones", tree[0].Children[0].Name)
+	assert.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, "Android", tree[0].Children[0].Children[0].Name)
+	assert.Equal(t, "Books", tree[1].Name)
+	assert.Empty(t, tree[1].Children)
+
+	subtree := BuildCategoryTree([]Category{*phones, *android})
+	assert.Len(t, subtree, 1)
+	assert.Equal(t, "Phones", subtree[0].Name)
+	assert.Len(t, subtree[0].Children, 1)
+}
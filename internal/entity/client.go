@@ -0,0 +1,107 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+)
+
+// Scopes lists every scope that can be granted to an access token.
+var Scopes = []string{ScopeProductsRead, ScopeProductsWrite}
+
+var (
+	ErrClientNameIsRequired = errors.New("client name is required")
+	ErrScopeIsRequired      = errors.New("scope is required")
+	ErrInvalidScope         = errors.New("invalid scope")
+)
+
+// Client is an OAuth2 client allowed to request tokens through the
+// client_credentials grant. Scope holds the space-delimited list of scopes
+// the client may request.
+type Client struct {
+	ID        entity.ID `json:"client_id"`
+	Name      string    `json:"name"`
+	Secret    string    `json:"-"`
+	Scope     string    `json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewClient registers a client and returns it together with the plain text
+// secret, which is only known at this point since the client stores its hash.
+func NewClient(name, scope string) (*Client, string, error) {
+	if name == "" {
+		return nil, "", ErrClientNameIsRequired
+	}
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return nil, "", ErrScopeIsRequired
+	}
+	for _, s := range scopes {
+		if !containsScope(Scopes, s) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", err
+	}
+	return &Client{
+		ID:        entity.NewID(),
+		Name:      name,
+		Secret:    string(hash),
+		Scope:     strings.Join(scopes, " "),
+		CreatedAt: time.Now(),
+	}, secret, nil
+}
+
+func (c *Client) ValidateSecret(secret string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(c.Secret), []byte(secret)) == nil
+}
+
+// GrantScope returns the scope to be granted for a token request. An empty
+// request grants every scope of the client, otherwise each requested scope
+// must have been registered for the client.
+func (c *Client) GrantScope(requested string) (string, error) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return c.Scope, nil
+	}
+	allowed := strings.Fields(c.Scope)
+	for _, s := range scopes {
+		if !containsScope(allowed, s) {
+			return "", ErrInvalidScope
+		}
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	client, secret, err := NewClient("Partner", "products:read products:write")
+	assert.Nil(t, err)
+	assert.NotNil(t, client)
+	assert.NotEmpty(t, client.ID)
+	assert.NotEmpty(t, secret)
+	assert.NotEqual(t, secret, client.Secret)
+	assert.Equal(t, "Partner", client.Name)
+	assert.Equal(t, "products:read products:write", client.Scope)
+}
+
+func TestNewClient_Validation(t *testing.T) {
+	_, _, err := NewClient("", "products:read")
+	assert.Equal(t, ErrClientNameIsRequired, err)
+
+	_, _, err = NewClient("Partner", "  ")
+	assert.Equal(t, ErrScopeIsRequired, err)
+
+	_, _, err = NewClient("Partner", "products:read orders:write")
+	assert.Equal(t, ErrInvalidScope, err)
+}
+
+func TestClient_ValidateSecret(t *testing.T) {
+	client, secret, err := NewClient("Partner", "products:read")
+	assert.Nil(t, err)
+	assert.True(t, client.ValidateSecret(secret))
+	assert.False(t, client.ValidateSecret(secret+"x"))
+}
+
+func TestClient_GrantScope(t *testing.T) {
+	client, _, err := NewClient("Partner", "products:read")
+	assert.Nil(t, err)
+
+	scope, err := client.GrantScope("")
+	assert.Nil(t, err)
+	assert.Equal(t, "products:read", scope)
+
+	scope, err = client.GrantScope("products:read")
+	assert.Nil(t, err)
+	assert.Equal(t, "products:read", scope)
+
+	_, err = client.GrantScope("products:read products:write")
+	assert.Equal(t, ErrInvalidScope, err)
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Types of the domain events.
+const (
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated"
+	EventProductDeleted = "product.deleted"
+	EventUserRegistered = "user.registered"
+)
+
+var ErrInvalidEventType = errors.New("invalid event type")
+
+// Event is a domain event telling that an entity changed, with the entity
+// as it was left as payload. Events are saved to the outbox in the same
+// transaction as the change and published afterwards, so an event is only
+// published for changes that were saved, and at least once. PublishedAt is
+// set once every consumer of the outbox got the event.
+type Event struct {
+	ID          entity.ID       `json:"id"`
+	Type        string          `json:"type"`
+	EntityID    string          `json:"entity_id"`
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"`
+	OccurredAt  time.Time       `json:"occurred_at" gorm:"index"`
+	PublishedAt *time.Time      `json:"-" gorm:"index"`
+}
+
+// EventPublication tracks the publication of an event to one consumer of
+// the outbox, such as the NATS server or the webhooks, so that each of them
+// goes through the events at its own pace and one that fails doesn't hold
+// the others up.
+type EventPublication struct {
+	EventID     entity.ID `gorm:"primaryKey"`
+	Consumer    string    `gorm:"primaryKey"`
+	PublishedAt *time.Time
+	// Attempts counts the failed attempts to publish the event, and
+	// LastError tells why the last one failed.
+	Attempts  int
+	LastError string
+}
+
+func NewEvent(eventType, entityID string, payload interface{}) (*Event, error) {
+	switch eventType {
+	case EventProductCreated, EventProductUpdated, EventProductDeleted, EventUserRegistered:
+	default:
+		return nil, ErrInvalidEventType
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		ID:         entity.NewID(),
+		Type:       eventType,
+		EntityID:   entityID,
+		Payload:    encoded,
+		OccurredAt: time.Now().UTC(),
+	}, nil
+}
+
+// Published tells whether the event was already published.
+func (e *Event) Published() bool {
+	return e.PublishedAt != nil
+}
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEvent(t *testing.T) {
+	e, err := NewEvent(EventProductUpdated, "product-1", map[string]string{"name": "Mouse"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, EventProductUpdated, e.Type)
+	assert.Equal(t, "product-1", e.EntityID)
+	assert.JSONEq(t, `{"name":"Mouse"}`, string(e.Payload))
+	assert.False(t, e.OccurredAt.IsZero())
+	assert.False(t, e.Published())
+
+	_, err = NewEvent("product.renamed", "product-1", nil)
+	assert.Equal(t, ErrInvalidEventType, err)
+}
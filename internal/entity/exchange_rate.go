@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+var (
+	ErrInvalidRate      = errors.New("rate must be a positive decimal number")
+	ErrSameCurrency     = errors.New("base and quote currencies must differ")
+	ErrRateDoesNotApply = errors.New("exchange rate doesn't convert from this currency")
+)
+
+// ExchangeRate tells how many units of Quote one unit of Base is worth. The
+// rate is kept as the decimal string it was given in so no precision is lost.
+type ExchangeRate struct {
+	Base      string    `json:"base" gorm:"primaryKey" example:"BRL"`
+	Quote     string    `json:"quote" gorm:"primaryKey" example:"USD"`
+	Rate      string    `json:"rate" example:"0.1987"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewExchangeRate(base, quote, rate string) (*ExchangeRate, error) {
+	exchangeRate := &ExchangeRate{
+		Base:      strings.ToUpper(strings.TrimSpace(base)),
+		Quote:     strings.ToUpper(strings.TrimSpace(quote)),
+		Rate:      strings.TrimSpace(rate),
+		UpdatedAt: time.Now(),
+	}
+	if err := exchangeRate.Validate(); err != nil {
+		return nil, err
+	}
+	return exchangeRate, nil
+}
+
+func (r *ExchangeRate) Validate() error {
+	if !entity.IsValidCurrency(r.Base) || !entity.IsValidCurrency(r.Quote) {
+		return entity.ErrInvalidCurrency
+	}
+	if r.Base == r.Quote {
+		return ErrSameCurrency
+	}
+	if _, err := parseRate(r.Rate); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert converts an amount in either currency of the pair to the other
+// one, using the inverse rate when converting from Quote to Base.
+func (r *ExchangeRate) Convert(m entity.Money) (entity.Money, error) {
+	rate, err := parseRate(r.Rate)
+	if err != nil {
+		return entity.Money{}, err
+	}
+	switch m.Currency {
+	case r.Base:
+		return m.Convert(r.Quote, rate)
+	case r.Quote:
+		return m.Convert(r.Base, rate.Inv(rate))
+	}
+	return entity.Money{}, ErrRateDoesNotApply
+}
+
+// parseRate only accepts plain decimals such as "5.0321", not the fractions
+// and exponents big.Rat would otherwise take.
+func parseRate(value string) (*big.Rat, error) {
+	units, fraction, hasSeparator := strings.Cut(value, ".")
+	if units == "" || (hasSeparator && fraction == "") {
+		return nil, ErrInvalidRate
+	}
+	for _, r := range units + fraction {
+		if r < '0' || r > '9' {
+			return nil, ErrInvalidRate
+		}
+	}
+	rate, ok := new(big.Rat).SetString(value)
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	return rate, nil
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExchangeRate(t *testing.T) {
+	rate, err := NewExchangeRate(" brl", "usd ", "0.1987")
+	assert.Nil(t, err)
+	assert.Equal(t, "BRL", rate.Base)
+	assert.Equal(t, "USD", rate.Quote)
+	assert.Equal(t, "0.1987", rate.Rate)
+	assert.NotZero(t, rate.UpdatedAt)
+}
+
+func TestNewExchangeRate_Invalid(t *testing.T) {
+	_, err := NewExchangeRate("BRL", "XYZ", "1")
+	assert.Equal(t, entity.ErrInvalidCurrency, err)
+	_, err = NewExchangeRate("BRL", "brl", "1")
+	assert.Equal(t, ErrSameCurrency, err)
+	for _, value := range []string{"", "0", "0.000", "-1", "1/3", "1e3", ".5", "5.", "abc"} {
+		_, err = NewExchangeRate("BRL", "USD", value)
+		assert.Equal(t, ErrInvalidRate, err, value)
+	}
+}
+
+func TestExchangeRate_Convert(t *testing.T) {
+	rate, _ := NewExchangeRate("USD", "BRL", "5")
+	price, err := rate.Convert(entity.Money{Amount: 1099, Currency: "USD"})
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Money{Amount: 5495, Currency: "BRL"}, price)
+
+	price, err = rate.Convert(entity.Money{Amount: 5495, Currency: "BRL"})
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Money{Amount: 1099, Currency: "USD"}, price)
+
+	_, err = rate.Convert(entity.Money{Amount: 1099, Currency: "EUR"})
+	assert.Equal(t, ErrRateDoesNotApply, err)
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted.
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("Idempotency-Key must be between 1 and 255 characters long")
+	ErrIdempotencyKeyReused  = errors.New("Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInUse   = errors.New("a request with this Idempotency-Key is still being processed")
+)
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header, so that when Owner sends the same request again
+// with the same key the response is replayed instead of the request being
+// carried out twice. Fingerprint identifies the request the key was used
+// for. Status is 0 until the response is saved.
+type IdempotencyKey struct {
+	Owner       string `gorm:"primaryKey"`
+	Key         string `gorm:"primaryKey"`
+	Fingerprint string
+	Status      int
+	Header      http.Header `gorm:"serializer:json"`
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+// NewIdempotencyKey reserves key for the request identified by fingerprint
+// for ttl.
+func NewIdempotencyKey(owner, key, fingerprint string, ttl time.Duration) (*IdempotencyKey, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+	now := time.Now()
+	return &IdempotencyKey{
+		Owner:       owner,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}, nil
+}
+
+// Complete saves the response to the request.
+func (k *IdempotencyKey) Complete(status int, header http.Header, body []byte) {
+	k.Status = status
+	k.Header = header
+	k.Body = body
+}
+
+// Completed tells whether the response was saved, or the request is still
+// being processed.
+func (k *IdempotencyKey) Completed() bool {
+	return k.Status != 0
+}
+
+// Expired tells whether the key can be used again for any request.
+func (k *IdempotencyKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIdempotencyKey(t *testing.T) {
+	k, err := NewIdempotencyKey("user-1", "4f1c2a", "abc", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-1", k.Owner)
+	assert.False(t, k.Completed())
+	assert.False(t, k.Expired(time.Now()))
+	assert.True(t, k.Expired(time.Now().Add(time.Hour)))
+
+	_, err = NewIdempotencyKey("user-1", "", "abc", time.Hour)
+	assert.Equal(t, ErrInvalidIdempotencyKey, err)
+	_, err = NewIdempotencyKey("user-1", strings.Repeat("k", 256), "abc", time.Hour)
+	assert.Equal(t, ErrInvalidIdempotencyKey, err)
+}
+
+func TestIdempotencyKey_Complete(t *testing.T) {
+	k, _ := NewIdempotencyKey("user-1", "4f1c2a", "abc", time.Hour)
+	k.Complete(http.StatusCreated, http.Header{"Content-Type": {"application/json"}}, []byte(`{"id":"1"}`))
+	assert.True(t, k.Completed())
+	assert.Equal(t, http.StatusCreated, k.Status)
+	assert.Equal(t, `{"id":"1"}`, string(k.Body))
+}
@@ -0,0 +1,102 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Job types.
+const (
+	JobProductImport = "product_import"
+	JobProductExport = "product_export"
+)
+
+// Job statuses. A queued job is picked by a worker, which marks it running
+// until it succeeds or fails.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+var (
+	ErrInvalidJobType = errors.New("job type must be product_import or product_export")
+	ErrJobNotRunning  = errors.New("only running jobs can be finished")
+)
+
+// Job is work done in the background on behalf of CreatedBy, such as a
+// large import or export. Params holds what the job needs to run, as JSON,
+// and ResultKey the file store key of what it produced, if anything.
+type Job struct {
+	ID        entity.ID `json:"id"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	Params    string    `json:"-"`
+	Processed int       `json:"processed"`
+	// Total is how many items the job has to process, or 0 while unknown.
+	Total             int    `json:"total"`
+	Error             string `json:"error,omitempty"`
+	ResultKey         string `json:"-"`
+	ResultContentType string `json:"-"`
+	ResultFilename    string `json:"-"`
+	// ResultURL is where the result is downloaded from. It isn't stored.
+	ResultURL  string     `json:"result_url,omitempty" gorm:"-"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func NewJob(jobType, params, createdBy string) (*Job, error) {
+	job := &Job{
+		ID:        entity.NewID(),
+		Type:      jobType,
+		Status:    JobQueued,
+		Params:    params,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	if job.Type != JobProductImport && job.Type != JobProductExport {
+		return nil, ErrInvalidJobType
+	}
+	return job, nil
+}
+
+// SetResult records the file the job produced, which is downloaded as
+// filename.
+func (j *Job) SetResult(key, contentType, filename string) {
+	j.ResultKey = key
+	j.ResultContentType = contentType
+	j.ResultFilename = filename
+}
+
+func (j *Job) Succeed() error {
+	if j.Status != JobRunning {
+		return ErrJobNotRunning
+	}
+	now := time.Now()
+	j.Status = JobSucceeded
+	j.FinishedAt = &now
+	return nil
+}
+
+// Fail finishes the job with the reason it failed. A failed job may still
+// have a result, such as the report of an import with invalid rows.
+func (j *Job) Fail(reason string) error {
+	if j.Status != JobRunning {
+		return ErrJobNotRunning
+	}
+	now := time.Now()
+	j.Status = JobFailed
+	j.Error = reason
+	j.FinishedAt = &now
+	return nil
+}
+
+// Finished tells whether the job succeeded or failed.
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJob(t *testing.T) {
+	j, err := NewJob(JobProductExport, `{"format":"csv"}`, "user-1")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, j.ID)
+	assert.Equal(t, JobQueued, j.Status)
+	assert.Equal(t, "user-1", j.CreatedBy)
+
+	_, err = NewJob("cleanup", "", "user-1")
+	assert.Equal(t, ErrInvalidJobType, err)
+}
+
+func TestJob_Finish(t *testing.T) {
+	j, _ := NewJob(JobProductExport, "", "user-1")
+	assert.Equal(t, ErrJobNotRunning, j.Succeed())
+
+	j.Status = JobRunning
+	j.SetResult("jobs/1/products.csv", "text/csv", "products.csv")
+	assert.Nil(t, j.Succeed())
+	assert.Equal(t, JobSucceeded, j.Status)
+	assert.Equal(t, "jobs/1/products.csv", j.ResultKey)
+	assert.NotNil(t, j.FinishedAt)
+	assert.True(t, j.Finished())
+	assert.Equal(t, ErrJobNotRunning, j.Fail("too late"))
+
+	j, _ = NewJob(JobProductImport, "", "user-1")
+	j.Status = JobRunning
+	assert.Nil(t, j.Fail("file has no rows to import"))
+	assert.Equal(t, JobFailed, j.Status)
+	assert.Equal(t, "file has no rows to import", j.Error)
+	assert.True(t, j.Finished())
+}
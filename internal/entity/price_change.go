@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// PriceChangedByScheduler is recorded as the author of the price changes
+// applied by scheduled prices.
+const PriceChangedByScheduler = "scheduler"
+
+// PriceChange is an entry of the price history of a product.
+type PriceChange struct {
+	ID        entity.ID    `json:"id"`
+	ProductID entity.ID    `json:"product_id"`
+	OldPrice  entity.Money `json:"old_price" gorm:"embedded;embeddedPrefix:old_price_"`
+	NewPrice  entity.Money `json:"new_price" gorm:"embedded;embeddedPrefix:new_price_"`
+	// ScheduledPriceID is set when the change was applied by a scheduled price.
+	ScheduledPriceID *entity.ID `json:"scheduled_price_id,omitempty"`
+	ChangedBy        string     `json:"changed_by"`
+	ChangedAt        time.Time  `json:"changed_at"`
+}
+
+func NewPriceChange(productID entity.ID, oldPrice, newPrice entity.Money, changedBy string) *PriceChange {
+	return &PriceChange{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		OldPrice:  oldPrice,
+		NewPrice:  newPrice,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	}
+}
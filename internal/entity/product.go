@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"gorm.io/gorm"
 )
 
 var (
@@ -13,21 +14,52 @@ var (
 	ErrNameIsRequired  = errors.New("name is required")
 	ErrPriceIsRequired = errors.New("price is required")
 	ErrInvalidPrice    = errors.New("price is required")
+
+	ErrInvalidLowStockThreshold = errors.New("low stock threshold can't be negative")
 )
 
+// Product is an item of the catalogue. DisplayPrice isn't stored: it holds
+// the price in the currency asked for by the client, and UpdatedBy tells who
+// made the last change. Version goes up whenever the product changes, except
+// through stock movements, so concurrent updates can be detected. Status only
+// changes through ChangeStatus. Deleted products stay in the trash, hidden
+// from every query, until restored or purged.
 type Product struct {
-	ID        entity.ID `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                entity.ID       `json:"id"`
+	Name              string          `json:"name"`
+	Status            string          `json:"status" gorm:"index"`
+	Price             entity.Money    `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Prices            []ProductPrice  `json:"prices" gorm:"foreignKey:ProductID"`
+	DisplayPrice      *entity.Money   `json:"display_price,omitempty" gorm:"-"`
+	Stock             int             `json:"stock"`
+	Reserved          int             `json:"reserved"`
+	LowStockThreshold int             `json:"low_stock_threshold"`
+	Categories        []Category      `json:"categories" gorm:"many2many:product_categories"`
+	Tags              []Tag           `json:"tags" gorm:"many2many:product_tags" swaggertype:"array,string"`
+	Variants          []Variant       `json:"variants" gorm:"foreignKey:ProductID"`
+	Attributes        AttributeValues `json:"attributes" gorm:"foreignKey:ProductID" swaggertype:"object"`
+	Images            []ProductImage  `json:"images" gorm:"foreignKey:ProductID"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	Version           int             `json:"version"`
+	UpdatedBy         string          `json:"updated_by,omitempty"`
+	DeletedAt         gorm.DeletedAt  `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" format:"date-time"`
+	DeletedBy         string          `json:"deleted_by,omitempty"`
 }
 
-func NewProduct(name string, price float64) (*Product, error) {
+func NewProduct(name string, price entity.Money) (*Product, error) {
 	product := &Product{
-		ID:        entity.NewID(),
-		Price:     price,
-		Name:      name,
-		CreatedAt: time.Now(),
+		ID:         entity.NewID(),
+		Price:      price,
+		Name:       name,
+		Status:     ProductDraft,
+		Prices:     []ProductPrice{},
+		Variants:   []Variant{},
+		Attributes: AttributeValues{},
+		Images:     []ProductImage{},
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		Version:    1,
 	}
 	if err := product.Validate(); err != nil {
 		return nil, err
@@ -45,11 +77,75 @@ func (p *Product) Validate() error {
 	if p.Name == "" {
 		return ErrNameIsRequired
 	}
-	if p.Price == 0.0 {
+	if !IsValidProductStatus(p.Status) {
+		return ErrInvalidProductStatus
+	}
+	if p.Price.IsZero() {
 		return ErrPriceIsRequired
 	}
-	if p.Price < 0 {
+	if p.Price.IsNegative() {
 		return ErrInvalidPrice
 	}
+	if err := p.Price.Validate(); err != nil {
+		return err
+	}
+	for _, price := range p.Prices {
+		if price.Currency == p.Price.Currency {
+			return ErrPriceOverrideInBaseCurrency
+		}
+	}
+	for _, variant := range p.Variants {
+		if variant.Price != nil && variant.Price.Currency != p.Price.Currency {
+			return ErrVariantPriceCurrency
+		}
+	}
+	if p.LowStockThreshold < 0 {
+		return ErrInvalidLowStockThreshold
+	}
+	return nil
+}
+
+// ChangeStatus moves the product to status when the transition is allowed.
+func (p *Product) ChangeStatus(status string) error {
+	if !IsValidProductStatus(status) {
+		return ErrInvalidProductStatus
+	}
+	if !CanTransition(p.Status, status) {
+		return ErrInvalidStatusTransition
+	}
+	p.Status = status
 	return nil
 }
+
+func (p *Product) IsPublished() bool {
+	return p.Status == ProductPublished
+}
+
+// AddTags adds the tags the product doesn't carry yet.
+func (p *Product) AddTags(tags []Tag) {
+	for _, tag := range tags {
+		if !p.HasTag(tag.Name) {
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+}
+
+// RemoveTag removes a tag from the product, reporting whether it was there.
+func (p *Product) RemoveTag(name string) bool {
+	for i, tag := range p.Tags {
+		if tag.Name == name {
+			p.Tags = append(p.Tags[:i], p.Tags[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Product) HasTag(name string) bool {
+	for _, tag := range p.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
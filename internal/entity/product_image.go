@@ -0,0 +1,147 @@
+package entity
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+var (
+	ErrUnsupportedImageType = errors.New("image must be a JPEG, PNG, GIF or WebP file")
+	ErrImageTypeMismatch    = errors.New("image content doesn't match its content type")
+	ErrInvalidImage         = errors.New("image can't be decoded")
+	ErrImageIsRequired      = errors.New("image is required")
+	ErrImageTooLarge        = errors.New("image is too large")
+	ErrInvalidImageOrder    = errors.New("image order must list every image of the product exactly once")
+)
+
+// imageExtensions are the file extensions of the accepted image types.
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// ThumbnailSizes are the names of the thumbnails generated for every image
+// and the length, in pixels, of their longest side.
+var ThumbnailSizes = map[string]int{
+	"small":  150,
+	"medium": 400,
+	"large":  800,
+}
+
+// ProductImage is a picture of a product. The original file and its
+// thumbnails are kept in a storage under Key and ThumbnailKeys, while URL
+// and Thumbnails aren't stored: they hold where the files are served from.
+// Images are shown in ascending Position.
+type ProductImage struct {
+	ID            entity.ID         `json:"id"`
+	ProductID     entity.ID         `json:"product_id" gorm:"index"`
+	Position      int               `json:"position"`
+	ContentType   string            `json:"content_type"`
+	Size          int64             `json:"size"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Key           string            `json:"-"`
+	ThumbnailKeys map[string]string `json:"-" gorm:"serializer:json"`
+	URL           string            `json:"url" gorm:"-"`
+	Thumbnails    map[string]string `json:"thumbnails" gorm:"-"`
+	CreatedBy     string            `json:"created_by"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+func NewProductImage(productID entity.ID, contentType string, size int64, width, height int, createdBy string) (*ProductImage, error) {
+	image := &ProductImage{
+		ID:          entity.NewID(),
+		ProductID:   productID,
+		ContentType: contentType,
+		Size:        size,
+		Width:       width,
+		Height:      height,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := image.Validate(); err != nil {
+		return nil, err
+	}
+	prefix := "products/" + productID.String() + "/images/" + image.ID.String() + "/"
+	image.Key = prefix + "original." + imageExtensions[contentType]
+	image.ThumbnailKeys = make(map[string]string, len(ThumbnailSizes))
+	thumbnailExtension := imageExtensions[ThumbnailContentType(contentType)]
+	for name := range ThumbnailSizes {
+		image.ThumbnailKeys[name] = prefix + name + "." + thumbnailExtension
+	}
+	return image, nil
+}
+
+func (i *ProductImage) Validate() error {
+	if !IsSupportedImageType(i.ContentType) {
+		return ErrUnsupportedImageType
+	}
+	if i.Size <= 0 {
+		return ErrImageIsRequired
+	}
+	if i.Width <= 0 || i.Height <= 0 {
+		return ErrInvalidImage
+	}
+	return nil
+}
+
+// Keys lists the storage keys of the original file and of its thumbnails.
+func (i *ProductImage) Keys() []string {
+	keys := []string{i.Key}
+	names := make([]string, 0, len(i.ThumbnailKeys))
+	for name := range i.ThumbnailKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		keys = append(keys, i.ThumbnailKeys[name])
+	}
+	return keys
+}
+
+func IsSupportedImageType(contentType string) bool {
+	_, ok := imageExtensions[contentType]
+	return ok
+}
+
+// ThumbnailContentType is the type thumbnails of an image are encoded in:
+// JPEG photos stay JPEG and everything else becomes PNG, which keeps
+// transparency.
+func ThumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return contentType
+	}
+	return "image/png"
+}
+
+// ReorderImages sets the position of each image to the index of its id in
+// ids, which must list every image exactly once, and sorts them.
+func ReorderImages(images []ProductImage, ids []string) error {
+	if len(ids) != len(images) {
+		return ErrInvalidImageOrder
+	}
+	positions := make(map[string]int, len(ids))
+	for position, id := range ids {
+		if _, ok := positions[id]; ok {
+			return ErrInvalidImageOrder
+		}
+		positions[id] = position
+	}
+	for _, image := range images {
+		if _, ok := positions[image.ID.String()]; !ok {
+			return ErrInvalidImageOrder
+		}
+	}
+	for i := range images {
+		images[i].Position = positions[images[i].ID.String()]
+	}
+	sort.Slice(images, func(a, b int) bool {
+		return images[a].Position < images[b].Position
+	})
+	return nil
+}
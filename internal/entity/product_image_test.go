@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProductImage(t *testing.T) {
+	productID := entity.NewID()
+	image, err := NewProductImage(productID, "image/png", 2048, 640, 480, "user-1")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, image.ID)
+	assert.Equal(t, productID, image.ProductID)
+	prefix := "products/" + productID.String() + "/images/" + image.ID.String() + "/"
+	assert.Equal(t, prefix+"original.png", image.Key)
+	assert.Len(t, image.ThumbnailKeys, len(ThumbnailSizes))
+	assert.Equal(t, prefix+"small.png", image.ThumbnailKeys["small"])
+	assert.Equal(t, []string{prefix + "original.png", prefix + "large.png", prefix + "medium.png", prefix + "small.png"}, image.Keys())
+
+	image, err = NewProductImage(productID, "image/jpeg", 2048, 640, 480, "user-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "jpg", image.ThumbnailKeys["small"][len(image.ThumbnailKeys["small"])-3:])
+}
+
+func TestNewProductImage_Invalid(t *testing.T) {
+	productID := entity.NewID()
+	_, err := NewProductImage(productID, "image/svg+xml", 2048, 640, 480, "")
+	assert.Equal(t, ErrUnsupportedImageType, err)
+	_, err = NewProductImage(productID, "image/png", 0, 640, 480, "")
+	assert.Equal(t, ErrImageIsRequired, err)
+	_, err = NewProductImage(productID, "image/png", 2048, 0, 480, "")
+	assert.Equal(t, ErrInvalidImage, err)
+}
+
+func TestThumbnailContentType(t *testing.T) {
+	assert.Equal(t, "image/jpeg", ThumbnailContentType("image/jpeg"))
+	assert.Equal(t, "image/png", ThumbnailContentType("image/png"))
+	assert.Equal(t, "image/png", ThumbnailContentType("image/gif"))
+	assert.Equal(t, "image/png", ThumbnailContentType("image/webp"))
+}
+
+func TestReorderImages(t *testing.T) {
+	productID := entity.NewID()
+	first, _ := NewProductImage(productID, "image/png", 1, 1, 1, "")
+	second, _ := NewProductImage(productID, "image/png", 1, 1, 1, "")
+	second.Position = 1
+	images := []ProductImage{*first, *second}
+
+	assert.Nil(t, ReorderImages(images, []string{second.ID.String(), first.ID.String()}))
+	assert.Equal(t, second.ID, images[0].ID)
+	assert.Equal(t, 0, images[0].Position)
+	assert.Equal(t, first.ID, images[1].ID)
+	assert.Equal(t, 1, images[1].Position)
+
+	assert.Equal(t, ErrInvalidImageOrder, ReorderImages(images, []string{first.ID.String()}))
+	assert.Equal(t, ErrInvalidImageOrder, ReorderImages(images, []string{first.ID.String(), first.ID.String()}))
+	assert.Equal(t, ErrInvalidImageOrder, ReorderImages(images, []string{first.ID.String(), entity.NewID().String()}))
+	assert.Equal(t, first.ID, images[1].ID)
+}
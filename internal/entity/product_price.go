@@ -0,0 +1,100 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+var (
+	ErrPriceOverrideInBaseCurrency = errors.New("price override can't use the base currency of the product")
+	ErrNoExchangeRate              = errors.New("no price or exchange rate for this currency")
+)
+
+// ProductPrice overrides the price of a product in a currency other than its
+// base currency. It is encoded in JSON as a money amount.
+type ProductPrice struct {
+	ProductID entity.ID `json:"-" gorm:"primaryKey"`
+	Currency  string    `json:"currency" gorm:"primaryKey" example:"USD"`
+	Amount    int64     `json:"amount" swaggertype:"string" example:"2.19"`
+}
+
+func (p ProductPrice) Money() entity.Money {
+	return entity.Money{Amount: p.Amount, Currency: p.Currency}
+}
+
+func (p ProductPrice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Money())
+}
+
+func (p *ProductPrice) UnmarshalJSON(data []byte) error {
+	var price entity.Money
+	if err := json.Unmarshal(data, &price); err != nil {
+		return err
+	}
+	p.Currency, p.Amount = price.Currency, price.Amount
+	return nil
+}
+
+// SetPriceOverride sets the price of the product in another currency,
+// replacing the previous override in that currency.
+func (p *Product) SetPriceOverride(price entity.Money) error {
+	if err := price.Validate(); err != nil {
+		return err
+	}
+	if price.Currency == p.Price.Currency {
+		return ErrPriceOverrideInBaseCurrency
+	}
+	if price.IsZero() {
+		return ErrPriceIsRequired
+	}
+	if price.IsNegative() {
+		return ErrInvalidPrice
+	}
+	for i := range p.Prices {
+		if p.Prices[i].Currency == price.Currency {
+			p.Prices[i].Amount = price.Amount
+			return nil
+		}
+	}
+	p.Prices = append(p.Prices, ProductPrice{ProductID: p.ID, Currency: price.Currency, Amount: price.Amount})
+	return nil
+}
+
+// RemovePriceOverride removes the override in currency, reporting whether
+// there was one.
+func (p *Product) RemovePriceOverride(currency string) bool {
+	for i, price := range p.Prices {
+		if price.Currency == currency {
+			p.Prices = append(p.Prices[:i], p.Prices[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// PriceIn gives the price of the product in currency: its base price, the
+// override set for that currency or, failing those, the base price
+// converted with rate.
+func (p *Product) PriceIn(currency string, rate *ExchangeRate) (entity.Money, error) {
+	if currency == p.Price.Currency {
+		return p.Price, nil
+	}
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price.Money(), nil
+		}
+	}
+	if rate == nil {
+		return entity.Money{}, ErrNoExchangeRate
+	}
+	price, err := rate.Convert(p.Price)
+	if err != nil {
+		return entity.Money{}, err
+	}
+	if price.Currency != currency {
+		return entity.Money{}, ErrNoExchangeRate
+	}
+	return price, nil
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProduct_SetPriceOverride(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1099))
+	assert.Nil(t, p.SetPriceOverride(entity.Money{Amount: 219, Currency: "USD"}))
+	assert.Nil(t, p.SetPriceOverride(entity.Money{Amount: 199, Currency: "EUR"}))
+	assert.Nil(t, p.SetPriceOverride(entity.Money{Amount: 229, Currency: "USD"}))
+	assert.Equal(t, []ProductPrice{
+		{ProductID: p.ID, Currency: "USD", Amount: 229},
+		{ProductID: p.ID, Currency: "EUR", Amount: 199},
+	}, p.Prices)
+
+	assert.True(t, p.RemovePriceOverride("USD"))
+	assert.False(t, p.RemovePriceOverride("USD"))
+	assert.Len(t, p.Prices, 1)
+}
+
+func TestProduct_SetPriceOverride_Invalid(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1099))
+	assert.Equal(t, ErrPriceOverrideInBaseCurrency, p.SetPriceOverride(brl(999)))
+	assert.Equal(t, ErrPriceIsRequired, p.SetPriceOverride(entity.Money{Currency: "USD"}))
+	assert.Equal(t, ErrInvalidPrice, p.SetPriceOverride(entity.Money{Amount: -1, Currency: "USD"}))
+	assert.Equal(t, entity.ErrInvalidCurrency, p.SetPriceOverride(entity.Money{Amount: 1, Currency: "XYZ"}))
+	assert.Empty(t, p.Prices)
+
+	p.Prices = []ProductPrice{{ProductID: p.ID, Currency: "BRL", Amount: 1}}
+	assert.Equal(t, ErrPriceOverrideInBaseCurrency, p.Validate())
+}
+
+func TestProduct_PriceIn(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1099))
+	p.SetPriceOverride(entity.Money{Amount: 199, Currency: "EUR"})
+	rate, _ := NewExchangeRate("USD", "BRL", "5")
+
+	price, err := p.PriceIn("BRL", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, brl(1099), price)
+
+	price, err = p.PriceIn("EUR", rate)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Money{Amount: 199, Currency: "EUR"}, price)
+
+	price, err = p.PriceIn("USD", rate)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Money{Amount: 220, Currency: "USD"}, price)
+
+	_, err = p.PriceIn("USD", nil)
+	assert.Equal(t, ErrNoExchangeRate, err)
+	_, err = p.PriceIn("GBP", rate)
+	assert.Equal(t, ErrNoExchangeRate, err)
+}
+
+func TestProductPrice_JSON(t *testing.T) {
+	data, err := json.Marshal(ProductPrice{Currency: "USD", Amount: 219})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"amount":"2.19","currency":"USD"}`, string(data))
+
+	var price ProductPrice
+	assert.Nil(t, json.Unmarshal(data, &price))
+	assert.Equal(t, ProductPrice{Currency: "USD", Amount: 219}, price)
+}
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Product statuses. Products are created as drafts and only published
+// products are listed to viewers.
+const (
+	ProductDraft     = "draft"
+	ProductPublished = "published"
+	ProductArchived  = "archived"
+)
+
+var (
+	ErrInvalidProductStatus    = errors.New("status must be draft, published or archived")
+	ErrInvalidStatusTransition = errors.New("product can't move to this status from its current one")
+)
+
+// productTransitions maps each status to the statuses a product may move to
+// from it.
+var productTransitions = map[string][]string{
+	ProductDraft:     {ProductPublished, ProductArchived},
+	ProductPublished: {ProductDraft, ProductArchived},
+	ProductArchived:  {ProductDraft},
+}
+
+func IsValidProductStatus(status string) bool {
+	_, ok := productTransitions[status]
+	return ok
+}
+
+// CanTransition tells whether a product may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, status := range productTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductStatusChange is an entry of the status history of a product.
+type ProductStatusChange struct {
+	ID         entity.ID `json:"id"`
+	ProductID  entity.ID `json:"product_id" gorm:"index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  string    `json:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+func NewProductStatusChange(productID entity.ID, fromStatus, toStatus, changedBy string) *ProductStatusChange {
+	return &ProductStatusChange{
+		ID:         entity.NewID(),
+		ProductID:  productID,
+		FromStatus: fromStatus,
+		ToStatus:   toStatus,
+		ChangedBy:  changedBy,
+		ChangedAt:  time.Now(),
+	}
+}
@@ -3,20 +3,25 @@ package entity
 import (
 	"testing"
 
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
 	"github.com/stretchr/testify/assert"
 )
 
+func brl(amount int64) entity.Money {
+	return entity.Money{Amount: amount, Currency: "BRL"}
+}
+
 func TestNewProduc(t *testing.T) {
-	p, err := NewProduct("Product 1", 10)
+	p, err := NewProduct("Product 1", brl(1000))
 	assert.Nil(t, err)
 	assert.NotNil(t, p)
 	assert.NotEmpty(t, p.ID)
 	assert.Equal(t, p.Name, "Product 1")
-	assert.Equal(t, p.Price, 10.)
+	assert.Equal(t, brl(1000), p.Price)
 }
 
 func TestProductNameIsRequired(t *testing.T) {
-	p, err := NewProduct("", 10.)
+	p, err := NewProduct("", brl(1000))
 	assert.NotNil(t, err)
 	assert.Equal(t, ErrNameIsRequired, err)
 	assert.EqualError(t, ErrNameIsRequired, err.Error())
@@ -24,7 +29,7 @@ func TestProductNameIsRequired(t *testing.T) {
 }
 
 func TestProductPriceIsRequired(t *testing.T) {
-	p, err := NewProduct("Product 1", 0.)
+	p, err := NewProduct("Product 1", brl(0))
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
 	assert.Equal(t, ErrPriceIsRequired, err)
@@ -32,16 +37,63 @@ func TestProductPriceIsRequired(t *testing.T) {
 }
 
 func TestProductPriceIsInvalid(t *testing.T) {
-	p, err := NewProduct("Product 1", -1.)
+	p, err := NewProduct("Product 1", brl(-100))
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
 	assert.Equal(t, ErrInvalidPrice, err)
 	assert.EqualError(t, ErrInvalidPrice, err.Error())
 }
 
+func TestProductCurrencyIsInvalid(t *testing.T) {
+	p, err := NewProduct("Product 1", entity.Money{Amount: 1000, Currency: "XYZ"})
+	assert.Nil(t, p)
+	assert.Equal(t, entity.ErrInvalidCurrency, err)
+}
+
 func TestProductValidate(t *testing.T) {
-	p, err := NewProduct("Product 1", 100)
+	p, err := NewProduct("Product 1", brl(10000))
 	assert.Nil(t, err)
 	assert.NotNil(t, p)
 	assert.Nil(t, p.Validate())
 }
+
+func TestProduct_AddAndRemoveTags(t *testing.T) {
+	p, err := NewProduct("Product 1", brl(1000))
+	assert.Nil(t, err)
+	p.AddTags([]Tag{{Name: "new"}, {Name: "sale"}})
+	p.AddTags([]Tag{{Name: "sale"}, {Name: "eco"}})
+	assert.Equal(t, []Tag{{Name: "new"}, {Name: "sale"}, {Name: "eco"}}, p.Tags)
+
+	assert.True(t, p.RemoveTag("sale"))
+	assert.False(t, p.RemoveTag("sale"))
+	assert.Equal(t, []Tag{{Name: "new"}, {Name: "eco"}}, p.Tags)
+}
+
+func TestProductLowStockThresholdIsInvalid(t *testing.T) {
+	p, err := NewProduct("Product 1", brl(1000))
+	assert.Nil(t, err)
+	p.LowStockThreshold = -1
+	assert.Equal(t, ErrInvalidLowStockThreshold, p.Validate())
+}
+
+func TestProductChangeStatus(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	assert.Equal(t, ProductDraft, p.Status)
+	assert.False(t, p.IsPublished())
+
+	assert.Nil(t, p.ChangeStatus(ProductPublished))
+	assert.True(t, p.IsPublished())
+	assert.Equal(t, ErrInvalidStatusTransition, p.ChangeStatus(ProductPublished))
+	assert.Nil(t, p.ChangeStatus(ProductArchived))
+	assert.Equal(t, ErrInvalidStatusTransition, p.ChangeStatus(ProductPublished))
+	assert.Equal(t, ErrInvalidProductStatus, p.ChangeStatus("deleted"))
+	assert.Equal(t, ProductArchived, p.Status)
+	assert.Nil(t, p.ChangeStatus(ProductDraft))
+	assert.Nil(t, p.ChangeStatus(ProductArchived))
+}
+
+func TestProductStatusIsValidated(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	p.Status = ""
+	assert.Equal(t, ErrInvalidProductStatus, p.Validate())
+}
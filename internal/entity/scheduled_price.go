@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Scheduled price statuses. A pending price becomes active once it takes
+// effect and completed when it expires, or right away when it has no end.
+const (
+	SchedulePending   = "pending"
+	ScheduleActive    = "active"
+	ScheduleCompleted = "completed"
+	ScheduleCancelled = "cancelled"
+)
+
+var (
+	ErrEffectiveFromIsRequired = errors.New("effective_from is required")
+	ErrInvalidEffectiveUntil   = errors.New("effective_until must be after effective_from and in the future")
+	ErrScheduleCurrency        = errors.New("scheduled price must use the base currency of the product")
+	ErrScheduleOverlaps        = errors.New("another scheduled price overlaps this period")
+	ErrScheduleNotPending      = errors.New("only pending scheduled prices can be cancelled")
+)
+
+// ScheduledPrice sets the price of a product from EffectiveFrom until
+// EffectiveUntil, when the price the product had before is restored. Without
+// EffectiveUntil the new price is kept.
+type ScheduledPrice struct {
+	ID             entity.ID    `json:"id"`
+	ProductID      entity.ID    `json:"product_id"`
+	Price          entity.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	EffectiveFrom  time.Time    `json:"effective_from"`
+	EffectiveUntil *time.Time   `json:"effective_until,omitempty"`
+	Status         string       `json:"status"`
+	// PreviousPrice is the price replaced when the schedule took effect.
+	PreviousPrice entity.Money `json:"-" gorm:"embedded;embeddedPrefix:previous_price_"`
+	CreatedBy     string       `json:"created_by"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// NewScheduledPrice schedules a price for product. Dates are kept in UTC so
+// they compare correctly in the database.
+func NewScheduledPrice(product *Product, price entity.Money, effectiveFrom time.Time, effectiveUntil *time.Time, createdBy string) (*ScheduledPrice, error) {
+	if effectiveUntil != nil {
+		until := effectiveUntil.UTC()
+		effectiveUntil = &until
+	}
+	scheduledPrice := &ScheduledPrice{
+		ID:             entity.NewID(),
+		ProductID:      product.ID,
+		Price:          price,
+		EffectiveFrom:  effectiveFrom.UTC(),
+		EffectiveUntil: effectiveUntil,
+		Status:         SchedulePending,
+		CreatedBy:      createdBy,
+		CreatedAt:      time.Now(),
+	}
+	if err := scheduledPrice.Validate(); err != nil {
+		return nil, err
+	}
+	if price.Currency != product.Price.Currency {
+		return nil, ErrScheduleCurrency
+	}
+	return scheduledPrice, nil
+}
+
+func (s *ScheduledPrice) Validate() error {
+	if err := s.Price.Validate(); err != nil {
+		return err
+	}
+	if s.Price.IsZero() {
+		return ErrPriceIsRequired
+	}
+	if s.Price.IsNegative() {
+		return ErrInvalidPrice
+	}
+	if s.EffectiveFrom.IsZero() {
+		return ErrEffectiveFromIsRequired
+	}
+	if s.EffectiveUntil != nil && (!s.EffectiveUntil.After(s.EffectiveFrom) || !s.EffectiveUntil.After(s.CreatedAt)) {
+		return ErrInvalidEffectiveUntil
+	}
+	return nil
+}
+
+// Overlaps tells whether the periods of both scheduled prices intersect.
+func (s *ScheduledPrice) Overlaps(other *ScheduledPrice) bool {
+	startsBeforeOtherEnds := other.EffectiveUntil == nil || s.EffectiveFrom.Before(*other.EffectiveUntil)
+	endsAfterOtherStarts := s.EffectiveUntil == nil || s.EffectiveUntil.After(other.EffectiveFrom)
+	return startsBeforeOtherEnds && endsAfterOtherStarts
+}
+
+func (s *ScheduledPrice) Cancel() error {
+	if s.Status != SchedulePending {
+		return ErrScheduleNotPending
+	}
+	s.Status = ScheduleCancelled
+	return nil
+}
+
+// Start applies the scheduled price to the product, returning the price
+// change to record.
+func (s *ScheduledPrice) Start(product *Product) *PriceChange {
+	s.PreviousPrice = product.Price
+	s.Status = ScheduleActive
+	if s.EffectiveUntil == nil {
+		s.Status = ScheduleCompleted
+	}
+	return s.changePrice(product, s.Price)
+}
+
+// End restores the price the product had before the schedule started. When
+// the price was changed by hand in the meantime it is kept and no change is
+// returned.
+func (s *ScheduledPrice) End(product *Product) *PriceChange {
+	s.Status = ScheduleCompleted
+	if product.Price != s.Price {
+		return nil
+	}
+	return s.changePrice(product, s.PreviousPrice)
+}
+
+func (s *ScheduledPrice) changePrice(product *Product, price entity.Money) *PriceChange {
+	change := NewPriceChange(product.ID, product.Price, price, PriceChangedByScheduler)
+	change.ScheduledPriceID = &s.ID
+	product.Price = price
+	product.UpdatedBy = PriceChangedByScheduler
+	return change
+}
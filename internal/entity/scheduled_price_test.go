@@ -0,0 +1,101 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScheduledPrice(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	from := time.Now().Add(time.Hour)
+	until := from.Add(24 * time.Hour)
+	s, err := NewScheduledPrice(p, brl(800), from, &until, "user-1")
+	assert.Nil(t, err)
+	assert.Equal(t, p.ID, s.ProductID)
+	assert.Equal(t, SchedulePending, s.Status)
+	assert.Equal(t, "user-1", s.CreatedBy)
+}
+
+func TestNewScheduledPrice_Invalid(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	from := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	_, err := NewScheduledPrice(p, brl(0), from, nil, "")
+	assert.Equal(t, ErrPriceIsRequired, err)
+	_, err = NewScheduledPrice(p, brl(-1), from, nil, "")
+	assert.Equal(t, ErrInvalidPrice, err)
+	_, err = NewScheduledPrice(p, brl(800), time.Time{}, nil, "")
+	assert.Equal(t, ErrEffectiveFromIsRequired, err)
+	_, err = NewScheduledPrice(p, brl(800), from, &from, "")
+	assert.Equal(t, ErrInvalidEffectiveUntil, err)
+	_, err = NewScheduledPrice(p, brl(800), past.Add(-time.Hour), &past, "")
+	assert.Equal(t, ErrInvalidEffectiveUntil, err)
+	_, err = NewScheduledPrice(p, entity.Money{Amount: 800, Currency: "USD"}, from, nil, "")
+	assert.Equal(t, ErrScheduleCurrency, err)
+}
+
+func TestScheduledPrice_Overlaps(t *testing.T) {
+	day := func(d int) *time.Time {
+		date := time.Date(2030, 1, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	schedule := func(from *time.Time, until *time.Time) *ScheduledPrice {
+		return &ScheduledPrice{EffectiveFrom: *from, EffectiveUntil: until}
+	}
+	assert.True(t, schedule(day(1), day(10)).Overlaps(schedule(day(5), day(15))))
+	assert.True(t, schedule(day(1), nil).Overlaps(schedule(day(5), day(15))))
+	assert.True(t, schedule(day(20), nil).Overlaps(schedule(day(5), nil)))
+	assert.False(t, schedule(day(1), day(5)).Overlaps(schedule(day(5), day(15))))
+	assert.False(t, schedule(day(15), nil).Overlaps(schedule(day(5), day(15))))
+}
+
+func TestScheduledPrice_StartAndEnd(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	until := time.Now().Add(time.Hour)
+	s, _ := NewScheduledPrice(p, brl(800), time.Now(), &until, "user-1")
+
+	change := s.Start(p)
+	assert.Equal(t, ScheduleActive, s.Status)
+	assert.Equal(t, brl(800), p.Price)
+	assert.Equal(t, brl(1000), change.OldPrice)
+	assert.Equal(t, brl(800), change.NewPrice)
+	assert.Equal(t, PriceChangedByScheduler, change.ChangedBy)
+	assert.Equal(t, s.ID, *change.ScheduledPriceID)
+
+	change = s.End(p)
+	assert.Equal(t, ScheduleCompleted, s.Status)
+	assert.Equal(t, brl(1000), p.Price)
+	assert.Equal(t, brl(800), change.OldPrice)
+	assert.Equal(t, brl(1000), change.NewPrice)
+}
+
+func TestScheduledPrice_EndKeepsManualPrice(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	until := time.Now().Add(time.Hour)
+	s, _ := NewScheduledPrice(p, brl(800), time.Now(), &until, "user-1")
+	s.Start(p)
+	p.Price = brl(900)
+
+	assert.Nil(t, s.End(p))
+	assert.Equal(t, ScheduleCompleted, s.Status)
+	assert.Equal(t, brl(900), p.Price)
+}
+
+func TestScheduledPrice_StartWithoutEnd(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	s, _ := NewScheduledPrice(p, brl(800), time.Now(), nil, "user-1")
+	s.Start(p)
+	assert.Equal(t, ScheduleCompleted, s.Status)
+	assert.Equal(t, brl(800), p.Price)
+}
+
+func TestScheduledPrice_Cancel(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	s, _ := NewScheduledPrice(p, brl(800), time.Now(), nil, "user-1")
+	assert.Nil(t, s.Cancel())
+	assert.Equal(t, ScheduleCancelled, s.Status)
+	assert.Equal(t, ErrScheduleNotPending, s.Cancel())
+}
@@ -0,0 +1,115 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Stock movement types. Receipts, sales, reservations and releases take a
+// positive quantity while adjustments are signed.
+const (
+	MovementReceipt     = "receipt"
+	MovementAdjustment  = "adjustment"
+	MovementSale        = "sale"
+	MovementReservation = "reservation"
+	MovementRelease     = "release"
+)
+
+var (
+	ErrInvalidMovementType     = errors.New("movement type must be receipt, adjustment, sale, reservation or release")
+	ErrInvalidMovementQuantity = errors.New("invalid movement quantity")
+	ErrReasonIsRequired        = errors.New("reason is required for adjustments")
+	ErrInsufficientStock       = errors.New("insufficient stock")
+	ErrInsufficientReserved    = errors.New("can't release more than is reserved")
+)
+
+// StockMovement is an entry of the append-only stock ledger of a product.
+// StockAfter and ReservedAfter record the product quantities once the
+// movement was applied.
+type StockMovement struct {
+	ID            entity.ID `json:"id"`
+	ProductID     entity.ID `json:"product_id"`
+	Type          string    `json:"type"`
+	Quantity      int       `json:"quantity"`
+	Reason        string    `json:"reason"`
+	StockAfter    int       `json:"stock_after"`
+	ReservedAfter int       `json:"reserved_after"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func NewStockMovement(productID entity.ID, movementType string, quantity int, reason, createdBy string) (*StockMovement, error) {
+	movement := &StockMovement{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		Type:      movementType,
+		Quantity:  quantity,
+		Reason:    reason,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	if err := movement.Validate(); err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+func (m *StockMovement) Validate() error {
+	switch m.Type {
+	case MovementAdjustment:
+		if m.Quantity == 0 {
+			return ErrInvalidMovementQuantity
+		}
+		if m.Reason == "" {
+			return ErrReasonIsRequired
+		}
+	case MovementReceipt, MovementSale, MovementReservation, MovementRelease:
+		if m.Quantity <= 0 {
+			return ErrInvalidMovementQuantity
+		}
+	default:
+		return ErrInvalidMovementType
+	}
+	return nil
+}
+
+// ApplyMovement updates the stock quantities of the product and records
+// the resulting quantities in the movement. Stock on hand can never drop
+// below what is reserved.
+func (p *Product) ApplyMovement(m *StockMovement) error {
+	stock, reserved := p.Stock, p.Reserved
+	switch m.Type {
+	case MovementReceipt:
+		stock += m.Quantity
+	case MovementAdjustment:
+		stock += m.Quantity
+	case MovementSale:
+		stock -= m.Quantity
+	case MovementReservation:
+		reserved += m.Quantity
+	case MovementRelease:
+		if m.Quantity > reserved {
+			return ErrInsufficientReserved
+		}
+		reserved -= m.Quantity
+	default:
+		return ErrInvalidMovementType
+	}
+	if stock < reserved {
+		return ErrInsufficientStock
+	}
+	p.Stock, p.Reserved = stock, reserved
+	m.StockAfter, m.ReservedAfter = stock, reserved
+	return nil
+}
+
+// Available is the quantity that can still be sold or reserved.
+func (p *Product) Available() int {
+	return p.Stock - p.Reserved
+}
+
+func (p *Product) IsLowStock() bool {
+	return p.Available() <= p.LowStockThreshold
+}
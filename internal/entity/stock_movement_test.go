@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStockMovement(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	m, err := NewStockMovement(p.ID, MovementReceipt, 5, "", "user-1")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, m.ID)
+	assert.Equal(t, p.ID, m.ProductID)
+	assert.Equal(t, "user-1", m.CreatedBy)
+}
+
+func TestNewStockMovement_Validation(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	_, err := NewStockMovement(p.ID, "theft", 1, "", "user-1")
+	assert.Equal(t, ErrInvalidMovementType, err)
+	_, err = NewStockMovement(p.ID, MovementSale, -1, "", "user-1")
+	assert.Equal(t, ErrInvalidMovementQuantity, err)
+	_, err = NewStockMovement(p.ID, MovementAdjustment, 0, "count", "user-1")
+	assert.Equal(t, ErrInvalidMovementQuantity, err)
+	_, err = NewStockMovement(p.ID, MovementAdjustment, -2, "", "user-1")
+	assert.Equal(t, ErrReasonIsRequired, err)
+	_, err = NewStockMovement(p.ID, MovementAdjustment, -2, "broken items", "user-1")
+	assert.Nil(t, err)
+}
+
+func TestProduct_ApplyMovement(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	apply := func(movementType string, quantity int, reason string) error {
+		m, err := NewStockMovement(p.ID, movementType, quantity, reason, "user-1")
+		assert.Nil(t, err)
+		return p.ApplyMovement(m)
+	}
+
+	assert.Nil(t, apply(MovementReceipt, 10, ""))
+	assert.Nil(t, apply(MovementReservation, 4, ""))
+	assert.Equal(t, 10, p.Stock)
+	assert.Equal(t, 4, p.Reserved)
+	assert.Equal(t, 6, p.Available())
+
+	assert.Equal(t, ErrInsufficientStock, apply(MovementSale, 7, ""))
+	assert.Equal(t, ErrInsufficientStock, apply(MovementReservation, 7, ""))
+	assert.Equal(t, ErrInsufficientStock, apply(MovementAdjustment, -7, "count"))
+	assert.Equal(t, ErrInsufficientReserved, apply(MovementRelease, 5, ""))
+	assert.Equal(t, 10, p.Stock)
+	assert.Equal(t, 4, p.Reserved)
+
+	assert.Nil(t, apply(MovementRelease, 4, ""))
+	assert.Nil(t, apply(MovementSale, 8, ""))
+	assert.Nil(t, apply(MovementAdjustment, -1, "damaged"))
+	assert.Equal(t, 1, p.Stock)
+	assert.Equal(t, 0, p.Reserved)
+}
+
+func TestProduct_IsLowStock(t *testing.T) {
+	p, _ := NewProduct("Product 1", brl(1000))
+	p.LowStockThreshold = 2
+	p.Stock = 5
+	assert.False(t, p.IsLowStock())
+	p.Reserved = 3
+	assert.True(t, p.IsLowStock())
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxTagLength = 50
+
+var ErrInvalidTag = errors.New("tags must have up to 50 letters, digits, spaces, '-' or '_'")
+
+// Tag is a free-form label attached to products. It is encoded in JSON as a
+// plain string.
+type Tag struct {
+	Name string `gorm:"primaryKey"`
+}
+
+// TagUsage tells how many products carry a tag.
+type TagUsage struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.Name)
+}
+
+// NormalizeTag lowercases a tag and replaces inner whitespace with a single
+// dash, so "Summer  Sale" and "summer-sale" are the same tag.
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if normalized == "" || utf8.RuneCountInString(normalized) > maxTagLength {
+		return "", ErrInvalidTag
+	}
+	for _, r := range normalized {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", ErrInvalidTag
+		}
+	}
+	return normalized, nil
+}
+
+// NormalizeTags normalizes every tag and drops duplicates, keeping the order
+// in which tags first appear.
+func NormalizeTags(tags []string) ([]Tag, error) {
+	seen := make(map[string]bool, len(tags))
+	result := []Tag{}
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		result = append(result, Tag{Name: normalized})
+	}
+	return result, nil
+}
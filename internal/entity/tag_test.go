@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Summer  Sale ", "summer-sale", "NEW", "eco_friendly", "new"})
+	assert.Nil(t, err)
+	assert.Equal(t, []Tag{{Name: "summer-sale"}, {Name: "new"}, {Name: "eco_friendly"}}, tags)
+
+	tags, err = NormalizeTags(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, tags)
+}
+
+func TestNormalizeTags_Invalid(t *testing.T) {
+	for _, tag := range []string{"", "   ", "50% off", "a/b", "this-tag-is-way-too-long-to-be-accepted-by-the-catalogue"} {
+		tags, err := NormalizeTags([]string{tag})
+		assert.Equal(t, ErrInvalidTag, err, tag)
+		assert.Nil(t, tags)
+	}
+}
+
+func TestTag_JSON(t *testing.T) {
+	data, err := json.Marshal([]Tag{{Name: "new"}, {Name: "sale"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `["new","sale"]`, string(data))
+
+	var tags []Tag
+	assert.Nil(t, json.Unmarshal(data, &tags))
+	assert.Equal(t, []Tag{{Name: "new"}, {Name: "sale"}}, tags)
+}
@@ -2,20 +2,40 @@ package entity
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
 var (
 	errPasswordRequired = errors.New("password field is required")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrUserDisabled     = errors.New("user is disabled")
 )
 
+// roleScopes maps each role to the scopes granted to its access tokens.
+var roleScopes = map[string][]string{
+	RoleAdmin:  {ScopeProductsRead, ScopeProductsWrite},
+	RoleEditor: {ScopeProductsRead, ScopeProductsWrite},
+	RoleViewer: {ScopeProductsRead},
+}
+
 type User struct {
-	ID       entity.ID `json:"id"`
-	Name     string    `json:"name"`
-	Email    string    `json:"email"`
-	Password string    `json:"-"`
+	ID        entity.ID `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Password  string    `json:"-"`
+	Role      string    `json:"role" gorm:"default:editor"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 func NewUser(name, email, password string) (*User, error) {
@@ -30,13 +50,40 @@ func NewUser(name, email, password string) (*User, error) {
 	}
 
 	return &User{
-		ID:       entity.NewID(),
-		Name:     name,
-		Email:    email,
-		Password: string(hash),
+		ID:        entity.NewID(),
+		Name:      name,
+		Email:     email,
+		Password:  string(hash),
+		Role:      RoleEditor,
+		CreatedAt: time.Now(),
 	}, nil
 }
 
 func (u *User) ValidatePassword(password string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
 }
+
+func (u *User) ChangeRole(role string) error {
+	if _, ok := roleScopes[role]; !ok {
+		return ErrInvalidRole
+	}
+	u.Role = role
+	return nil
+}
+
+func (u *User) Disable() {
+	u.Disabled = true
+}
+
+func (u *User) Enable() {
+	u.Disabled = false
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// Scope returns the space-delimited scopes granted to the user's role.
+func (u *User) Scope() string {
+	return strings.Join(roleScopes[u.Role], " ")
+}
@@ -23,3 +23,27 @@ func TestUser_ValidatePassword(t *testing.T) {
 	assert.False(t, user.ValidatePassword("1234567"))
 	assert.NotEqual(t, user.Password, "123456")
 }
+
+func TestUser_ChangeRole(t *testing.T) {
+	user, err := NewUser("Matheus", "matheus@abc.com", "123456")
+	assert.Nil(t, err)
+	assert.Equal(t, RoleEditor, user.Role)
+	assert.Equal(t, "products:read products:write", user.Scope())
+
+	assert.Nil(t, user.ChangeRole(RoleViewer))
+	assert.Equal(t, RoleViewer, user.Role)
+	assert.Equal(t, "products:read", user.Scope())
+
+	assert.Equal(t, ErrInvalidRole, user.ChangeRole("root"))
+	assert.Equal(t, RoleViewer, user.Role)
+}
+
+func TestUser_DisableAndEnable(t *testing.T) {
+	user, err := NewUser("Matheus", "matheus@abc.com", "123456")
+	assert.Nil(t, err)
+	assert.False(t, user.Disabled)
+	user.Disable()
+	assert.True(t, user.Disabled)
+	user.Enable()
+	assert.False(t, user.Disabled)
+}
@@ -0,0 +1,166 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+var (
+	ErrSKUIsRequired           = errors.New("sku is required")
+	ErrInvalidSKU              = errors.New("sku may only have letters, digits, '-', '_' and '.' and up to 64 characters")
+	ErrInvalidBarcode          = errors.New("barcode must be a valid GTIN-8, UPC-A, EAN-13 or GTIN-14")
+	ErrOptionsAreRequired      = errors.New("a variant needs at least one option")
+	ErrInvalidOption           = errors.New("option names and values can't be empty")
+	ErrVariantPriceCurrency    = errors.New("variant price must use the base currency of the product")
+	ErrDuplicateSKU            = errors.New("sku is already in use")
+	ErrDuplicateBarcode        = errors.New("barcode is already in use")
+	ErrDuplicateVariantOptions = errors.New("the product already has a variant with these options")
+)
+
+// Variant is a sellable version of a product, such as a t-shirt in a given
+// size and color. SKU and barcode are unique across every product. Price
+// overrides the price of the product when set.
+type Variant struct {
+	ID        entity.ID         `json:"id"`
+	ProductID entity.ID         `json:"product_id" gorm:"index"`
+	SKU       string            `json:"sku" gorm:"uniqueIndex"`
+	Barcode   *string           `json:"barcode,omitempty" gorm:"uniqueIndex"`
+	Options   map[string]string `json:"options" gorm:"serializer:json"`
+	Price     *entity.Money     `json:"price,omitempty" gorm:"serializer:json"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func NewVariant(product *Product, sku string, barcode *string, options map[string]string, price *entity.Money) (*Variant, error) {
+	variant := &Variant{
+		ID:        entity.NewID(),
+		ProductID: product.ID,
+		CreatedAt: time.Now(),
+	}
+	if err := variant.Change(product, sku, barcode, options, price); err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// Change normalizes and validates the new SKU, barcode, options and price
+// of the variant, leaving it untouched when they are invalid.
+func (v *Variant) Change(product *Product, sku string, barcode *string, options map[string]string, price *entity.Money) error {
+	changed := *v
+	changed.SKU = NormalizeSKU(sku)
+	changed.Barcode = nil
+	if barcode != nil && strings.TrimSpace(*barcode) != "" {
+		trimmed := strings.TrimSpace(*barcode)
+		changed.Barcode = &trimmed
+	}
+	changed.Options = normalizeOptions(options)
+	changed.Price = price
+	if err := changed.Validate(); err != nil {
+		return err
+	}
+	if price != nil && price.Currency != product.Price.Currency {
+		return ErrVariantPriceCurrency
+	}
+	*v = changed
+	return nil
+}
+
+func (v *Variant) Validate() error {
+	if v.SKU == "" {
+		return ErrSKUIsRequired
+	}
+	if len(v.SKU) > 64 {
+		return ErrInvalidSKU
+	}
+	for _, r := range v.SKU {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return ErrInvalidSKU
+		}
+	}
+	if v.Barcode != nil && !IsValidBarcode(*v.Barcode) {
+		return ErrInvalidBarcode
+	}
+	if len(v.Options) == 0 {
+		return ErrOptionsAreRequired
+	}
+	for name, value := range v.Options {
+		if name == "" || value == "" {
+			return ErrInvalidOption
+		}
+	}
+	if v.Price != nil {
+		if err := v.Price.Validate(); err != nil {
+			return err
+		}
+		if v.Price.IsZero() {
+			return ErrPriceIsRequired
+		}
+		if v.Price.IsNegative() {
+			return ErrInvalidPrice
+		}
+	}
+	return nil
+}
+
+// SameOptions tells whether both variants have the same option values.
+func (v *Variant) SameOptions(other *Variant) bool {
+	if len(v.Options) != len(other.Options) {
+		return false
+	}
+	for name, value := range v.Options {
+		if other.Options[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// EffectivePrice is the price of the variant, falling back to the price of
+// its product.
+func (v *Variant) EffectivePrice(product *Product) entity.Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
+
+// NormalizeSKU trims and uppercases a SKU code.
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+// IsValidBarcode checks the length and check digit of a GTIN barcode.
+func IsValidBarcode(barcode string) bool {
+	switch len(barcode) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	sum := 0
+	for i, r := range barcode {
+		if r < '0' || r > '9' {
+			return false
+		}
+		digit := int(r - '0')
+		// Weights alternate 3 and 1 from the rightmost digit before the check digit.
+		if (len(barcode)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		if i < len(barcode)-1 {
+			sum += digit
+		}
+	}
+	check := (10 - sum%10) % 10
+	return int(barcode[len(barcode)-1]-'0') == check
+}
+
+// normalizeOptions lowercases option names and trims names and values.
+func normalizeOptions(options map[string]string) map[string]string {
+	normalized := make(map[string]string, len(options))
+	for name, value := range options {
+		normalized[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return normalized
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVariant(t *testing.T) {
+	p, _ := NewProduct("T-shirt", brl(4990))
+	barcode := " 4006381333931 "
+	price := brl(5490)
+	v, err := NewVariant(p, " tshirt-blue-xl ", &barcode, map[string]string{" Size ": "XL ", "Color": "Blue"}, &price)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, v.ID)
+	assert.Equal(t, p.ID, v.ProductID)
+	assert.Equal(t, "TSHIRT-BLUE-XL", v.SKU)
+	assert.Equal(t, "4006381333931", *v.Barcode)
+	assert.Equal(t, map[string]string{"size": "XL", "color": "Blue"}, v.Options)
+	assert.Equal(t, brl(5490), v.EffectivePrice(p))
+}
+
+func TestNewVariant_Invalid(t *testing.T) {
+	p, _ := NewProduct("T-shirt", brl(4990))
+	options := map[string]string{"size": "M"}
+	badBarcode := "4006381333932"
+	usd := entity.Money{Amount: 1000, Currency: "USD"}
+	zero := brl(0)
+
+	_, err := NewVariant(p, " ", nil, options, nil)
+	assert.Equal(t, ErrSKUIsRequired, err)
+	_, err = NewVariant(p, "tshirt m", nil, options, nil)
+	assert.Equal(t, ErrInvalidSKU, err)
+	_, err = NewVariant(p, "TSHIRT-M", &badBarcode, options, nil)
+	assert.Equal(t, ErrInvalidBarcode, err)
+	_, err = NewVariant(p, "TSHIRT-M", nil, nil, nil)
+	assert.Equal(t, ErrOptionsAreRequired, err)
+	_, err = NewVariant(p, "TSHIRT-M", nil, map[string]string{"size": " "}, nil)
+	assert.Equal(t, ErrInvalidOption, err)
+	_, err = NewVariant(p, "TSHIRT-M", nil, options, &zero)
+	assert.Equal(t, ErrPriceIsRequired, err)
+	_, err = NewVariant(p, "TSHIRT-M", nil, options, &usd)
+	assert.Equal(t, ErrVariantPriceCurrency, err)
+}
+
+func TestVariant_ChangeKeepsVariantWhenInvalid(t *testing.T) {
+	p, _ := NewProduct("T-shirt", brl(4990))
+	v, _ := NewVariant(p, "TSHIRT-M", nil, map[string]string{"size": "M"}, nil)
+	assert.Equal(t, ErrInvalidSKU, v.Change(p, "bad sku", nil, map[string]string{"size": "L"}, nil))
+	assert.Equal(t, "TSHIRT-M", v.SKU)
+	assert.Equal(t, "M", v.Options["size"])
+	assert.Equal(t, brl(4990), v.EffectivePrice(p))
+}
+
+func TestVariant_SameOptions(t *testing.T) {
+	a := &Variant{Options: map[string]string{"size": "M", "color": "blue"}}
+	assert.True(t, a.SameOptions(&Variant{Options: map[string]string{"color": "blue", "size": "M"}}))
+	assert.False(t, a.SameOptions(&Variant{Options: map[string]string{"size": "M"}}))
+	assert.False(t, a.SameOptions(&Variant{Options: map[string]string{"size": "M", "color": "red"}}))
+}
+
+func TestIsValidBarcode(t *testing.T) {
+	for _, barcode := range []string{"4006381333931", "036000291452", "96385074", "10614141000415"} {
+		assert.True(t, IsValidBarcode(barcode), barcode)
+	}
+	for _, barcode := range []string{"", "123", "4006381333930", "40063813339a1", "0360002914521"} {
+		assert.False(t, IsValidBarcode(barcode), barcode)
+	}
+}
+
+func TestProduct_ValidateVariantPriceCurrency(t *testing.T) {
+	p, _ := NewProduct("T-shirt", brl(4990))
+	price := brl(5490)
+	v, _ := NewVariant(p, "TSHIRT-M", nil, map[string]string{"size": "M"}, &price)
+	p.Variants = []Variant{*v}
+	p.Price = entity.Money{Amount: 999, Currency: "USD"}
+	assert.Equal(t, ErrVariantPriceCurrency, p.Validate())
+}
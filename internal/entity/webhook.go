@@ -0,0 +1,223 @@
+package entity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// EventWebhookPing is the type of the deliveries sent to test a webhook.
+const EventWebhookPing = "webhook.ping"
+
+// WebhookAllEvents subscribes a webhook to every event type.
+const WebhookAllEvents = "*"
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// minWebhookSecretLength is the shortest secret a webhook can be given.
+const minWebhookSecretLength = 16
+
+// WebhookEventTypes lists the event types webhooks can subscribe to.
+var WebhookEventTypes = []string{EventProductCreated, EventProductUpdated, EventProductDeleted, EventUserRegistered}
+
+var (
+	ErrInvalidWebhookURL     = errors.New("url must be an absolute http or https URL")
+	ErrWebhookEventsRequired = errors.New("event_types is required")
+	ErrInvalidWebhookEvent   = errors.New("invalid event type")
+	ErrWebhookSecretTooShort = errors.New("secret must have at least 16 characters")
+	ErrDeliveryNotFinished   = errors.New("delivery is still pending")
+)
+
+// Webhook sends the events of EventTypes to URL as they happen. Deliveries
+// are signed with Secret, which is only shown when the webhook is created.
+type Webhook struct {
+	ID         entity.ID `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types" gorm:"serializer:json"`
+	Secret     string    `json:"-"`
+	Active     bool      `json:"active"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// NewWebhook subscribes url to the event types, generating a secret when
+// none is given.
+func NewWebhook(rawURL string, eventTypes []string, secret, createdBy string) (*Webhook, error) {
+	if secret == "" {
+		var err error
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+	w := &Webhook{
+		ID:        entity.NewID(),
+		Active:    true,
+		CreatedBy: createdBy,
+	}
+	if err := w.Change(rawURL, eventTypes, secret); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Change points the webhook at url and the event types, and replaces its
+// secret unless it is empty.
+func (w *Webhook) Change(rawURL string, eventTypes []string, secret string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if len(eventTypes) == 0 {
+		return ErrWebhookEventsRequired
+	}
+	for _, eventType := range eventTypes {
+		if eventType != WebhookAllEvents && !containsString(WebhookEventTypes, eventType) {
+			return ErrInvalidWebhookEvent
+		}
+	}
+	if secret != "" && len(secret) < minWebhookSecretLength {
+		return ErrWebhookSecretTooShort
+	}
+	w.URL = rawURL
+	w.EventTypes = eventTypes
+	if secret != "" {
+		w.Secret = secret
+	}
+	return nil
+}
+
+// Wants tells whether the webhook is subscribed to events of the type.
+func (w *Webhook) Wants(eventType string) bool {
+	return w.Active && (containsString(w.EventTypes, WebhookAllEvents) || containsString(w.EventTypes, eventType))
+}
+
+// Sign computes the signature of a delivery sent at timestamp, the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret. Receivers
+// compute it the same way and reject old timestamps to prevent replays.
+func (w *Webhook) Sign(timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewPingEvent is the event sent to test the webhook, whatever the event
+// types it is subscribed to.
+func (w *Webhook) NewPingEvent() *Event {
+	return &Event{
+		ID:         entity.NewID(),
+		Type:       EventWebhookPing,
+		EntityID:   w.ID.String(),
+		Payload:    json.RawMessage(`{}`),
+		OccurredAt: time.Now(),
+	}
+}
+
+// WebhookDelivery is an event sent, or to be sent, to a webhook, with the
+// outcome of the last attempt.
+type WebhookDelivery struct {
+	ID            entity.ID  `json:"id"`
+	WebhookID     entity.ID  `json:"webhook_id" gorm:"uniqueIndex:idx_delivery_event;index:idx_delivery_webhook"`
+	EventID       string     `json:"event_id" gorm:"uniqueIndex:idx_delivery_event"`
+	EventType     string     `json:"event_type"`
+	Body          string     `json:"-"`
+	Status        string     `json:"status" gorm:"index"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" gorm:"index"`
+	// ResponseStatus and ResponseBody are what the last attempt got back,
+	// and Error why it failed when there was no response.
+	ResponseStatus int        `json:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index:idx_delivery_webhook"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// NewWebhookDelivery queues body, the event eventID of type eventType, to
+// be sent to the webhook right away.
+func NewWebhookDelivery(webhookID entity.ID, eventID, eventType string, body []byte) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		ID:            entity.NewID(),
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Body:          string(body),
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+}
+
+// Succeed records an attempt that got a 2xx response.
+func (d *WebhookDelivery) Succeed(status int, body string, at time.Time) {
+	d.Attempts++
+	d.Status = DeliverySucceeded
+	d.ResponseStatus = status
+	d.ResponseBody = body
+	d.Error = ""
+	d.NextAttemptAt = nil
+	d.DeliveredAt = &at
+}
+
+// Fail records an attempt that got any other response, or none when status
+// is zero, scheduling the next attempt after backoff. The delivery fails
+// for good when backoff is zero.
+func (d *WebhookDelivery) Fail(status int, body, reason string, at time.Time, backoff time.Duration) {
+	d.Attempts++
+	d.ResponseStatus = status
+	d.ResponseBody = body
+	d.Error = reason
+	if backoff <= 0 {
+		d.Status = DeliveryFailed
+		d.NextAttemptAt = nil
+		return
+	}
+	next := at.Add(backoff)
+	d.NextAttemptAt = &next
+}
+
+// Replay queues a finished delivery to be sent again right away, with a
+// fresh set of attempts.
+func (d *WebhookDelivery) Replay() error {
+	if d.Status == DeliveryPending {
+		return ErrDeliveryNotFinished
+	}
+	now := time.Now()
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = &now
+	d.DeliveredAt = nil
+	return nil
+}
+
+// WebhookBackoff tells how long to wait after the attempt-th failed attempt
+// to deliver an event: base doubling on every attempt, up to max. It is
+// zero once maxAttempts were made.
+func WebhookBackoff(attempt, maxAttempts int, base, max time.Duration) time.Duration {
+	if attempt >= maxAttempts {
+		return 0
+	}
+	backoff := base
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
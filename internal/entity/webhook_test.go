@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWebhook(t *testing.T) {
+	w, err := NewWebhook("https://partner.example.com/hooks", []string{EventProductCreated}, "", "user-1")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, w.ID)
+	assert.True(t, w.Active)
+	assert.NotEmpty(t, w.Secret)
+	assert.Equal(t, "user-1", w.CreatedBy)
+
+	w, err = NewWebhook("https://partner.example.com/hooks", []string{WebhookAllEvents}, "0123456789abcdef", "user-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "0123456789abcdef", w.Secret)
+
+	_, err = NewWebhook("ftp://partner.example.com", []string{EventProductCreated}, "", "user-1")
+	assert.Equal(t, ErrInvalidWebhookURL, err)
+	_, err = NewWebhook("/hooks", []string{EventProductCreated}, "", "user-1")
+	assert.Equal(t, ErrInvalidWebhookURL, err)
+	_, err = NewWebhook("https://partner.example.com", nil, "", "user-1")
+	assert.Equal(t, ErrWebhookEventsRequired, err)
+	_, err = NewWebhook("https://partner.example.com", []string{"product.renamed"}, "", "user-1")
+	assert.Equal(t, ErrInvalidWebhookEvent, err)
+	_, err = NewWebhook("https://partner.example.com", []string{EventProductCreated}, "short", "user-1")
+	assert.Equal(t, ErrWebhookSecretTooShort, err)
+}
+
+func TestWebhook_Change(t *testing.T) {
+	w, _ := NewWebhook("https://partner.example.com/hooks", []string{EventProductCreated}, "0123456789abcdef", "user-1")
+	assert.Nil(t, w.Change("https://partner.example.com/v2", []string{EventProductDeleted}, ""))
+	assert.Equal(t, "https://partner.example.com/v2", w.URL)
+	assert.Equal(t, "0123456789abcdef", w.Secret)
+	assert.Equal(t, ErrInvalidWebhookURL, w.Change("partner", []string{EventProductDeleted}, ""))
+}
+
+func TestWebhook_Wants(t *testing.T) {
+	w, _ := NewWebhook("https://partner.example.com/hooks", []string{EventProductCreated}, "", "user-1")
+	assert.True(t, w.Wants(EventProductCreated))
+	assert.False(t, w.Wants(EventProductDeleted))
+	w.EventTypes = []string{WebhookAllEvents}
+	assert.True(t, w.Wants(EventUserRegistered))
+	w.Active = false
+	assert.False(t, w.Wants(EventUserRegistered))
+}
+
+func TestWebhook_Sign(t *testing.T) {
+	w, _ := NewWebhook("https://partner.example.com/hooks", []string{EventProductCreated}, "0123456789abcdef", "user-1")
+	timestamp := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"product.created"}`)
+
+	mac := hmac.New(sha256.New, []byte("0123456789abcdef"))
+	mac.Write([]byte(`1700000000.{"type":"product.created"}`))
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), w.Sign(timestamp, body))
+	assert.NotEqual(t, w.Sign(timestamp, body), w.Sign(timestamp.Add(time.Second), body))
+}
+
+func TestWebhook_NewPingEvent(t *testing.T) {
+	w, _ := NewWebhook("https://partner.example.com/hooks", []string{EventProductCreated}, "", "user-1")
+	event := w.NewPingEvent()
+	assert.Equal(t, EventWebhookPing, event.Type)
+	assert.Equal(t, w.ID.String(), event.EntityID)
+	assert.NotEqual(t, event.ID, w.NewPingEvent().ID)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	w, _ := NewWebhook("https://partner.example.com/hooks", []string{EventProductCreated}, "", "user-1")
+	d := NewWebhookDelivery(w.ID, "event-1", EventProductCreated, []byte(`{}`))
+	assert.Equal(t, DeliveryPending, d.Status)
+	assert.NotNil(t, d.NextAttemptAt)
+	assert.Equal(t, ErrDeliveryNotFinished, d.Replay())
+
+	now := time.Now()
+	d.Fail(503, "unavailable", "", now, time.Minute)
+	assert.Equal(t, DeliveryPending, d.Status)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, now.Add(time.Minute), *d.NextAttemptAt)
+
+	d.Fail(0, "", "connection refused", now, 0)
+	assert.Equal(t, DeliveryFailed, d.Status)
+	assert.Equal(t, "connection refused", d.Error)
+	assert.Nil(t, d.NextAttemptAt)
+
+	assert.Nil(t, d.Replay())
+	assert.Equal(t, DeliveryPending, d.Status)
+	assert.Equal(t, 0, d.Attempts)
+	d.Succeed(200, "ok", now)
+	assert.Equal(t, DeliverySucceeded, d.Status)
+	assert.Equal(t, 200, d.ResponseStatus)
+	assert.Equal(t, "", d.Error)
+	assert.NotNil(t, d.DeliveredAt)
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, WebhookBackoff(1, 5, time.Minute, time.Hour))
+	assert.Equal(t, 2*time.Minute, WebhookBackoff(2, 5, time.Minute, time.Hour))
+	assert.Equal(t, 8*time.Minute, WebhookBackoff(4, 5, time.Minute, time.Hour))
+	assert.Equal(t, time.Duration(0), WebhookBackoff(5, 5, time.Minute, time.Hour))
+	assert.Equal(t, time.Hour, WebhookBackoff(20, 30, time.Minute, time.Hour))
+}
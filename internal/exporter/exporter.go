@@ -0,0 +1,189 @@
+// Package exporter writes products as CSV, NDJSON or XLSX files, for
+// downloads and for export jobs alike.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/spreadsheet"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+)
+
+const (
+	CSV    = "csv"
+	NDJSON = "ndjson"
+	XLSX   = "xlsx"
+
+	NDJSONType = "application/x-ndjson"
+
+	// batchSize is how many products are read from the database at a time.
+	batchSize = 500
+)
+
+var ErrInvalidFormat = errors.New("format must be csv, ndjson or xlsx")
+
+// ContentTypes are the content types of the formats.
+var ContentTypes = map[string]string{
+	CSV:    spreadsheet.CSVType,
+	NDJSON: NDJSONType,
+	XLSX:   spreadsheet.XLSXType,
+}
+
+// Columns are the columns of CSV and XLSX exports. name, price, currency,
+// low_stock_threshold and tags can be imported back.
+var Columns = []string{
+	"id", "name", "status", "price", "currency", "stock", "reserved", "low_stock_threshold",
+	"tags", "category_ids", "created_at", "updated_at", "version",
+}
+
+// Exporter writes products in a format.
+type Exporter interface {
+	Write(product *entity.Product) error
+	// Flush writes what is buffered to the underlying writer.
+	Flush() error
+	Close() error
+}
+
+// New starts an export in format to w. NDJSON exports hold every field of
+// the products, with the URLs of their images in store.
+func New(w io.Writer, format string, store storage.Storage) (Exporter, error) {
+	switch format {
+	case CSV:
+		writer := csv.NewWriter(w)
+		return &csvExporter{writer: writer}, writer.Write(Columns)
+	case NDJSON:
+		return &ndjsonExporter{encoder: json.NewEncoder(w), storage: store}, nil
+	case XLSX:
+		writer, err := spreadsheet.NewXLSXWriter(w, "Products")
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxExporter{writer: writer}, writer.WriteRow(stringsToValues(Columns))
+	}
+	return nil, ErrInvalidFormat
+}
+
+// Export writes every product that matches the filter, oldest first, and
+// closes the exporter. afterBatch is called with how many products were
+// written so far each time a batch was flushed.
+func Export(productDB database.ProductInterface, filter database.ProductFilter, exporter Exporter, afterBatch func(written int) error) error {
+	written := 0
+	err := productDB.Stream(filter, batchSize, func(products []entity.Product) error {
+		for i := range products {
+			if err := exporter.Write(&products[i]); err != nil {
+				return err
+			}
+		}
+		if err := exporter.Flush(); err != nil {
+			return err
+		}
+		written += len(products)
+		return afterBatch(written)
+	})
+	if err != nil {
+		return err
+	}
+	return exporter.Close()
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+func (e *csvExporter) Write(product *entity.Product) error {
+	return e.writer.Write(row(product))
+}
+
+func (e *csvExporter) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExporter) Close() error {
+	return e.Flush()
+}
+
+type ndjsonExporter struct {
+	encoder *json.Encoder
+	storage storage.Storage
+}
+
+func (e *ndjsonExporter) Write(product *entity.Product) error {
+	storage.SetImageURLs(e.storage, product.Images)
+	return e.encoder.Encode(product)
+}
+
+func (e *ndjsonExporter) Flush() error {
+	return nil
+}
+
+func (e *ndjsonExporter) Close() error {
+	return nil
+}
+
+type xlsxExporter struct {
+	writer *spreadsheet.XLSXWriter
+}
+
+func (e *xlsxExporter) Write(product *entity.Product) error {
+	values := stringsToValues(row(product))
+	// Quantities and prices are numbers in Excel.
+	price, _ := strconv.ParseFloat(product.Price.Decimal(), 64)
+	values[3] = price
+	values[5] = product.Stock
+	values[6] = product.Reserved
+	values[7] = product.LowStockThreshold
+	values[12] = product.Version
+	return e.writer.WriteRow(values)
+}
+
+func (e *xlsxExporter) Flush() error {
+	return e.writer.Flush()
+}
+
+func (e *xlsxExporter) Close() error {
+	return e.writer.Close()
+}
+
+// row holds the values of Columns for the product.
+func row(product *entity.Product) []string {
+	tags := make([]string, len(product.Tags))
+	for i, tag := range product.Tags {
+		tags[i] = tag.Name
+	}
+	categoryIDs := make([]string, len(product.Categories))
+	for i, category := range product.Categories {
+		categoryIDs[i] = category.ID.String()
+	}
+	return []string{
+		product.ID.String(),
+		product.Name,
+		product.Status,
+		product.Price.Decimal(),
+		product.Price.Currency,
+		strconv.Itoa(product.Stock),
+		strconv.Itoa(product.Reserved),
+		strconv.Itoa(product.LowStockThreshold),
+		strings.Join(tags, ","),
+		strings.Join(categoryIDs, ","),
+		product.CreatedAt.UTC().Format(time.RFC3339),
+		product.UpdatedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(product.Version),
+	}
+}
+
+func stringsToValues(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/spreadsheet"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createProducts(t *testing.T, count int) *database.Product {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db, "BRL"))
+	productDB := database.NewProduct(db)
+	for i := 0; i < count; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), pkgEntity.Money{Amount: 1999, Currency: "BRL"})
+		assert.NoError(t, err)
+		assert.NoError(t, productDB.Create(product))
+	}
+	return productDB
+}
+
+func TestExport_CSV(t *testing.T) {
+	productDB := createProducts(t, 3)
+	var body bytes.Buffer
+	e, err := New(&body, CSV, storage.NewLocal(t.TempDir(), "/uploads"))
+	assert.NoError(t, err)
+	var progress []int
+	err = Export(productDB, database.ProductFilter{}, e, func(written int) error {
+		progress = append(progress, written)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3}, progress)
+
+	rows, err := spreadsheet.ReadCSV(&body)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, Columns, rows[0])
+	assert.Equal(t, "Product 0", rows[1][1])
+	assert.Equal(t, "19.99", rows[1][3])
+	assert.Equal(t, "BRL", rows[1][4])
+}
+
+func TestExport_XLSX(t *testing.T) {
+	productDB := createProducts(t, 2)
+	var body bytes.Buffer
+	e, err := New(&body, XLSX, storage.NewLocal(t.TempDir(), "/uploads"))
+	assert.NoError(t, err)
+	assert.NoError(t, Export(productDB, database.ProductFilter{}, e, func(int) error { return nil }))
+
+	rows, err := spreadsheet.ReadXLSX(&body)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "Product 1", rows[2][1])
+	assert.Equal(t, "19.99", rows[2][3])
+}
+
+func TestExport_NDJSON(t *testing.T) {
+	productDB := createProducts(t, 2)
+	var body bytes.Buffer
+	e, err := New(&body, NDJSON, storage.NewLocal(t.TempDir(), "/uploads"))
+	assert.NoError(t, err)
+	assert.NoError(t, Export(productDB, database.ProductFilter{}, e, func(int) error { return nil }))
+
+	decoder := json.NewDecoder(&body)
+	var names []string
+	for decoder.More() {
+		var product struct {
+			Name string `json:"name"`
+		}
+		assert.NoError(t, decoder.Decode(&product))
+		names = append(names, product.Name)
+	}
+	assert.Equal(t, []string{"Product 0", "Product 1"}, names)
+}
+
+func TestNew_InvalidFormat(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "pdf", nil)
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+}
@@ -0,0 +1,390 @@
+// Package importer creates and updates products from the rows of a
+// spreadsheet, matching them to existing products by name.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+// Fields a column can be mapped to. Name and price are required.
+const (
+	FieldName              = "name"
+	FieldPrice             = "price"
+	FieldCurrency          = "currency"
+	FieldLowStockThreshold = "low_stock_threshold"
+	FieldTags              = "tags"
+)
+
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionError     = "error"
+)
+
+var fields = []string{FieldName, FieldPrice, FieldCurrency, FieldLowStockThreshold, FieldTags}
+
+// matchBatchSize is how many rows are looked up at once.
+var matchBatchSize = 500
+
+var (
+	ErrInvalidMapping = errors.New("column mapping must be given as field=Column, with field one of name, price, currency, low_stock_threshold or tags")
+	ErrMissingColumn  = errors.New("column not found in the header row")
+	ErrNoRows         = errors.New("file has no rows to import")
+	// ErrInvalidRows is returned along with the report when some rows
+	// can't be imported, in which case nothing is.
+	ErrInvalidRows = errors.New("some rows are invalid, nothing was imported")
+
+	errDuplicateName = errors.New("name already appears in row")
+	errAmbiguousName = errors.New("more than one product has this name")
+	errInvalidNumber = errors.New("low stock threshold must be a whole number")
+)
+
+// Mapping tells the header of the column each field is read from. Fields
+// that aren't mapped are read from the column named like them.
+type Mapping map[string]string
+
+// ParseMapping reads field=Column pairs.
+func ParseMapping(pairs []string) (Mapping, error) {
+	mapping := Mapping{}
+	for _, pair := range pairs {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		if !ok || strings.TrimSpace(column) == "" || !isField(field) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMapping, pair)
+		}
+		mapping[field] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+func isField(field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+type Options struct {
+	Mapping Mapping
+	// Currency is the currency of prices when there is no currency column
+	// or it is empty.
+	Currency string
+	// DryRun checks every row and reports what would be done without
+	// changing anything.
+	DryRun bool
+	// Source is who the changes are recorded as made by.
+	Source entity.AuditSource
+	// Progress, when set, is called as the rows are checked with how many
+	// were and how many there are, leaving blank rows out. Returning an
+	// error stops the import.
+	Progress func(processed, total int) error
+}
+
+type Importer struct {
+	ProductDB database.ProductInterface
+}
+
+func NewImporter(productDB database.ProductInterface) *Importer {
+	return &Importer{ProductDB: productDB}
+}
+
+// row is a line of the file read into the fields of a product.
+type row struct {
+	name              string
+	price             pkgEntity.Money
+	lowStockThreshold *int
+	tags              []entity.Tag
+	product           *entity.Product
+	result            dto.ProductImportRow
+}
+
+// Import creates the products of rows whose name isn't in use and updates
+// the price, low stock threshold and tags of the others. The first row
+// holds the column headers. Either every row is imported or, when any row
+// is invalid, none is and ErrInvalidRows is returned with the report.
+func (i *Importer) Import(rows [][]string, options Options) (*dto.ProductImportOutput, error) {
+	if len(rows) < 2 {
+		return nil, ErrNoRows
+	}
+	columns, err := columnIndexes(rows[0], options.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	var parsed []*row
+	lines := map[string]int{}
+	for index, values := range rows[1:] {
+		if isEmpty(values) {
+			continue
+		}
+		line := index + 2
+		r := &row{result: dto.ProductImportRow{Row: line}}
+		parsed = append(parsed, r)
+		if err := r.parse(values, columns, options.Currency); err != nil {
+			r.fail(err)
+			continue
+		}
+		if first, ok := lines[r.name]; ok {
+			r.fail(fmt.Errorf("%w %d", errDuplicateName, first))
+			continue
+		}
+		lines[r.name] = line
+	}
+	if len(parsed) == 0 {
+		return nil, ErrNoRows
+	}
+	if err = options.progress(0, len(parsed)); err != nil {
+		return nil, err
+	}
+	for start := 0; start < len(parsed); start += matchBatchSize {
+		end := start + matchBatchSize
+		if end > len(parsed) {
+			end = len(parsed)
+		}
+		if err = i.match(parsed[start:end], options.Source.Actor); err != nil {
+			return nil, err
+		}
+		if err = options.progress(end, len(parsed)); err != nil {
+			return nil, err
+		}
+	}
+	report := &dto.ProductImportOutput{DryRun: options.DryRun, Rows: len(parsed), Results: []dto.ProductImportRow{}}
+	var creates []*entity.Product
+	var updates []*entity.Product
+	for _, r := range parsed {
+		report.Results = append(report.Results, r.result)
+		switch r.result.Action {
+		case ActionCreate:
+			report.Created++
+			creates = append(creates, r.product)
+		case ActionUpdate:
+			report.Updated++
+			updates = append(updates, r.product)
+		case ActionUnchanged:
+			report.Unchanged++
+		default:
+			report.Failed++
+		}
+	}
+	if report.Failed > 0 {
+		if options.DryRun {
+			return report, nil
+		}
+		return report, ErrInvalidRows
+	}
+	if options.DryRun {
+		return report, nil
+	}
+	err = i.ProductDB.As(options.Source).Transaction(func(tx database.ProductInterface) error {
+		if err := tx.CreateMany(creates); err != nil {
+			return err
+		}
+		for _, product := range updates {
+			if err := tx.Update(product); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (o Options) progress(processed, total int) error {
+	if o.Progress == nil {
+		return nil
+	}
+	return o.Progress(processed, total)
+}
+
+// match looks up the products the rows are about and builds what each of
+// them will become.
+func (i *Importer) match(rows []*row, actor string) error {
+	var names []string
+	for _, r := range rows {
+		if r.result.Error == "" {
+			names = append(names, r.name)
+		}
+	}
+	existing, err := i.ProductDB.FindByNames(names)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string][]entity.Product, len(existing))
+	for _, product := range existing {
+		byName[product.Name] = append(byName[product.Name], product)
+	}
+	for _, r := range rows {
+		if r.result.Error != "" {
+			continue
+		}
+		var err error
+		switch products := byName[r.name]; len(products) {
+		case 0:
+			err = r.create(actor)
+		case 1:
+			err = r.update(&products[0], actor)
+		default:
+			err = errAmbiguousName
+		}
+		if err != nil {
+			r.fail(err)
+		}
+	}
+	return nil
+}
+
+func (r *row) create(actor string) error {
+	product, err := entity.NewProduct(r.name, r.price)
+	if err != nil {
+		return err
+	}
+	if r.lowStockThreshold != nil {
+		product.LowStockThreshold = *r.lowStockThreshold
+	}
+	if r.tags != nil {
+		product.Tags = r.tags
+	}
+	if err = product.Validate(); err != nil {
+		return err
+	}
+	product.UpdatedBy = actor
+	r.product = product
+	r.result.Action = ActionCreate
+	r.result.ID = product.ID.String()
+	return nil
+}
+
+func (r *row) update(product *entity.Product, actor string) error {
+	changed := product.Price != r.price
+	product.Price = r.price
+	if r.lowStockThreshold != nil {
+		changed = changed || product.LowStockThreshold != *r.lowStockThreshold
+		product.LowStockThreshold = *r.lowStockThreshold
+	}
+	if r.tags != nil {
+		changed = changed || !sameTags(product.Tags, r.tags)
+		product.Tags = r.tags
+	}
+	if err := product.Validate(); err != nil {
+		return err
+	}
+	product.UpdatedBy = actor
+	r.product = product
+	r.result.ID = product.ID.String()
+	r.result.Action = ActionUnchanged
+	if changed {
+		r.result.Action = ActionUpdate
+	}
+	return nil
+}
+
+func (r *row) fail(err error) {
+	r.result.Action = ActionError
+	r.result.Error = err.Error()
+}
+
+// parse reads the fields of the row. Empty low stock threshold and tags
+// cells leave them as they are.
+func (r *row) parse(values []string, columns map[string]int, currency string) error {
+	cell := func(field string) string {
+		column, ok := columns[field]
+		if !ok || column >= len(values) {
+			return ""
+		}
+		return strings.TrimSpace(values[column])
+	}
+	r.name = cell(FieldName)
+	r.result.Name = r.name
+	if r.name == "" {
+		return entity.ErrNameIsRequired
+	}
+	if value := cell(FieldCurrency); value != "" {
+		currency = strings.ToUpper(value)
+	}
+	amount := cell(FieldPrice)
+	if amount == "" {
+		return entity.ErrPriceIsRequired
+	}
+	var err error
+	if r.price, err = pkgEntity.ParseMoney(amount, currency); err != nil {
+		return err
+	}
+	if value := cell(FieldLowStockThreshold); value != "" {
+		threshold, err := strconv.Atoi(value)
+		if err != nil {
+			return errInvalidNumber
+		}
+		r.lowStockThreshold = &threshold
+	}
+	if value := cell(FieldTags); value != "" {
+		if r.tags, err = entity.NormalizeTags(strings.Split(value, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnIndexes finds the column of each field in the header row.
+func columnIndexes(header []string, mapping Mapping) (map[string]int, error) {
+	byHeader := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := byHeader[name]; !ok {
+			byHeader[name] = i
+		}
+	}
+	columns := map[string]int{}
+	for _, field := range fields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		column, ok := byHeader[strings.ToLower(name)]
+		if !ok {
+			if mapped || field == FieldName || field == FieldPrice {
+				return nil, fmt.Errorf("%w: %s", ErrMissingColumn, name)
+			}
+			continue
+		}
+		columns[field] = column
+	}
+	return columns, nil
+}
+
+func sameTags(a, b []entity.Tag) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	names := make(map[string]bool, len(a))
+	for _, tag := range a {
+		names[tag.Name] = true
+	}
+	for _, tag := range b {
+		if !names[tag.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmpty(values []string) bool {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
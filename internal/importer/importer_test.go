@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createImporter(t *testing.T) (*Importer, *database.Product) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db, "BRL"))
+	productDB := database.NewProduct(db)
+	return NewImporter(productDB), productDB
+}
+
+func createProduct(t *testing.T, productDB *database.Product, name, amount string) *entity.Product {
+	price, err := pkgEntity.ParseMoney(amount, "BRL")
+	assert.NoError(t, err)
+	product, err := entity.NewProduct(name, price)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.Create(product))
+	return product
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := ParseMapping([]string{"name=Product", " price = Preço "})
+	assert.NoError(t, err)
+	assert.Equal(t, Mapping{"name": "Product", "price": "Preço"}, mapping)
+
+	_, err = ParseMapping([]string{"stock=Qty"})
+	assert.ErrorIs(t, err, ErrInvalidMapping)
+	_, err = ParseMapping([]string{"name"})
+	assert.ErrorIs(t, err, ErrInvalidMapping)
+}
+
+func TestImport(t *testing.T) {
+	importer, productDB := createImporter(t)
+	mouse := createProduct(t, productDB, "Mouse", "39.99")
+	createProduct(t, productDB, "Cable", "9.90")
+
+	rows := [][]string{
+		{"Product", "Preço", "Tags"},
+		{"Mouse", "34.99", "wireless, Summer Sale"},
+		{"Keyboard", "99.90", ""},
+		{},
+		{"Cable", "9.90", ""},
+	}
+	options := Options{Mapping: Mapping{"name": "Product", "price": "preço"}, Currency: "BRL", Source: entity.AuditSource{Actor: "user-1"}}
+	report, err := importer.Import(rows, options)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Rows)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, ActionUpdate, report.Results[0].Action)
+	assert.Equal(t, mouse.ID.String(), report.Results[0].ID)
+	assert.Equal(t, 3, report.Results[1].Row)
+	assert.Equal(t, 5, report.Results[2].Row)
+
+	updated, err := productDB.FindById(mouse.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "34.99", updated.Price.Decimal())
+	assert.Len(t, updated.Tags, 2)
+	assert.Equal(t, 2, updated.Version)
+	products, err := productDB.FindByNames([]string{"Keyboard"})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	entries, err := database.NewAudit(productDB.DB).FindAll(database.AuditFilter{Actor: "user-1"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestImport_DryRun(t *testing.T) {
+	importer, productDB := createImporter(t)
+	mouse := createProduct(t, productDB, "Mouse", "39.99")
+
+	rows := [][]string{{"name", "price", "currency"}, {"Mouse", "34.99", "usd"}, {"Keyboard", "99.90", ""}}
+	report, err := importer.Import(rows, Options{Currency: "BRL", DryRun: true})
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+
+	unchanged, err := productDB.FindById(mouse.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "39.99", unchanged.Price.Decimal())
+	products, err := productDB.FindByNames([]string{"Keyboard"})
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestImport_InvalidRows(t *testing.T) {
+	importer, productDB := createImporter(t)
+	createProduct(t, productDB, "Mouse", "39.99")
+	createProduct(t, productDB, "Twin", "1")
+	createProduct(t, productDB, "Twin", "2")
+
+	rows := [][]string{
+		{"name", "price", "low_stock_threshold"},
+		{"Keyboard", "99.90", ""},
+		{"", "1", ""},
+		{"Cable", "9.999", ""},
+		{"Keyboard", "89.90", ""},
+		{"Twin", "3", ""},
+		{"Mouse", "10", "few"},
+		{"Mouse", "-1", ""},
+	}
+	report, err := importer.Import(rows, Options{Currency: "BRL"})
+	assert.ErrorIs(t, err, ErrInvalidRows)
+	assert.Equal(t, 6, report.Failed)
+	assert.Equal(t, ActionCreate, report.Results[0].Action)
+	assert.Equal(t, entity.ErrNameIsRequired.Error(), report.Results[1].Error)
+	assert.Equal(t, pkgEntity.ErrInvalidAmount.Error(), report.Results[2].Error)
+	assert.Equal(t, "name already appears in row 2", report.Results[3].Error)
+	assert.Equal(t, errAmbiguousName.Error(), report.Results[4].Error)
+	assert.Equal(t, errInvalidNumber.Error(), report.Results[5].Error)
+	assert.Equal(t, ActionError, report.Results[6].Action)
+
+	// Nothing is imported when a row is invalid.
+	products, err := productDB.FindByNames([]string{"Keyboard"})
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+
+	report, err = importer.Import(rows, Options{Currency: "BRL", DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, report.Failed)
+}
+
+func TestImport_Progress(t *testing.T) {
+	importer, productDB := createImporter(t)
+	defer func(size int) { matchBatchSize = size }(matchBatchSize)
+	matchBatchSize = 2
+
+	// Blank rows aren't counted, and the rows are checked two at a time.
+	rows := [][]string{{"name", "price"}, {"Mouse", "39.99"}, {}, {"Keyboard", "99.90"}, {" ", ""}, {"Cable", "9.90"}}
+	var calls [][2]int
+	_, err := importer.Import(rows, Options{Currency: "BRL", Progress: func(processed, total int) error {
+		calls = append(calls, [2]int{processed, total})
+		return nil
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int{{0, 3}, {2, 3}, {3, 3}}, calls)
+	products, err := productDB.FindByNames([]string{"Mouse", "Keyboard", "Cable"})
+	assert.NoError(t, err)
+	assert.Len(t, products, 3)
+
+	// Nothing is imported once progress fails.
+	rows = [][]string{{"name", "price"}, {"Mouse", "29.99"}}
+	_, err = importer.Import(rows, Options{Currency: "BRL", Progress: func(processed, total int) error {
+		return assert.AnError
+	}})
+	assert.ErrorIs(t, err, assert.AnError)
+	products, err = productDB.FindByNames([]string{"Mouse"})
+	assert.NoError(t, err)
+	assert.Equal(t, "39.99", products[0].Price.Decimal())
+}
+
+func TestImport_MissingColumn(t *testing.T) {
+	importer, _ := createImporter(t)
+	_, err := importer.Import([][]string{{"name"}, {"Mouse"}}, Options{Currency: "BRL"})
+	assert.ErrorIs(t, err, ErrMissingColumn)
+	_, err = importer.Import([][]string{{"name", "price"}, {"Mouse", "1"}}, Options{Currency: "BRL", Mapping: Mapping{"tags": "Labels"}})
+	assert.ErrorIs(t, err, ErrMissingColumn)
+	_, err = importer.Import([][]string{{"name", "price"}, {"", ""}}, Options{Currency: "BRL"})
+	assert.ErrorIs(t, err, ErrNoRows)
+}
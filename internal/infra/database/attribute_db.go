@@ -0,0 +1,54 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type AttributeDefinition struct {
+	DB *gorm.DB
+}
+
+func NewAttributeDefinition(db *gorm.DB) *AttributeDefinition {
+	return &AttributeDefinition{DB: db}
+}
+
+func (a *AttributeDefinition) Create(definition *entity.AttributeDefinition) error {
+	return a.DB.Create(definition).Error
+}
+
+func (a *AttributeDefinition) FindAll() ([]entity.AttributeDefinition, error) {
+	definitions := []entity.AttributeDefinition{}
+	err := a.DB.Order("code asc").Find(&definitions).Error
+	return definitions, err
+}
+
+func (a *AttributeDefinition) FindByCode(code string) (*entity.AttributeDefinition, error) {
+	var definition entity.AttributeDefinition
+	if err := a.DB.First(&definition, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+func (a *AttributeDefinition) Update(definition *entity.AttributeDefinition) error {
+	if _, err := a.FindByCode(definition.Code); err != nil {
+		return err
+	}
+	return a.DB.Save(definition).Error
+}
+
+// Delete removes the definition together with the values products have
+// for it.
+func (a *AttributeDefinition) Delete(code string) error {
+	definition, err := a.FindByCode(code)
+	if err != nil {
+		return err
+	}
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code = ?", code).Delete(&entity.AttributeValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(definition).Error
+	})
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createAttributeMemoryDB(t *testing.T) (*AttributeDefinition, *Product) {
+	db := createTestDB(t)
+	return NewAttributeDefinition(db), NewProduct(db)
+}
+
+func createAttributedProduct(t *testing.T, productDB *Product, definitions []entity.AttributeDefinition, name string, attributes map[string]interface{}) *entity.Product {
+	product, err := entity.NewProduct(name, brl(1000))
+	assert.NoError(t, err)
+	product.Attributes, err = entity.NewAttributeValues(product.ID, attributes, definitions)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.Create(product))
+	return product
+}
+
+func TestAttributeDefinitionCrud(t *testing.T) {
+	attributeDB, productDB := createAttributeMemoryDB(t)
+	material, _ := entity.NewAttributeDefinition("material", "Material", entity.AttributeEnum, "", entity.AttributeRules{Options: []string{"cotton", "wool"}})
+	assert.NoError(t, attributeDB.Create(material))
+
+	found, err := attributeDB.FindByCode("material")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cotton", "wool"}, found.Options)
+
+	assert.NoError(t, found.Change("Fabric", entity.AttributeEnum, "", entity.AttributeRules{Options: []string{"cotton", "wool", "silk"}}))
+	assert.NoError(t, attributeDB.Update(found))
+	definitions, err := attributeDB.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, definitions, 1)
+	assert.Equal(t, "Fabric", definitions[0].Name)
+
+	product := createAttributedProduct(t, productDB, definitions, "Sweater", map[string]interface{}{"material": "wool"})
+	assert.NoError(t, attributeDB.Delete("material"))
+	assert.ErrorIs(t, attributeDB.Delete("material"), gorm.ErrRecordNotFound)
+	product, err = productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, product.Attributes)
+}
+
+func Test_ProductFindAll_FilterByAttributes(t *testing.T) {
+	attributeDB, productDB := createAttributeMemoryDB(t)
+	weight, _ := entity.NewAttributeDefinition("weight", "Weight", entity.AttributeNumber, "kg", entity.AttributeRules{})
+	material, _ := entity.NewAttributeDefinition("material", "Material", entity.AttributeString, "", entity.AttributeRules{})
+	assert.NoError(t, attributeDB.Create(weight))
+	assert.NoError(t, attributeDB.Create(material))
+	definitions := []entity.AttributeDefinition{*weight, *material}
+
+	light := createAttributedProduct(t, productDB, definitions, "Light", map[string]interface{}{"weight": 0.5, "material": "cotton"})
+	heavy := createAttributedProduct(t, productDB, definitions, "Heavy", map[string]interface{}{"weight": 12.0, "material": "wool"})
+	createAttributedProduct(t, productDB, definitions, "Bare", nil)
+
+	products, err := productDB.FindAll(0, 0, "asc", ProductFilter{Attributes: []AttributeFilter{{Code: "material", Values: []string{"cotton"}}}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, light.ID, products[0].ID)
+
+	min := 1.0
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{Attributes: []AttributeFilter{{Code: "weight", Min: &min}}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, heavy.ID, products[0].ID)
+	assert.Equal(t, "wool", products[0].Attributes[0].Value)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{Attributes: []AttributeFilter{
+		{Code: "weight", Min: &min},
+		{Code: "material", Values: []string{"cotton"}},
+	}})
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func Test_ProductUpdate_ReplacesAttributes(t *testing.T) {
+	attributeDB, productDB := createAttributeMemoryDB(t)
+	weight, _ := entity.NewAttributeDefinition("weight", "Weight", entity.AttributeNumber, "kg", entity.AttributeRules{})
+	assert.NoError(t, attributeDB.Create(weight))
+	definitions := []entity.AttributeDefinition{*weight}
+	product := createAttributedProduct(t, productDB, definitions, "Box", map[string]interface{}{"weight": 1.0})
+
+	var err error
+	product.Attributes, err = entity.NewAttributeValues(product.ID, map[string]interface{}{"weight": 2.0}, definitions)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.Update(product))
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Attributes, 1)
+	assert.Equal(t, "2", found.Attributes[0].Value)
+}
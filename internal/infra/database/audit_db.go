@@ -0,0 +1,129 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+// verifyBatchSize is how many audit entries are read at a time while the
+// chain is verified.
+const verifyBatchSize = 500
+
+// AuditFilter narrows down the entries listed by FindAll. Zero values don't
+// filter anything.
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	Actor      string
+	Action     string
+	RequestID  string
+	From, To   *time.Time
+}
+
+type Audit struct {
+	DB *gorm.DB
+}
+
+func NewAudit(db *gorm.DB) *Audit {
+	return &Audit{DB: db}
+}
+
+// Append links the entry to the last one of the chain and saves it.
+func (a *Audit) Append(entry *entity.AuditEntry) error {
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		return appendAudit(tx, entry)
+	})
+}
+
+// appendAudit links the entry to the last one of the chain and saves it
+// through tx. It first takes the audit lock, which is held until tx ends,
+// so concurrent appends link one after the other even from other
+// processes.
+func appendAudit(tx *gorm.DB, entry *entity.AuditEntry) error {
+	lock := entity.AuditLock{ID: 1, AcquiredAt: time.Now()}
+	if err := tx.Save(&lock).Error; err != nil {
+		return err
+	}
+	var last []entity.AuditEntry
+	if err := tx.Order("sequence desc").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	if len(last) == 0 {
+		entry.Link(nil)
+	} else {
+		entry.Link(&last[0])
+	}
+	return tx.Create(entry).Error
+}
+
+// recordAudit appends to the log through tx, the transaction of the change,
+// that source applied action to an entity, which went from before to after.
+func recordAudit(tx *gorm.DB, source entity.AuditSource, action, entityType, entityID string, before, after interface{}) error {
+	entry, err := entity.NewAuditEntry(action, entityType, entityID, source, before, after)
+	if err != nil {
+		return err
+	}
+	return appendAudit(tx, entry)
+}
+
+// FindAll lists the entries that match the filter, newest first.
+func (a *Audit) FindAll(filter AuditFilter, page, limit int) ([]entity.AuditEntry, error) {
+	query := a.DB.Model(&entity.AuditEntry{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", filter.From.UTC())
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", filter.To.UTC())
+	}
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	entries := []entity.AuditEntry{}
+	err := query.Order("sequence desc").Find(&entries).Error
+	return entries, err
+}
+
+// Verify walks the whole chain checking that every entry is intact and
+// follows the previous one. It returns how many entries it checked and the
+// first one that doesn't, if any.
+func (a *Audit) Verify() (int64, *entity.AuditEntry, error) {
+	var checked int64
+	var previous *entity.AuditEntry
+	for {
+		var entries []entity.AuditEntry
+		query := a.DB.Order("sequence asc").Limit(verifyBatchSize)
+		if previous != nil {
+			query = query.Where("sequence > ?", previous.Sequence)
+		}
+		if err := query.Find(&entries).Error; err != nil {
+			return checked, nil, err
+		}
+		for i := range entries {
+			if !entries[i].Follows(previous) {
+				return checked, &entries[i], nil
+			}
+			previous = &entries[i]
+			checked++
+		}
+		if len(entries) < verifyBatchSize {
+			return checked, nil, nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func appendAuditEntry(t *testing.T, auditDB *Audit, action, entityID, actor string) *entity.AuditEntry {
+	entry, err := entity.NewAuditEntry(action, entity.AuditProduct, entityID, entity.AuditSource{Actor: actor}, nil, map[string]string{"name": entityID})
+	assert.NoError(t, err)
+	assert.NoError(t, auditDB.Append(entry))
+	return entry
+}
+
+func TestAuditAppendAndFindAll(t *testing.T) {
+	auditDB := NewAudit(createTestDB(t))
+	first := appendAuditEntry(t, auditDB, entity.AuditCreate, "product-1", "user-1")
+	second := appendAuditEntry(t, auditDB, entity.AuditUpdate, "product-1", "user-2")
+	appendAuditEntry(t, auditDB, entity.AuditCreate, "product-2", "user-1")
+	assert.Equal(t, int64(1), first.Sequence)
+	assert.Equal(t, first.Hash, second.PrevHash)
+
+	entries, err := auditDB.FindAll(AuditFilter{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, int64(3), entries[0].Sequence)
+
+	entries, err = auditDB.FindAll(AuditFilter{EntityType: entity.AuditProduct, EntityID: "product-1"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	entries, err = auditDB.FindAll(AuditFilter{Actor: "user-1", Action: entity.AuditCreate}, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "product-2", entries[0].EntityID)
+
+	future := time.Now().Add(time.Hour)
+	entries, err = auditDB.FindAll(AuditFilter{From: &future}, 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAuditVerify(t *testing.T) {
+	auditDB := NewAudit(createTestDB(t))
+	checked, broken, err := auditDB.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), checked)
+	assert.Nil(t, broken)
+
+	for i := 0; i < 5; i++ {
+		appendAuditEntry(t, auditDB, entity.AuditUpdate, "product-1", "user-1")
+	}
+	checked, broken, err = auditDB.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), checked)
+	assert.Nil(t, broken)
+
+	// Tampering with an entry breaks the chain there.
+	assert.NoError(t, auditDB.DB.Model(&entity.AuditEntry{}).Where("sequence = ?", 3).Update("actor", "user-2").Error)
+	checked, broken, err = auditDB.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), checked)
+	assert.Equal(t, int64(3), broken.Sequence)
+
+	// So does removing one.
+	assert.NoError(t, auditDB.DB.Model(&entity.AuditEntry{}).Where("sequence = ?", 3).Update("actor", "user-1").Error)
+	assert.NoError(t, auditDB.DB.Where("sequence = ?", 4).Delete(&entity.AuditEntry{}).Error)
+	checked, broken, err = auditDB.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), checked)
+	assert.Equal(t, int64(5), broken.Sequence)
+}
+
+func TestAudit_RecordedWithChanges(t *testing.T) {
+	db := createTestDB(t)
+	source := entity.AuditSource{Actor: "user-1", RequestID: "host/abc-000001", IP: "10.0.0.1"}
+	productDB := NewProduct(db).As(source)
+	product, _ := entity.NewProduct("Camera", brl(99900))
+	assert.NoError(t, productDB.Create(product))
+	product.Price = brl(89900)
+	assert.NoError(t, productDB.Update(product))
+	variant, _ := entity.NewVariant(product, "CAMERA-BLACK", nil, map[string]string{"color": "black"}, nil)
+	assert.NoError(t, NewVariant(db).As(source).Create(variant))
+	image, _ := entity.NewProductImage(product.ID, "image/jpeg", 1024, 800, 600, "user-1")
+	assert.NoError(t, NewProductImage(db).As(source).Create(image))
+	movement, _ := entity.NewStockMovement(product.ID, entity.MovementReceipt, 5, "", "user-1")
+	_, err := NewStockMovement(db).As(source).Apply(movement)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.Delete(product.ID.String(), "user-1", 0))
+
+	auditDB := NewAudit(db)
+	entries, err := auditDB.FindAll(AuditFilter{Actor: "user-1"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 6)
+	assert.Equal(t, entity.AuditDelete, entries[0].Action)
+	assert.Equal(t, entity.AuditProductImage, entries[2].EntityType)
+	assert.Equal(t, entity.AuditVariant, entries[3].EntityType)
+	assert.Equal(t, "host/abc-000001", entries[0].RequestID)
+	assert.Equal(t, "10.0.0.1", entries[0].IP)
+	var changes map[string]entity.AuditChange
+	assert.NoError(t, json.Unmarshal(entries[1].Changes, &changes))
+	assert.Equal(t, json.RawMessage("0"), changes["stock"].Before)
+	assert.Equal(t, json.RawMessage("5"), changes["stock"].After)
+
+	purged, err := NewProduct(db).As(entity.AuditSource{Actor: entity.AuditSystem}).Purge(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, purged, 1)
+	entries, err = auditDB.FindAll(AuditFilter{Actor: entity.AuditSystem, Action: entity.AuditPurge}, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	checked, broken, err := auditDB.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), checked)
+	assert.Nil(t, broken)
+}
+
+func TestAudit_RolledBackWithTheChange(t *testing.T) {
+	db := createTestDB(t)
+	productDB := NewProduct(db).As(entity.AuditSource{Actor: "user-1"})
+	failed := errors.New("failed")
+	err := productDB.Transaction(func(tx ProductInterface) error {
+		product, _ := entity.NewProduct("Camera", brl(99900))
+		if err := tx.Create(product); err != nil {
+			return err
+		}
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	entries, err := NewAudit(db).FindAll(AuditFilter{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAuditAppend_Concurrent(t *testing.T) {
+	// Each Audit has a database handle of its own, like separate processes
+	// sharing a database would.
+	path := filepath.Join(t.TempDir(), "audit.db")
+	var audits []*Audit
+	for i := 0; i < 2; i++ {
+		db, err := gorm.Open(sqlite.Open(path+"?_busy_timeout=5000"), &gorm.Config{})
+		assert.NoError(t, err)
+		if i == 0 {
+			assert.NoError(t, Migrate(db, "BRL"))
+		}
+		audits = append(audits, NewAudit(db))
+	}
+
+	var wg sync.WaitGroup
+	for _, auditDB := range audits {
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(auditDB *Audit) {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					appendAuditEntry(t, auditDB, entity.AuditUpdate, "product-1", "user-1")
+				}
+			}(auditDB)
+		}
+	}
+	wg.Wait()
+	checked, broken, err := audits[0].Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(40), checked)
+	assert.Nil(t, broken)
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Category struct {
+	DB *gorm.DB
+}
+
+func NewCategory(db *gorm.DB) *Category {
+	return &Category{DB: db}
+}
+
+func (c *Category) Create(category *entity.Category) error {
+	if category.ParentID != nil {
+		if _, err := c.FindById(category.ParentID.String()); err != nil {
+			return err
+		}
+	}
+	return c.DB.Create(category).Error
+}
+
+func (c *Category) FindAll() ([]entity.Category, error) {
+	var categories []entity.Category
+	err := c.DB.Order("name asc").Find(&categories).Error
+	return categories, err
+}
+
+func (c *Category) FindById(id string) (*entity.Category, error) {
+	var category entity.Category
+	if err := c.DB.First(&category, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindByIds returns the categories with the given ids, failing with
+// gorm.ErrRecordNotFound when any of them doesn't exist.
+func (c *Category) FindByIds(ids []string) ([]entity.Category, error) {
+	categories := []entity.Category{}
+	if len(ids) == 0 {
+		return categories, nil
+	}
+	if err := c.DB.Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	if len(categories) != len(uniqueStrings(ids)) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return categories, nil
+}
+
+// FindSubtree returns the category with the given id followed by all of its
+// descendants.
+func (c *Category) FindSubtree(id string) ([]entity.Category, error) {
+	ids, err := c.DescendantIds(id)
+	if err != nil {
+		return nil, err
+	}
+	var categories []entity.Category
+	err = c.DB.Where("id IN ?", append([]string{id}, ids...)).Order("name asc").Find(&categories).Error
+	return categories, err
+}
+
+// DescendantIds walks down the hierarchy and returns the ids of every
+// category below the given one.
+func (c *Category) DescendantIds(id string) ([]string, error) {
+	var ids []string
+	err := c.DB.Raw(`WITH RECURSIVE descendants(id) AS (
+		SELECT id FROM categories WHERE parent_id = ?
+		UNION
+		SELECT categories.id FROM categories JOIN descendants ON categories.parent_id = descendants.id
+	) SELECT id FROM descendants`, id).Scan(&ids).Error
+	return ids, err
+}
+
+func (c *Category) Update(category *entity.Category) error {
+	if _, err := c.FindById(category.ID.String()); err != nil {
+		return err
+	}
+	if category.ParentID != nil {
+		if _, err := c.FindById(category.ParentID.String()); err != nil {
+			return err
+		}
+		descendants, err := c.DescendantIds(category.ID.String())
+		if err != nil {
+			return err
+		}
+		for _, id := range descendants {
+			if id == category.ParentID.String() {
+				return entity.ErrCategoryCycle
+			}
+		}
+	}
+	return c.DB.Save(category).Error
+}
+
+func (c *Category) Delete(id string) error {
+	category, err := c.FindById(id)
+	if err != nil {
+		return err
+	}
+	var children int64
+	if err := c.DB.Model(&entity.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return entity.ErrCategoryHasChildren
+	}
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCategoryCreateAndFind(t *testing.T) {
+	categoryDB := NewCategory(createTestDB(t))
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	assert.NoError(t, categoryDB.Create(electronics))
+	phones, _ := entity.NewCategory("Phones", &electronics.ID)
+	assert.NoError(t, categoryDB.Create(phones))
+
+	found, err := categoryDB.FindById(phones.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, electronics.ID, *found.ParentID)
+
+	categories, err := categoryDB.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+
+	categories, err = categoryDB.FindByIds([]string{electronics.ID.String(), phones.ID.String()})
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+
+	unknown, _ := entity.NewCategory("Unknown", nil)
+	_, err = categoryDB.FindByIds([]string{electronics.ID.String(), unknown.ID.String()})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	orphan, _ := entity.NewCategory("Orphan", &unknown.ID)
+	assert.Error(t, categoryDB.Create(orphan))
+}
+
+func TestCategoryDescendants(t *testing.T) {
+	categoryDB := NewCategory(createTestDB(t))
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	phones, _ := entity.NewCategory("Phones", &electronics.ID)
+	android, _ := entity.NewCategory("Android", &phones.ID)
+	books, _ := entity.NewCategory("Books", nil)
+	for _, c := range []*entity.Category{electronics, phones, android, books} {
+		assert.NoError(t, categoryDB.Create(c))
+	}
+
+	ids, err := categoryDB.DescendantIds(electronics.ID.String())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{phones.ID.String(), android.ID.String()}, ids)
+
+	subtree, err := categoryDB.FindSubtree(phones.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, subtree, 2)
+}
+
+func TestCategoryUpdateRejectsCycles(t *testing.T) {
+	categoryDB := NewCategory(createTestDB(t))
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	phones, _ := entity.NewCategory("Phones", &electronics.ID)
+	android, _ := entity.NewCategory("Android", &phones.ID)
+	for _, c := range []*entity.Category{electronics, phones, android} {
+		assert.NoError(t, categoryDB.Create(c))
+	}
+
+	electronics.ParentID = &android.ID
+	assert.Equal(t, entity.ErrCategoryCycle, categoryDB.Update(electronics))
+
+	android.ParentID = nil
+	android.Name = "Android phones"
+	assert.NoError(t, categoryDB.Update(android))
+	found, err := categoryDB.FindById(android.ID.String())
+	assert.NoError(t, err)
+	assert.Nil(t, found.ParentID)
+	assert.Equal(t, "Android phones", found.Name)
+}
+
+func TestCategoryDelete(t *testing.T) {
+	db := createTestDB(t)
+	categoryDB := NewCategory(db)
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	phones, _ := entity.NewCategory("Phones", &electronics.ID)
+	assert.NoError(t, categoryDB.Create(electronics))
+	assert.NoError(t, categoryDB.Create(phones))
+
+	product, _ := entity.NewProduct("Phone", brl(1000))
+	product.Categories = []entity.Category{*phones}
+	assert.NoError(t, NewProduct(db).Create(product))
+
+	assert.Equal(t, entity.ErrCategoryHasChildren, categoryDB.Delete(electronics.ID.String()))
+	assert.NoError(t, categoryDB.Delete(phones.ID.String()))
+
+	found, err := NewProduct(db).FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, found.Categories)
+}
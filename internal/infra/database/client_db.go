@@ -0,0 +1,26 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Client struct {
+	DB *gorm.DB
+}
+
+func NewClient(db *gorm.DB) *Client {
+	return &Client{DB: db}
+}
+
+func (c *Client) Create(client *entity.Client) error {
+	return c.DB.Create(client).Error
+}
+
+func (c *Client) FindById(id string) (*entity.Client, error) {
+	var client entity.Client
+	if err := c.DB.First(&client, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
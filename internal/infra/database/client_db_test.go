@@ -0,0 +1,28 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCreateAndFindById(t *testing.T) {
+	db := createTestDB(t)
+	client, secret, err := entity.NewClient("Partner", "products:read")
+	assert.NoError(t, err)
+	clientDB := NewClient(db)
+	assert.NoError(t, clientDB.Create(client))
+
+	found, err := clientDB.FindById(client.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, client.ID, found.ID)
+	assert.Equal(t, client.Name, found.Name)
+	assert.Equal(t, client.Scope, found.Scope)
+	assert.True(t, found.ValidateSecret(secret))
+
+	found, err = clientDB.FindById(pkgEntity.NewID().String())
+	assert.Error(t, err)
+	assert.Nil(t, found)
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// createTestDB opens an empty in-memory database with the tables of every
+// entity, as Migrate creates them.
+func createTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Migrate(db, "BRL"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Event is the outbox the repositories save domain events to, along with
+// the changes they are about.
+type Event struct {
+	DB *gorm.DB
+}
+
+func NewEvent(db *gorm.DB) *Event {
+	return &Event{DB: db}
+}
+
+// FindUnpublished lists up to limit events waiting to be published to the
+// consumer, in the order they occurred.
+func (e *Event) FindUnpublished(consumer string, limit int) ([]entity.Event, error) {
+	events := []entity.Event{}
+	published := e.DB.Model(&entity.EventPublication{}).Select("event_id").
+		Where("consumer = ? AND published_at IS NOT NULL", consumer)
+	err := e.DB.Where("published_at IS NULL AND id NOT IN (?)", published).
+		Order("occurred_at asc, id asc").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// MarkPublished records that the event was published to the consumer.
+func (e *Event) MarkPublished(consumer, id string, at time.Time) error {
+	eventID, err := pkgEntity.ParseID(id)
+	if err != nil {
+		return err
+	}
+	publication := entity.EventPublication{EventID: eventID, Consumer: consumer, PublishedAt: &at}
+	return e.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}, {Name: "consumer"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"published_at": at, "last_error": ""}),
+	}).Create(&publication).Error
+}
+
+// MarkFailed records a failed attempt to publish the event to the consumer.
+func (e *Event) MarkFailed(consumer, id, reason string) error {
+	eventID, err := pkgEntity.ParseID(id)
+	if err != nil {
+		return err
+	}
+	publication := entity.EventPublication{EventID: eventID, Consumer: consumer, Attempts: 1, LastError: reason}
+	return e.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "event_id"}, {Name: "consumer"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"attempts":   gorm.Expr("event_publications.attempts + 1"),
+			"last_error": reason,
+		}),
+	}).Create(&publication).Error
+}
+
+// Complete marks the events published to every one of the consumers as
+// published at at, returning how many there were.
+func (e *Event) Complete(consumers []string, at time.Time) (int64, error) {
+	published := e.DB.Model(&entity.EventPublication{}).Select("COUNT(*)").
+		Where("event_publications.event_id = events.id AND consumer IN ? AND published_at IS NOT NULL", consumers)
+	result := e.DB.Model(&entity.Event{}).Where("published_at IS NULL AND (?) = ?", published, len(consumers)).
+		Update("published_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// DeletePublished deletes the events published before publishedBefore,
+// together with their publications, returning how many there were.
+func (e *Event) DeletePublished(publishedBefore time.Time) (int64, error) {
+	var deleted int64
+	err := e.DB.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&entity.Event{}).Select("id").Where("published_at < ?", publishedBefore)
+		if err := tx.Where("event_id IN (?)", expired).Delete(&entity.EventPublication{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("published_at < ?", publishedBefore).Delete(&entity.Event{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// recordEvent saves an event to the outbox through tx, the transaction of
+// the change it is about.
+func recordEvent(tx *gorm.DB, eventType, entityID string, payload interface{}) error {
+	event, err := entity.NewEvent(eventType, entityID, payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(event).Error
+}
+
+// recordProductEvent saves an event with the product as tx left it, deleted
+// or not.
+func recordProductEvent(tx *gorm.DB, eventType, id string) error {
+	var product entity.Product
+	if err := NewProduct(tx).preload().Unscoped().First(&product, "id = ?", id).Error; err != nil {
+		return err
+	}
+	return recordEvent(tx, eventType, id, &product)
+}
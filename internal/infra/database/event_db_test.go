@@ -0,0 +1,171 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductEvents(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	eventDB := NewEvent(productDB.DB)
+
+	product, _ := entity.NewProduct("Mouse", brl(3999))
+	assert.NoError(t, productDB.Create(product))
+	product.Name = "Wireless Mouse"
+	assert.NoError(t, productDB.Update(product))
+	// A conflicting update is rolled back along with its event.
+	product.Version = 1
+	assert.ErrorIs(t, productDB.Update(product), ErrVersionConflict)
+	assert.NoError(t, productDB.Delete(product.ID.String(), "user-1", 0))
+	assert.NoError(t, productDB.Restore(product.ID.String()))
+
+	events, err := eventDB.FindUnpublished("bus", 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 4)
+	types := make([]string, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+		assert.Equal(t, product.ID.String(), event.EntityID)
+	}
+	assert.Equal(t, []string{entity.EventProductCreated, entity.EventProductUpdated, entity.EventProductDeleted, entity.EventProductUpdated}, types)
+
+	var deleted struct {
+		Name      string `json:"name"`
+		DeletedBy string `json:"deleted_by"`
+		Version   int    `json:"version"`
+	}
+	assert.NoError(t, json.Unmarshal(events[2].Payload, &deleted))
+	assert.Equal(t, "Wireless Mouse", deleted.Name)
+	assert.Equal(t, "user-1", deleted.DeletedBy)
+	assert.Equal(t, 3, deleted.Version)
+}
+
+func TestProductEvents_VariantsAndImages(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	eventDB := NewEvent(productDB.DB)
+	product, _ := entity.NewProduct("T-shirt", brl(4990))
+	assert.NoError(t, productDB.Create(product))
+	variantDB := NewVariant(productDB.DB)
+	imageDB := NewProductImage(productDB.DB)
+
+	variant, _ := entity.NewVariant(product, "TSHIRT-S", nil, map[string]string{"size": "S"}, nil)
+	assert.NoError(t, variantDB.Create(variant))
+	variant.Options = map[string]string{"size": "M"}
+	assert.NoError(t, variantDB.Update(variant))
+	assert.NoError(t, variantDB.Delete(product.ID.String(), variant.ID.String()))
+	front, _ := entity.NewProductImage(product.ID, "image/jpeg", 1024, 800, 600, "user-1")
+	back, _ := entity.NewProductImage(product.ID, "image/jpeg", 1024, 800, 600, "user-1")
+	assert.NoError(t, imageDB.Create(front))
+	assert.NoError(t, imageDB.Create(back))
+	_, err := imageDB.Reorder(product.ID.String(), []string{back.ID.String(), front.ID.String()})
+	assert.NoError(t, err)
+	// Keeping the order doesn't change the product.
+	_, err = imageDB.Reorder(product.ID.String(), []string{back.ID.String(), front.ID.String()})
+	assert.NoError(t, err)
+	assert.NoError(t, imageDB.Delete(product.ID.String(), front.ID.String()))
+
+	events, err := eventDB.FindUnpublished("bus", 20)
+	assert.NoError(t, err)
+	assert.Len(t, events, 8)
+	for _, event := range events[1:] {
+		assert.Equal(t, entity.EventProductUpdated, event.Type)
+		assert.Equal(t, product.ID.String(), event.EntityID)
+	}
+	var updated entity.Product
+	assert.NoError(t, json.Unmarshal(events[7].Payload, &updated))
+	assert.Len(t, updated.Images, 1)
+	assert.Equal(t, back.ID, updated.Images[0].ID)
+}
+
+func TestProductEvents_RolledBack(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	eventDB := NewEvent(productDB.DB)
+
+	failure := errors.New("failed")
+	err := productDB.Transaction(func(tx ProductInterface) error {
+		mouse, _ := entity.NewProduct("Mouse", brl(3999))
+		keyboard, _ := entity.NewProduct("Keyboard", brl(9990))
+		if err := tx.CreateMany([]*entity.Product{mouse, keyboard}); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	events, err := eventDB.FindUnpublished("bus", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	mouse, _ := entity.NewProduct("Mouse", brl(3999))
+	keyboard, _ := entity.NewProduct("Keyboard", brl(9990))
+	assert.NoError(t, productDB.CreateMany([]*entity.Product{mouse, keyboard}))
+	events, err = eventDB.FindUnpublished("bus", 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestUserRegisteredEvent(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	eventDB := NewEvent(productDB.DB)
+
+	user, _ := entity.NewUser("John", "j@j.com", "123456")
+	assert.NoError(t, NewUser(productDB.DB).Create(user))
+	events, err := eventDB.FindUnpublished("bus", 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, entity.EventUserRegistered, events[0].Type)
+	assert.NotContains(t, string(events[0].Payload), "password")
+	assert.Contains(t, string(events[0].Payload), "j@j.com")
+}
+
+func TestEventPublishing(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	eventDB := NewEvent(productDB.DB)
+	for _, name := range []string{"Mouse", "Keyboard", "Cable"} {
+		product, _ := entity.NewProduct(name, brl(1000))
+		assert.NoError(t, productDB.Create(product))
+	}
+	events, err := eventDB.FindUnpublished("bus", 2)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	assert.NoError(t, eventDB.MarkFailed("nats", events[0].ID.String(), "connection refused"))
+	assert.NoError(t, eventDB.MarkFailed("nats", events[0].ID.String(), "connection refused"))
+	var failed entity.EventPublication
+	assert.NoError(t, eventDB.DB.First(&failed, "event_id = ? AND consumer = ?", events[0].ID, "nats").Error)
+	assert.Equal(t, 2, failed.Attempts)
+	assert.Equal(t, "connection refused", failed.LastError)
+	assert.Nil(t, failed.PublishedAt)
+
+	// Each consumer goes through the events on its own.
+	now := time.Now()
+	assert.NoError(t, eventDB.MarkPublished("bus", events[0].ID.String(), now.Add(-time.Hour)))
+	assert.NoError(t, eventDB.MarkPublished("bus", events[1].ID.String(), now))
+	assert.NoError(t, eventDB.MarkPublished("nats", events[0].ID.String(), now.Add(-time.Hour)))
+	unpublished, err := eventDB.FindUnpublished("bus", 10)
+	assert.NoError(t, err)
+	assert.Len(t, unpublished, 1)
+	unpublished, err = eventDB.FindUnpublished("nats", 10)
+	assert.NoError(t, err)
+	assert.Len(t, unpublished, 2)
+	assert.Equal(t, events[1].ID, unpublished[0].ID)
+
+	// Events are published once every consumer got them.
+	completed, err := eventDB.Complete([]string{"bus", "nats"}, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), completed)
+	unpublished, err = eventDB.FindUnpublished("webhooks", 10)
+	assert.NoError(t, err)
+	assert.Len(t, unpublished, 2)
+
+	deleted, err := eventDB.DeletePublished(now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	var publications int64
+	eventDB.DB.Model(&entity.EventPublication{}).Count(&publications)
+	assert.Equal(t, int64(1), publications)
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRate struct {
+	DB *gorm.DB
+}
+
+func NewExchangeRate(db *gorm.DB) *ExchangeRate {
+	return &ExchangeRate{DB: db}
+}
+
+// Save creates the rate or replaces the rate of the same currency pair.
+func (e *ExchangeRate) Save(rate *entity.ExchangeRate) error {
+	return e.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(rate).Error
+}
+
+// SaveAll saves every rate or none of them.
+func (e *ExchangeRate) SaveAll(rates []entity.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return e.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rates).Error
+	})
+}
+
+func (e *ExchangeRate) FindAll() ([]entity.ExchangeRate, error) {
+	rates := []entity.ExchangeRate{}
+	err := e.DB.Order("base asc, quote asc").Find(&rates).Error
+	return rates, err
+}
+
+// Find returns a rate converting between base and quote, which may be the
+// rate of the inverse pair when only that one is registered.
+func (e *ExchangeRate) Find(base, quote string) (*entity.ExchangeRate, error) {
+	var rate entity.ExchangeRate
+	err := e.DB.
+		Where("(base = ? AND quote = ?) OR (base = ? AND quote = ?)", base, quote, quote, base).
+		Order(clause.Expr{SQL: "base = ? DESC", Vars: []interface{}{base}}).
+		First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (e *ExchangeRate) Delete(base, quote string) error {
+	result := e.DB.Delete(&entity.ExchangeRate{}, "base = ? AND quote = ?", base, quote)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestExchangeRateSave(t *testing.T) {
+	exchangeRateDB := NewExchangeRate(createTestDB(t))
+	rate, _ := entity.NewExchangeRate("USD", "BRL", "5.02")
+	assert.NoError(t, exchangeRateDB.Save(rate))
+	rate, _ = entity.NewExchangeRate("USD", "BRL", "4.98")
+	assert.NoError(t, exchangeRateDB.Save(rate))
+
+	rates, err := exchangeRateDB.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, rates, 1)
+	assert.Equal(t, "4.98", rates[0].Rate)
+}
+
+func TestExchangeRateFind(t *testing.T) {
+	exchangeRateDB := NewExchangeRate(createTestDB(t))
+	usdBrl, _ := entity.NewExchangeRate("USD", "BRL", "5")
+	assert.NoError(t, exchangeRateDB.Save(usdBrl))
+
+	rate, err := exchangeRateDB.Find("USD", "BRL")
+	assert.NoError(t, err)
+	assert.Equal(t, "5", rate.Rate)
+
+	// Only the inverse pair is registered.
+	rate, err = exchangeRateDB.Find("BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", rate.Base)
+
+	brlUsd, _ := entity.NewExchangeRate("BRL", "USD", "0.21")
+	assert.NoError(t, exchangeRateDB.Save(brlUsd))
+	rate, err = exchangeRateDB.Find("BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.21", rate.Rate)
+
+	_, err = exchangeRateDB.Find("BRL", "EUR")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestExchangeRateSaveAllAndDelete(t *testing.T) {
+	exchangeRateDB := NewExchangeRate(createTestDB(t))
+	assert.NoError(t, exchangeRateDB.SaveAll([]entity.ExchangeRate{
+		{Base: "EUR", Quote: "BRL", Rate: "5.4"},
+		{Base: "USD", Quote: "BRL", Rate: "5"},
+	}))
+	rates, err := exchangeRateDB.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+
+	assert.NoError(t, exchangeRateDB.Delete("EUR", "BRL"))
+	assert.ErrorIs(t, exchangeRateDB.Delete("EUR", "BRL"), gorm.ErrRecordNotFound)
+	rates, err = exchangeRateDB.FindAll()
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", rates[0].Base)
+}
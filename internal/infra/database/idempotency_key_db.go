@@ -0,0 +1,65 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyKey struct {
+	DB *gorm.DB
+}
+
+func NewIdempotencyKey(db *gorm.DB) *IdempotencyKey {
+	return &IdempotencyKey{DB: db}
+}
+
+// Reserve saves the key unless its owner already used it. It returns nil
+// when the key was reserved, and otherwise the key saved before, which may
+// still be waiting for its response. Expired keys are replaced.
+func (i *IdempotencyKey) Reserve(key *entity.IdempotencyKey) (*entity.IdempotencyKey, error) {
+	for {
+		result := i.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+		var existing []entity.IdempotencyKey
+		err := i.DB.Where("owner = ? AND key = ?", key.Owner, key.Key).Limit(1).Find(&existing).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) == 0 {
+			// Released since the insert, try again.
+			continue
+		}
+		if !existing[0].Expired(time.Now()) {
+			return &existing[0], nil
+		}
+		err = i.DB.Where("owner = ? AND key = ? AND expires_at <= ?", key.Owner, key.Key, time.Now()).
+			Delete(&entity.IdempotencyKey{}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Complete saves the response of the request the key was reserved for.
+func (i *IdempotencyKey) Complete(key *entity.IdempotencyKey) error {
+	return i.DB.Model(key).Select("status", "header", "body").Updates(key).Error
+}
+
+// Release forgets a key whose request failed, so it can be retried.
+func (i *IdempotencyKey) Release(owner, key string) error {
+	return i.DB.Where("owner = ? AND key = ?", owner, key).Delete(&entity.IdempotencyKey{}).Error
+}
+
+// DeleteExpired deletes the keys that expired by now, returning how many.
+func (i *IdempotencyKey) DeleteExpired(now time.Time) (int64, error) {
+	result := i.DB.Where("expires_at <= ?", now).Delete(&entity.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeyReserve(t *testing.T) {
+	keyDB := NewIdempotencyKey(createTestDB(t))
+	key, _ := entity.NewIdempotencyKey("user-1", "key-1", "fingerprint-1", time.Hour)
+	existing, err := keyDB.Reserve(key)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	again, _ := entity.NewIdempotencyKey("user-1", "key-1", "fingerprint-2", time.Hour)
+	existing, err = keyDB.Reserve(again)
+	assert.NoError(t, err)
+	assert.NotNil(t, existing)
+	assert.Equal(t, "fingerprint-1", existing.Fingerprint)
+	assert.False(t, existing.Completed())
+
+	key.Complete(http.StatusCreated, http.Header{"Location": {"/product/1"}}, []byte(`{"id":"1"}`))
+	assert.NoError(t, keyDB.Complete(key))
+	existing, err = keyDB.Reserve(again)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, existing.Status)
+	assert.Equal(t, "/product/1", existing.Header.Get("Location"))
+	assert.Equal(t, `{"id":"1"}`, string(existing.Body))
+
+	// Keys belong to their owner.
+	other, _ := entity.NewIdempotencyKey("user-2", "key-1", "fingerprint-1", time.Hour)
+	existing, err = keyDB.Reserve(other)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	assert.NoError(t, keyDB.Release("user-1", "key-1"))
+	existing, err = keyDB.Reserve(again)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+}
+
+func TestIdempotencyKeyReserve_Expired(t *testing.T) {
+	keyDB := NewIdempotencyKey(createTestDB(t))
+	key, _ := entity.NewIdempotencyKey("user-1", "key-1", "fingerprint-1", -time.Minute)
+	_, err := keyDB.Reserve(key)
+	assert.NoError(t, err)
+
+	again, _ := entity.NewIdempotencyKey("user-1", "key-1", "fingerprint-2", time.Hour)
+	existing, err := keyDB.Reserve(again)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	expired, _ := entity.NewIdempotencyKey("user-2", "key-1", "fingerprint-1", -time.Minute)
+	_, err = keyDB.Reserve(expired)
+	assert.NoError(t, err)
+	deleted, err := keyDB.DeleteExpired(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -1,16 +1,186 @@
 package database
 
-import "github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+import (
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+)
 
 type UserInterface interface {
+	As(source entity.AuditSource) UserInterface
 	Create(user *entity.User) error
 	FindByEmail(email string) (*entity.User, error)
+	FindById(id string) (*entity.User, error)
+	FindAll(page, limit int, search string) ([]entity.User, int64, error)
+	Update(user *entity.User) error
+	Delete(id string) error
+}
+
+// ProductFilter narrows down the products listed by FindAll. Zero values
+// don't filter anything.
+type ProductFilter struct {
+	// Statuses keeps products in any of the statuses.
+	Statuses           []string
+	CategoryID         string
+	IncludeDescendants bool
+	// Tags matches products carrying any of the tags, or all of them when
+	// MatchAllTags is set.
+	Tags         []string
+	MatchAllTags bool
+	// InStock keeps products with (true) or without (false) available stock.
+	InStock    *bool
+	LowStock   bool
+	Attributes []AttributeFilter
+}
+
+// AttributeFilter keeps products whose attribute has one of Values, given
+// in their text form, and whose number value is within Min and Max.
+type AttributeFilter struct {
+	Code     string
+	Values   []string
+	Min, Max *float64
 }
 
 type ProductInterface interface {
+	As(source entity.AuditSource) ProductInterface
 	Create(product *entity.Product) error
-	FindAll(page, limit int, sort string) ([]entity.Product, error)
+	CreateMany(products []*entity.Product) error
+	Transaction(fn func(tx ProductInterface) error) error
+	FindAll(page, limit int, sort string, filter ProductFilter) ([]entity.Product, error)
+	Stream(filter ProductFilter, batchSize int, fn func(products []entity.Product) error) error
+	Count(filter ProductFilter) (int64, error)
+	Matcher(filter ProductFilter) (func(product *entity.Product) bool, error)
 	FindById(id string) (*entity.Product, error)
+	FindByNames(names []string) ([]entity.Product, error)
 	Update(product *entity.Product) error
+	Delete(id, deletedBy string, version int) error
+	FindDeleted(page, limit int) ([]entity.Product, error)
+	Restore(id string) error
+	Purge(deletedBefore time.Time) ([]entity.Product, error)
+}
+
+type ClientInterface interface {
+	Create(client *entity.Client) error
+	FindById(id string) (*entity.Client, error)
+}
+
+type CategoryInterface interface {
+	Create(category *entity.Category) error
+	FindAll() ([]entity.Category, error)
+	FindById(id string) (*entity.Category, error)
+	FindByIds(ids []string) ([]entity.Category, error)
+	FindSubtree(id string) ([]entity.Category, error)
+	DescendantIds(id string) ([]string, error)
+	Update(category *entity.Category) error
 	Delete(id string) error
 }
+
+type TagInterface interface {
+	FindAll() ([]entity.TagUsage, error)
+}
+
+type StockMovementInterface interface {
+	As(source entity.AuditSource) StockMovementInterface
+	Apply(movement *entity.StockMovement) (*entity.Product, error)
+	FindByProductId(productID string, page, limit int) ([]entity.StockMovement, error)
+}
+
+type ExchangeRateInterface interface {
+	Save(rate *entity.ExchangeRate) error
+	SaveAll(rates []entity.ExchangeRate) error
+	FindAll() ([]entity.ExchangeRate, error)
+	Find(base, quote string) (*entity.ExchangeRate, error)
+	Delete(base, quote string) error
+}
+
+type ProductStatusChangeInterface interface {
+	FindByProductId(productID string, page, limit int) ([]entity.ProductStatusChange, error)
+}
+
+type PriceChangeInterface interface {
+	FindByProductId(productID string, page, limit int) ([]entity.PriceChange, error)
+}
+
+type ScheduledPriceInterface interface {
+	Create(scheduledPrice *entity.ScheduledPrice) error
+	FindById(id string) (*entity.ScheduledPrice, error)
+	FindByProductId(productID string) ([]entity.ScheduledPrice, error)
+	Update(scheduledPrice *entity.ScheduledPrice) error
+	ApplyDue(now time.Time) (int, error)
+}
+
+type VariantInterface interface {
+	As(source entity.AuditSource) VariantInterface
+	Create(variant *entity.Variant) error
+	FindById(productID, id string) (*entity.Variant, error)
+	FindByProductId(productID string) ([]entity.Variant, error)
+	FindBySKU(sku string) (*entity.Variant, error)
+	Update(variant *entity.Variant) error
+	Delete(productID, id string) error
+}
+
+type AttributeDefinitionInterface interface {
+	Create(definition *entity.AttributeDefinition) error
+	FindAll() ([]entity.AttributeDefinition, error)
+	FindByCode(code string) (*entity.AttributeDefinition, error)
+	Update(definition *entity.AttributeDefinition) error
+	Delete(code string) error
+}
+
+type ProductImageInterface interface {
+	As(source entity.AuditSource) ProductImageInterface
+	Create(image *entity.ProductImage) error
+	FindById(productID, id string) (*entity.ProductImage, error)
+	FindByProductId(productID string) ([]entity.ProductImage, error)
+	Reorder(productID string, ids []string) ([]entity.ProductImage, error)
+	Delete(productID, id string) error
+}
+
+type JobInterface interface {
+	Create(job *entity.Job) error
+	FindById(id string) (*entity.Job, error)
+	Claim() (*entity.Job, error)
+	UpdateProgress(id string, processed, total int) error
+	Finish(job *entity.Job) error
+	Requeue() (int64, error)
+	Purge(finishedBefore time.Time) ([]entity.Job, error)
+}
+
+type IdempotencyKeyInterface interface {
+	Reserve(key *entity.IdempotencyKey) (*entity.IdempotencyKey, error)
+	Complete(key *entity.IdempotencyKey) error
+	Release(owner, key string) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type AuditInterface interface {
+	Append(entry *entity.AuditEntry) error
+	FindAll(filter AuditFilter, page, limit int) ([]entity.AuditEntry, error)
+	Verify() (int64, *entity.AuditEntry, error)
+}
+
+type EventInterface interface {
+	FindUnpublished(consumer string, limit int) ([]entity.Event, error)
+	MarkPublished(consumer, id string, at time.Time) error
+	MarkFailed(consumer, id, reason string) error
+	Complete(consumers []string, at time.Time) (int64, error)
+	DeletePublished(publishedBefore time.Time) (int64, error)
+}
+
+type WebhookInterface interface {
+	Create(webhook *entity.Webhook) error
+	FindById(id string) (*entity.Webhook, error)
+	FindAll(page, limit int) ([]entity.Webhook, error)
+	FindSubscribed(eventType string) ([]entity.Webhook, error)
+	Update(webhook *entity.Webhook) error
+	Delete(id string) error
+}
+
+type WebhookDeliveryInterface interface {
+	Enqueue(deliveries []*entity.WebhookDelivery) error
+	FindById(webhookID, id string) (*entity.WebhookDelivery, error)
+	FindByWebhookId(webhookID, status string, page, limit int) ([]entity.WebhookDelivery, error)
+	FindDue(now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	Update(delivery *entity.WebhookDelivery) error
+	DeleteFinished(finishedBefore time.Time) (int64, error)
+}
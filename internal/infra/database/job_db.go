@@ -0,0 +1,97 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Job struct {
+	DB *gorm.DB
+}
+
+func NewJob(db *gorm.DB) *Job {
+	return &Job{DB: db}
+}
+
+func (j *Job) Create(job *entity.Job) error {
+	return j.DB.Create(job).Error
+}
+
+func (j *Job) FindById(id string) (*entity.Job, error) {
+	var job entity.Job
+	if err := j.DB.First(&job, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Claim marks the oldest queued job as running and returns it, or returns
+// gorm.ErrRecordNotFound when no job is queued. The status is only changed
+// while the job is still queued, so two workers never claim the same job.
+func (j *Job) Claim() (*entity.Job, error) {
+	for {
+		// Find rather than First, so polling an empty queue isn't logged as
+		// an error.
+		var queued []entity.Job
+		if err := j.DB.Where("status = ?", entity.JobQueued).Order("created_at asc").Limit(1).Find(&queued).Error; err != nil {
+			return nil, err
+		}
+		if len(queued) == 0 {
+			return nil, gorm.ErrRecordNotFound
+		}
+		job := queued[0]
+		now := time.Now()
+		result := j.DB.Model(&entity.Job{}).Where("id = ? AND status = ?", job.ID, entity.JobQueued).
+			Updates(map[string]interface{}{"status": entity.JobRunning, "started_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = entity.JobRunning
+			job.StartedAt = &now
+			return &job, nil
+		}
+	}
+}
+
+// UpdateProgress records how many of the items of a job were processed.
+func (j *Job) UpdateProgress(id string, processed, total int) error {
+	return j.DB.Model(&entity.Job{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"processed": processed, "total": total}).Error
+}
+
+// Finish saves the outcome of a job that succeeded or failed.
+func (j *Job) Finish(job *entity.Job) error {
+	if !job.Finished() {
+		return errors.New("job isn't finished")
+	}
+	return j.DB.Model(job).Select("status", "processed", "total", "error", "result_key",
+		"result_content_type", "result_filename", "finished_at").Updates(job).Error
+}
+
+// Requeue puts the jobs left running when the server stopped back in the
+// queue, returning how many there were.
+func (j *Job) Requeue() (int64, error) {
+	result := j.DB.Model(&entity.Job{}).Where("status = ?", entity.JobRunning).
+		Updates(map[string]interface{}{"status": entity.JobQueued, "started_at": nil, "processed": 0})
+	return result.RowsAffected, result.Error
+}
+
+// Purge deletes the jobs that finished before finishedBefore, returning them
+// so their files can be removed too.
+func (j *Job) Purge(finishedBefore time.Time) ([]entity.Job, error) {
+	var jobs []entity.Job
+	err := j.DB.Where("status IN ? AND finished_at < ?", []string{entity.JobSucceeded, entity.JobFailed}, finishedBefore).
+		Find(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return jobs, err
+	}
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID.String()
+	}
+	return jobs, j.DB.Where("id IN ?", ids).Delete(&entity.Job{}).Error
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestJobClaim(t *testing.T) {
+	jobDB := NewJob(createTestDB(t))
+	_, err := jobDB.Claim()
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	first, _ := entity.NewJob(entity.JobProductExport, "{}", "user-1")
+	second, _ := entity.NewJob(entity.JobProductImport, "{}", "user-1")
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	assert.NoError(t, jobDB.Create(first))
+	assert.NoError(t, jobDB.Create(second))
+
+	claimed, err := jobDB.Claim()
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, claimed.ID)
+	assert.Equal(t, entity.JobRunning, claimed.Status)
+	assert.NotNil(t, claimed.StartedAt)
+	claimed, err = jobDB.Claim()
+	assert.NoError(t, err)
+	assert.Equal(t, second.ID, claimed.ID)
+	_, err = jobDB.Claim()
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, jobDB.UpdateProgress(claimed.ID.String(), 5, 10))
+	found, err := jobDB.FindById(claimed.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, found.Processed)
+	assert.Equal(t, 10, found.Total)
+}
+
+func TestJobFinishAndPurge(t *testing.T) {
+	jobDB := NewJob(createTestDB(t))
+	job, _ := entity.NewJob(entity.JobProductExport, "{}", "user-1")
+	assert.NoError(t, jobDB.Create(job))
+	assert.Error(t, jobDB.Finish(job))
+
+	job, err := jobDB.Claim()
+	assert.NoError(t, err)
+	job.Processed, job.Total = 3, 3
+	job.SetResult("jobs/1/products.csv", "text/csv", "products.csv")
+	assert.NoError(t, job.Succeed())
+	assert.NoError(t, jobDB.Finish(job))
+	found, err := jobDB.FindById(job.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.JobSucceeded, found.Status)
+	assert.Equal(t, "jobs/1/products.csv", found.ResultKey)
+	assert.Equal(t, 3, found.Processed)
+
+	purged, err := jobDB.Purge(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, purged)
+	purged, err = jobDB.Purge(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, purged, 1)
+	_, err = jobDB.FindById(job.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestJobRequeue(t *testing.T) {
+	jobDB := NewJob(createTestDB(t))
+	job, _ := entity.NewJob(entity.JobProductImport, "{}", "user-1")
+	assert.NoError(t, jobDB.Create(job))
+	_, err := jobDB.Claim()
+	assert.NoError(t, err)
+
+	requeued, err := jobDB.Requeue()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+	found, _ := jobDB.FindById(job.ID.String())
+	assert.Equal(t, entity.JobQueued, found.Status)
+	assert.Nil(t, found.StartedAt)
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"gorm.io/gorm"
+)
+
+// Migrate creates or updates the tables of every entity and converts data
+// written by previous versions of the API.
+func Migrate(db *gorm.DB, defaultCurrency string) error {
+	err := db.AutoMigrate(
+		&entity.Product{},
+		&entity.User{},
+		&entity.Client{},
+		&entity.Category{},
+		&entity.Tag{},
+		&entity.StockMovement{},
+		&entity.ProductPrice{},
+		&entity.ExchangeRate{},
+		&entity.PriceChange{},
+		&entity.ScheduledPrice{},
+		&entity.Variant{},
+		&entity.AttributeDefinition{},
+		&entity.AttributeValue{},
+		&entity.ProductImage{},
+		&entity.ProductStatusChange{},
+		&entity.Job{},
+		&entity.IdempotencyKey{},
+		&entity.AuditEntry{},
+		&entity.AuditLock{},
+		&entity.Event{},
+		&entity.EventPublication{},
+		&entity.Webhook{},
+		&entity.WebhookDelivery{},
+	)
+	if err != nil {
+		return err
+	}
+	if err = migrateFloatPrices(db, defaultCurrency); err != nil {
+		return err
+	}
+	if err = backfillStock(db); err != nil {
+		return err
+	}
+	if err = backfillStatus(db); err != nil {
+		return err
+	}
+	return backfillVersion(db)
+}
+
+// migrateFloatPrices converts the legacy float "price" column of products to
+// minor units in the default currency and drops it.
+func migrateFloatPrices(db *gorm.DB, currency string) error {
+	if !db.Migrator().HasColumn("products", "price") {
+		return nil
+	}
+	exponent, ok := pkgEntity.CurrencyExponent(currency)
+	if !ok {
+		return pkgEntity.ErrInvalidCurrency
+	}
+	factor := 1
+	for i := 0; i < exponent; i++ {
+		factor *= 10
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(
+			"UPDATE products SET price_amount = CAST(ROUND(price * ?) AS INTEGER), price_currency = ? WHERE price IS NOT NULL AND (price_currency IS NULL OR price_currency = '')",
+			factor, currency,
+		).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE products DROP COLUMN price").Error
+	})
+}
+
+// backfillStock sets the stock quantities of products created before
+// inventory was tracked, so stock filters don't have to deal with NULLs.
+func backfillStock(db *gorm.DB) error {
+	return db.Exec(`UPDATE products SET
+		stock = COALESCE(stock, 0),
+		reserved = COALESCE(reserved, 0),
+		low_stock_threshold = COALESCE(low_stock_threshold, 0)
+		WHERE stock IS NULL OR reserved IS NULL OR low_stock_threshold IS NULL`).Error
+}
+
+// backfillStatus publishes the products created before they had a status,
+// which were all visible until then.
+func backfillStatus(db *gorm.DB) error {
+	return db.Model(&entity.Product{}).
+		Where("status IS NULL OR status = ''").
+		Update("status", entity.ProductPublished).Error
+}
+
+// backfillVersion sets the first version of products created before they
+// were versioned.
+func backfillVersion(db *gorm.DB) error {
+	return db.Exec("UPDATE products SET version = 1 WHERE version IS NULL OR version = 0").Error
+}
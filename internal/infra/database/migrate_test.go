@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrate_ConvertsFloatPrices(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	// Table as created by the versions storing prices as float64.
+	assert.NoError(t, db.Exec("CREATE TABLE products (id text, name text, price real, created_at datetime, PRIMARY KEY (id))").Error)
+	id := pkgEntity.NewID()
+	assert.NoError(t, db.Exec("INSERT INTO products (id, name, price, created_at) VALUES (?, ?, ?, ?)", id, "Legacy", 0.1+0.2, time.Now()).Error)
+
+	assert.NoError(t, Migrate(db, "BRL"))
+	assert.False(t, db.Migrator().HasColumn("products", "price"))
+
+	product, err := NewProduct(db).FindById(id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, pkgEntity.Money{Amount: 30, Currency: "BRL"}, product.Price)
+	// Products were all visible before they had a status.
+	assert.Equal(t, "published", product.Status)
+	assert.Equal(t, 1, product.Version)
+
+	no := false
+	products, err := NewProduct(db).FindAll(0, 0, "asc", ProductFilter{InStock: &no})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+
+	// Running it again is a no-op.
+	assert.NoError(t, Migrate(db, "BRL"))
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type PriceChange struct {
+	DB *gorm.DB
+}
+
+func NewPriceChange(db *gorm.DB) *PriceChange {
+	return &PriceChange{DB: db}
+}
+
+// FindByProductId lists the price history of a product, newest first.
+func (p *PriceChange) FindByProductId(productID string, page, limit int) ([]entity.PriceChange, error) {
+	changes := []entity.PriceChange{}
+	query := p.DB.Where("product_id = ?", productID).Order("changed_at desc")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&changes).Error
+	return changes, err
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceChange_RecordedOnProductUpdate(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	product, _ := entity.NewProduct("Mouse", brl(3999))
+	assert.NoError(t, productDB.Create(product))
+
+	product.Name = "Gaming mouse"
+	assert.NoError(t, productDB.Update(product))
+	product.Price = brl(4599)
+	product.UpdatedBy = "user-1"
+	assert.NoError(t, productDB.Update(product))
+	product.Price = brl(4299)
+	product.UpdatedBy = "user-2"
+	assert.NoError(t, productDB.Update(product))
+
+	priceChangeDB := NewPriceChange(productDB.DB)
+	changes, err := priceChangeDB.FindByProductId(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, brl(4599), changes[0].OldPrice)
+	assert.Equal(t, brl(4299), changes[0].NewPrice)
+	assert.Equal(t, "user-2", changes[0].ChangedBy)
+	assert.Equal(t, brl(3999), changes[1].OldPrice)
+	assert.Equal(t, "user-1", changes[1].ChangedBy)
+
+	changes, err = priceChangeDB.FindByProductId(product.ID.String(), 2, 1)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "user-1", changes[0].ChangedBy)
+}
@@ -1,14 +1,25 @@
 package database
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
 	"gorm.io/gorm"
 )
 
+// createBatchSize is how many products CreateMany inserts per statement.
+const createBatchSize = 100
+
+// ErrVersionConflict is returned when the product was changed since the
+// version being updated was read.
+var ErrVersionConflict = errors.New("product was changed by someone else, reload it and try again")
+
 type Product struct {
 	DB *gorm.DB
+	// Source is who the changes are recorded in the audit log as made by.
+	Source entity.AuditSource
 }
 
 func NewProduct(db *gorm.DB) *Product {
@@ -17,44 +28,451 @@ func NewProduct(db *gorm.DB) *Product {
 	}
 }
 
+// As returns a copy of the repository whose changes are audited as made by
+// source.
+func (p *Product) As(source entity.AuditSource) ProductInterface {
+	return &Product{DB: p.DB, Source: source}
+}
+
+// Create saves the product along with a product.created event and its
+// audit entry.
 func (p *Product) Create(product *entity.Product) error {
-	return p.DB.Create(product).Error
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Categories.*", "Variants", "Images").Create(product).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, p.Source, entity.AuditCreate, entity.AuditProduct, product.ID.String(), nil, product); err != nil {
+			return err
+		}
+		return recordEvent(tx, entity.EventProductCreated, product.ID.String(), product)
+	})
+}
+
+// CreateMany inserts the products a batch at a time, along with their
+// prices, tags, attributes, product.created events and audit entries.
+// Either all of them are created or none is.
+func (p *Product) CreateMany(products []*entity.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Categories.*", "Variants", "Images").CreateInBatches(products, createBatchSize).Error; err != nil {
+			return err
+		}
+		events := make([]*entity.Event, len(products))
+		for i, product := range products {
+			event, err := entity.NewEvent(entity.EventProductCreated, product.ID.String(), product)
+			if err != nil {
+				return err
+			}
+			events[i] = event
+			if err = recordAudit(tx, p.Source, entity.AuditCreate, entity.AuditProduct, product.ID.String(), nil, product); err != nil {
+				return err
+			}
+		}
+		return tx.CreateInBatches(events, createBatchSize).Error
+	})
+}
+
+// Transaction runs fn with products stored in a single transaction, which
+// is rolled back when fn fails.
+func (p *Product) Transaction(fn func(tx ProductInterface) error) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&Product{DB: tx, Source: p.Source})
+	})
 }
 
-func (p *Product) FindAll(page, limit int, sort string) ([]entity.Product, error) {
+func (p *Product) FindAll(page, limit int, sort string, filter ProductFilter) ([]entity.Product, error) {
 	sort = strings.ToLower(sort)
 	if sort != "" && sort != "asc" && sort != "desc" {
 		sort = "asc"
 	}
+	query, err := p.filter(p.preload(), filter)
+	if err != nil {
+		return nil, err
+	}
 	var products []entity.Product
-	var err error
 	if page != 0 && limit != 0 {
-		err = p.DB.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
+		err = query.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
 	} else {
-		err = p.DB.Order("created_at " + sort).Find(&products).Error
+		err = query.Order("created_at " + sort).Find(&products).Error
 	}
 	return products, err
 }
 
+// Stream calls fn with the products that match the filter, oldest first,
+// batchSize products at a time. Each batch is read by a new query starting
+// after the last product of the previous one, so only a batch is ever held
+// in memory.
+func (p *Product) Stream(filter ProductFilter, batchSize int, fn func(products []entity.Product) error) error {
+	var last *entity.Product
+	for {
+		query, err := p.filter(p.preload(), filter)
+		if err != nil {
+			return err
+		}
+		if last != nil {
+			query = query.Where("(created_at > ? OR (created_at = ? AND id > ?))", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+		var products []entity.Product
+		if err = query.Order("created_at asc, id asc").Limit(batchSize).Find(&products).Error; err != nil {
+			return err
+		}
+		if len(products) == 0 {
+			return nil
+		}
+		if err = fn(products); err != nil {
+			return err
+		}
+		if len(products) < batchSize {
+			return nil
+		}
+		last = &products[len(products)-1]
+	}
+}
+
+// Count tells how many products match the filter.
+func (p *Product) Count(filter ProductFilter) (int64, error) {
+	query, err := p.filter(p.DB.Model(&entity.Product{}), filter)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = query.Count(&count).Error
+	return count, err
+}
+
+// preload loads the associations returned with every product.
+func (p *Product) preload() *gorm.DB {
+	return p.DB.Preload("Categories").Preload("Tags").Preload("Prices").Preload("Attributes").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at asc")
+		}).
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position asc")
+		})
+}
+
+func (p *Product) filter(query *gorm.DB, filter ProductFilter) (*gorm.DB, error) {
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.CategoryID != "" {
+		categoryIds := []string{filter.CategoryID}
+		if filter.IncludeDescendants {
+			descendants, err := NewCategory(p.DB).DescendantIds(filter.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			categoryIds = append(categoryIds, descendants...)
+		}
+		query = query.Where("id IN (SELECT product_id FROM product_categories WHERE category_id IN ?)", categoryIds)
+	}
+	if len(filter.Tags) > 0 {
+		if filter.MatchAllTags {
+			query = query.Where("id IN (SELECT product_id FROM product_tags WHERE tag_name IN ? GROUP BY product_id HAVING COUNT(DISTINCT tag_name) = ?)", filter.Tags, len(filter.Tags))
+		} else {
+			query = query.Where("id IN (SELECT product_id FROM product_tags WHERE tag_name IN ?)", filter.Tags)
+		}
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			query = query.Where("stock - reserved > 0")
+		} else {
+			query = query.Where("stock - reserved <= 0")
+		}
+	}
+	if filter.LowStock {
+		query = query.Where("stock - reserved <= low_stock_threshold")
+	}
+	for _, attribute := range filter.Attributes {
+		values := p.DB.Model(&entity.AttributeValue{}).Select("product_id").Where("code = ?", attribute.Code)
+		if len(attribute.Values) > 0 {
+			values = values.Where("value IN ?", attribute.Values)
+		}
+		if attribute.Min != nil {
+			values = values.Where("number >= ?", *attribute.Min)
+		}
+		if attribute.Max != nil {
+			values = values.Where("number <= ?", *attribute.Max)
+		}
+		query = query.Where("id IN (?)", values)
+	}
+	return query, nil
+}
+
+// Matcher returns a function telling whether a product, such as one read
+// from an event, matches the filter the way FindAll would match it.
+// Subcategories are looked up once, when the matcher is made.
+func (p *Product) Matcher(filter ProductFilter) (func(product *entity.Product) bool, error) {
+	var categoryIds []string
+	if filter.CategoryID != "" {
+		categoryIds = []string{filter.CategoryID}
+		if filter.IncludeDescendants {
+			descendants, err := NewCategory(p.DB).DescendantIds(filter.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			categoryIds = append(categoryIds, descendants...)
+		}
+	}
+	return func(product *entity.Product) bool {
+		if len(filter.Statuses) > 0 && !containsString(filter.Statuses, product.Status) {
+			return false
+		}
+		if categoryIds != nil && !inCategories(product, categoryIds) {
+			return false
+		}
+		if len(filter.Tags) > 0 && !hasTags(product, filter.Tags, filter.MatchAllTags) {
+			return false
+		}
+		available := product.Stock - product.Reserved
+		if filter.InStock != nil && (available > 0) != *filter.InStock {
+			return false
+		}
+		if filter.LowStock && available > product.LowStockThreshold {
+			return false
+		}
+		for _, attribute := range filter.Attributes {
+			if !hasAttribute(product, attribute) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func inCategories(product *entity.Product, categoryIds []string) bool {
+	for _, category := range product.Categories {
+		if containsString(categoryIds, category.ID.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTags(product *entity.Product, tags []string, all bool) bool {
+	matched := 0
+	for _, tag := range tags {
+		if product.HasTag(tag) {
+			matched++
+		}
+	}
+	if all {
+		return matched == len(tags)
+	}
+	return matched > 0
+}
+
+func hasAttribute(product *entity.Product, filter AttributeFilter) bool {
+	for _, value := range product.Attributes {
+		if value.Code != filter.Code {
+			continue
+		}
+		if len(filter.Values) > 0 && !containsString(filter.Values, value.Value) {
+			return false
+		}
+		if filter.Min != nil && (value.Number == nil || *value.Number < *filter.Min) {
+			return false
+		}
+		if filter.Max != nil && (value.Number == nil || *value.Number > *filter.Max) {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
 func (p *Product) FindById(id string) (*entity.Product, error) {
 	var product entity.Product
-	if err := p.DB.First(&product, "id = ?", id).Error; err != nil {
+	if err := p.preload().First(&product, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &product, nil
 }
 
+// FindByNames finds the products with any of the names, a hundred names
+// at a time so no query has too many parameters.
+func (p *Product) FindByNames(names []string) ([]entity.Product, error) {
+	products := []entity.Product{}
+	for start := 0; start < len(names); start += createBatchSize {
+		end := start + createBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		var found []entity.Product
+		if err := p.preload().Where("name IN ?", names[start:end]).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		products = append(products, found...)
+	}
+	return products, nil
+}
+
+// Update saves the product, recording the change in its price history when
+// the price changed, in its status history when the status changed, in the
+// audit log and as a product.updated event. It only writes over the version
+// of the product it was given, failing with ErrVersionConflict when the
+// product was changed since, and moves the product to the next version.
 func (p *Product) Update(product *entity.Product) error {
-	if _, err := p.FindById(product.ID.String()); err != nil {
+	current, err := p.FindById(product.ID.String())
+	if err != nil {
 		return err
 	}
-	return p.DB.Save(product).Error
+	if current.Version != product.Version {
+		return ErrVersionConflict
+	}
+	err = p.DB.Transaction(func(tx *gorm.DB) error {
+		if current.Price != product.Price {
+			change := entity.NewPriceChange(product.ID, current.Price, product.Price, product.UpdatedBy)
+			if err := tx.Create(change).Error; err != nil {
+				return err
+			}
+		}
+		if current.Status != product.Status {
+			change := entity.NewProductStatusChange(product.ID, current.Status, product.Status, product.UpdatedBy)
+			if err := tx.Create(change).Error; err != nil {
+				return err
+			}
+		}
+		// Stock quantities only change through stock movements.
+		saved := *product
+		saved.Version++
+		result := tx.Model(&saved).Where("version = ?", product.Version).Select("*").
+			Omit("ID", "CreatedAt", "DeletedAt", "DeletedBy", "Categories", "Tags", "Prices", "Variants", "Attributes", "Images", "Stock", "Reserved").
+			Updates(&saved)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if err := tx.Where("product_id = ?", product.ID).Delete(&entity.ProductPrice{}).Error; err != nil {
+			return err
+		}
+		if len(product.Prices) > 0 {
+			if err := tx.Create(&product.Prices).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("product_id = ?", product.ID).Delete(&entity.AttributeValue{}).Error; err != nil {
+			return err
+		}
+		if len(product.Attributes) > 0 {
+			if err := tx.Create(&product.Attributes).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(product).Omit("Categories.*").Association("Categories").Replace(product.Categories); err != nil {
+			return err
+		}
+		if err := tx.Model(product).Association("Tags").Replace(product.Tags); err != nil {
+			return err
+		}
+		if err := recordAudit(tx, p.Source, entity.AuditUpdate, entity.AuditProduct, product.ID.String(), current, &saved); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, product.ID.String())
+	})
+	if err != nil {
+		return err
+	}
+	product.Version++
+	return nil
+}
+
+// Delete moves the product to the trash, keeping everything it owns so it
+// can be restored, and records a product.deleted event and its audit
+// entry. When version isn't zero the product is only deleted if it is still
+// at that version.
+func (p *Product) Delete(id, deletedBy string, version int) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := NewProduct(tx).FindById(id)
+		if err != nil {
+			return err
+		}
+		query := tx.Model(&entity.Product{}).Where("id = ?", id)
+		if version != 0 {
+			query = query.Where("version = ?", version)
+		}
+		result := query.Updates(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"deleted_by": deletedBy,
+			"version":    gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if err = recordAudit(tx, p.Source, entity.AuditDelete, entity.AuditProduct, id, before, nil); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductDeleted, id)
+	})
 }
 
-func (p *Product) Delete(id string) error {
-	product, err := p.FindById(id)
+// FindDeleted lists the products in the trash, most recently deleted first.
+func (p *Product) FindDeleted(page, limit int) ([]entity.Product, error) {
+	products := []entity.Product{}
+	query := p.preload().Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&products).Error
+	return products, err
+}
+
+// Restore takes the product out of the trash, which is published as a
+// product.updated event and audited.
+func (p *Product) Restore(id string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&entity.Product{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": "", "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		restored, err := NewProduct(tx).FindById(id)
+		if err != nil {
+			return err
+		}
+		if err = recordAudit(tx, p.Source, entity.AuditRestore, entity.AuditProduct, id, nil, restored); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, id)
+	})
+}
+
+// Purge permanently deletes the products that went to the trash before
+// deletedBefore together with everything they own and their history,
+// recording each purge in the audit log. It returns them so the files of
+// their images can be removed too.
+func (p *Product) Purge(deletedBefore time.Time) ([]entity.Product, error) {
+	var products []entity.Product
+	err := p.preload().Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).Find(&products).Error
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for i := range products {
+		err = p.DB.Transaction(func(tx *gorm.DB) error {
+			for _, history := range []interface{}{&entity.StockMovement{}, &entity.PriceChange{}, &entity.ScheduledPrice{}, &entity.ProductStatusChange{}} {
+				if err := tx.Where("product_id = ?", products[i].ID).Delete(history).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Unscoped().Select("Categories", "Tags", "Prices", "Variants", "Attributes", "Images").Delete(&products[i]).Error; err != nil {
+				return err
+			}
+			return recordAudit(tx, p.Source, entity.AuditPurge, entity.AuditProduct, products[i].ID.String(), &products[i], nil)
+		})
+		if err != nil {
+			return products[:i], err
+		}
 	}
-	return p.DB.Delete(product).Error
+	return products, nil
 }
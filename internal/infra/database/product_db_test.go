@@ -1,9 +1,12 @@
 package database
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
 	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
@@ -15,14 +18,14 @@ import (
 func Test_ProductCreate(t *testing.T) {
 	productDB, err := createMemoryDB()
 	assert.NoError(t, err)
-	product, err := entity.NewProduct("Product 1", 10.)
+	product, err := entity.NewProduct("Product 1", brl(1000))
 	assert.NoError(t, err)
 	err = productDB.Create(product)
 	assert.Nil(t, err)
 	assert.NotNil(t, product)
 	assert.NotEmpty(t, product.ID)
 	assert.Equal(t, product.Name, "Product 1")
-	assert.Equal(t, product.Price, 10.0)
+	assert.Equal(t, brl(1000), product.Price)
 	assert.NotNil(t, product.CreatedAt)
 }
 
@@ -30,29 +33,29 @@ func Test_ProductFindAll(t *testing.T) {
 	productDB, err := createMemoryDB()
 	assert.NoError(t, err)
 	for i := 0; i < 24; i++ {
-		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100)
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), brl(rand.Int63n(10000)+1))
 		assert.NoError(t, err)
 		productDB.Create(product)
 	}
-	products, err := productDB.FindAll(1, 10, "asc")
+	products, err := productDB.FindAll(1, 10, "asc", ProductFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 0", products[0].Name)
 	assert.Equal(t, "Product 9", products[9].Name)
 
-	products, err = productDB.FindAll(2, 10, "asc")
+	products, err = productDB.FindAll(2, 10, "asc", ProductFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 10", products[0].Name)
 	assert.Equal(t, "Product 19", products[9].Name)
 
-	products, err = productDB.FindAll(3, 10, "asc")
+	products, err = productDB.FindAll(3, 10, "asc", ProductFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, products, 4)
 	assert.Equal(t, "Product 20", products[0].Name)
 	assert.Equal(t, "Product 23", products[3].Name)
 
-	products, err = productDB.FindAll(1, 10, "desc")
+	products, err = productDB.FindAll(1, 10, "desc", ProductFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 23", products[0].Name)
@@ -61,7 +64,7 @@ func Test_ProductFindAll(t *testing.T) {
 func Test_ProductFindById(t *testing.T) {
 	productDB, err := createMemoryDB()
 	assert.NoError(t, err)
-	p, err := entity.NewProduct("Product 1", 10.)
+	p, err := entity.NewProduct("Product 1", brl(1000))
 	assert.NoError(t, err)
 	err = productDB.Create(p)
 	assert.NoError(t, err)
@@ -82,16 +85,16 @@ func Test_ProductFindById_ShouldNotFindProductThatDoesNotExist(t *testing.T) {
 func Test_ProductUpdate(t *testing.T) {
 	productDB, err := createMemoryDB()
 	assert.NoError(t, err)
-	p, err := entity.NewProduct("Product 1", 10.)
+	p, err := entity.NewProduct("Product 1", brl(1000))
 	assert.NoError(t, err)
 	err = productDB.Create(p)
 	assert.NoError(t, err)
 	p.Name = "Updated product"
-	p.Price = 5.
+	p.Price = brl(500)
 	err = productDB.Update(p)
 	assert.Nil(t, err)
 	assert.Equal(t, p.Name, "Updated product")
-	assert.Equal(t, p.Price, 5.0)
+	assert.Equal(t, brl(500), p.Price)
 	assert.NotEmpty(t, p.ID)
 }
 
@@ -99,18 +102,86 @@ func Test_ProductDelete(t *testing.T) {
 	productDB, err := createMemoryDB()
 	assert.NoError(t, err)
 
-	p, err := entity.NewProduct("Product 1", 10.)
+	p, err := entity.NewProduct("Product 1", brl(1000))
 	assert.NoError(t, err)
 
 	err = productDB.Create(p)
 	assert.NoError(t, err)
 
-	err = productDB.Delete(p.ID.String())
+	err = productDB.Delete(p.ID.String(), "user-1", 0)
 	assert.NoError(t, err)
 
 	product, err := productDB.FindById(p.ID.String())
 	assert.Error(t, err)
 	assert.Nil(t, product)
+	products, err := productDB.FindAll(0, 0, "asc", ProductFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+	assert.ErrorIs(t, productDB.Delete(p.ID.String(), "user-1", 0), gorm.ErrRecordNotFound)
+}
+
+func Test_ProductTrash(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	kept, _ := entity.NewProduct("Kept", brl(1000))
+	deleted, _ := entity.NewProduct("Deleted", brl(1000))
+	deleted.Prices = []entity.ProductPrice{{ProductID: deleted.ID, Currency: "USD", Amount: 200}}
+	assert.NoError(t, productDB.Create(kept))
+	assert.NoError(t, productDB.Create(deleted))
+	movement, _ := entity.NewStockMovement(deleted.ID, entity.MovementReceipt, 5, "", "user-1")
+	scheduled, _ := entity.NewScheduledPrice(deleted, brl(800), time.Now().Add(time.Hour), nil, "user-1")
+	for _, history := range []interface{}{
+		movement,
+		scheduled,
+		entity.NewPriceChange(deleted.ID, brl(900), brl(1000), "user-1"),
+		entity.NewProductStatusChange(deleted.ID, entity.ProductDraft, entity.ProductPublished, "user-1"),
+	} {
+		assert.NoError(t, productDB.DB.Create(history).Error)
+	}
+	assert.NoError(t, productDB.Delete(deleted.ID.String(), "user-1", 0))
+
+	trash, err := productDB.FindDeleted(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, deleted.ID, trash[0].ID)
+	assert.Equal(t, "user-1", trash[0].DeletedBy)
+	assert.True(t, trash[0].DeletedAt.Valid)
+	assert.Len(t, trash[0].Prices, 1)
+
+	assert.NoError(t, productDB.Restore(deleted.ID.String()))
+	assert.ErrorIs(t, productDB.Restore(deleted.ID.String()), gorm.ErrRecordNotFound)
+	restored, err := productDB.FindById(deleted.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, restored.DeletedBy)
+	assert.Len(t, restored.Prices, 1)
+	trash, err = productDB.FindDeleted(0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+
+	// Only products deleted before the retention limit are purged.
+	assert.NoError(t, productDB.Delete(deleted.ID.String(), "user-1", 0))
+	purged, err := productDB.Purge(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, purged)
+	purged, err = productDB.Purge(time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Len(t, purged, 1)
+	trash, err = productDB.FindDeleted(0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+	for _, owned := range []interface{}{&entity.ProductPrice{}, &entity.StockMovement{}, &entity.PriceChange{}, &entity.ScheduledPrice{}, &entity.ProductStatusChange{}} {
+		var count int64
+		productDB.DB.Model(owned).Count(&count)
+		assert.Zero(t, count)
+	}
+	assert.ErrorIs(t, productDB.Restore(deleted.ID.String()), gorm.ErrRecordNotFound)
+
+	products, err := productDB.FindAll(0, 0, "asc", ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+}
+
+func brl(amount int64) pkgEntity.Money {
+	return pkgEntity.Money{Amount: amount, Currency: "BRL"}
 }
 
 func createMemoryDB() (*Product, error) {
@@ -118,6 +189,317 @@ func createMemoryDB() (*Product, error) {
 	if err != nil {
 		return nil, err
 	}
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.ProductPrice{}, &entity.PriceChange{}, &entity.Variant{}, &entity.AttributeValue{}, &entity.ProductImage{}, &entity.ProductStatusChange{}, &entity.Event{}, &entity.AuditEntry{}, &entity.AuditLock{})
 	return NewProduct(db), err
 }
+
+func Test_ProductFindAll_FilterByCategory(t *testing.T) {
+	db := createTestDB(t)
+	categoryDB := NewCategory(db)
+	productDB := NewProduct(db)
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	phones, _ := entity.NewCategory("Phones", &electronics.ID)
+	assert.NoError(t, categoryDB.Create(electronics))
+	assert.NoError(t, categoryDB.Create(phones))
+
+	tv, _ := entity.NewProduct("TV", brl(10000))
+	tv.Categories = []entity.Category{*electronics}
+	phone, _ := entity.NewProduct("Phone", brl(5000))
+	phone.Categories = []entity.Category{*phones}
+	book, _ := entity.NewProduct("Book", brl(1000))
+	for _, p := range []*entity.Product{tv, phone, book} {
+		assert.NoError(t, productDB.Create(p))
+	}
+
+	products, err := productDB.FindAll(0, 0, "asc", ProductFilter{CategoryID: electronics.ID.String()})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "TV", products[0].Name)
+	assert.Len(t, products[0].Categories, 1)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{CategoryID: electronics.ID.String(), IncludeDescendants: true})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, products, 3)
+}
+
+func Test_ProductUpdate_ReplacesCategories(t *testing.T) {
+	db := createTestDB(t)
+	categoryDB := NewCategory(db)
+	productDB := NewProduct(db)
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	books, _ := entity.NewCategory("Books", nil)
+	assert.NoError(t, categoryDB.Create(electronics))
+	assert.NoError(t, categoryDB.Create(books))
+
+	p, _ := entity.NewProduct("E-reader", brl(10000))
+	p.Categories = []entity.Category{*electronics}
+	assert.NoError(t, productDB.Create(p))
+
+	p.Categories = []entity.Category{*books}
+	assert.NoError(t, productDB.Update(p))
+
+	product, err := productDB.FindById(p.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, product.Categories, 1)
+	assert.Equal(t, books.ID, product.Categories[0].ID)
+}
+
+func Test_ProductUpdate_ReplacesPrices(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	p, _ := entity.NewProduct("Mouse", brl(3999))
+	assert.NoError(t, p.SetPriceOverride(pkgEntity.Money{Amount: 799, Currency: "USD"}))
+	assert.NoError(t, productDB.Create(p))
+
+	assert.NoError(t, p.SetPriceOverride(pkgEntity.Money{Amount: 749, Currency: "EUR"}))
+	assert.NoError(t, p.SetPriceOverride(pkgEntity.Money{Amount: 849, Currency: "USD"}))
+	assert.NoError(t, productDB.Update(p))
+	product, err := productDB.FindById(p.ID.String())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []entity.ProductPrice{
+		{ProductID: p.ID, Currency: "USD", Amount: 849},
+		{ProductID: p.ID, Currency: "EUR", Amount: 749},
+	}, product.Prices)
+
+	product.RemovePriceOverride("USD")
+	assert.NoError(t, productDB.Update(product))
+	product, err = productDB.FindById(p.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.ProductPrice{{ProductID: p.ID, Currency: "EUR", Amount: 749}}, product.Prices)
+
+	assert.NoError(t, productDB.Delete(p.ID.String(), "user-1", 0))
+	_, err = productDB.Purge(time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	for _, owned := range []interface{}{&entity.ProductPrice{}, &entity.StockMovement{}, &entity.PriceChange{}, &entity.ScheduledPrice{}, &entity.ProductStatusChange{}} {
+		var count int64
+		productDB.DB.Model(owned).Count(&count)
+		assert.Zero(t, count)
+	}
+}
+
+func Test_ProductUpdate_Version(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	p, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, productDB.Create(p))
+	assert.Equal(t, 1, p.Version)
+
+	first, _ := productDB.FindById(p.ID.String())
+	second, _ := productDB.FindById(p.ID.String())
+	first.Name = "First"
+	assert.NoError(t, productDB.Update(first))
+	assert.Equal(t, 2, first.Version)
+
+	// second was read before first was saved, so it can't overwrite it.
+	second.Name = "Second"
+	assert.ErrorIs(t, productDB.Update(second), ErrVersionConflict)
+	assert.Equal(t, 1, second.Version)
+	found, err := productDB.FindById(p.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "First", found.Name)
+	assert.Equal(t, 2, found.Version)
+
+	found.LowStockThreshold = 0
+	found.UpdatedBy = ""
+	assert.NoError(t, productDB.Update(found))
+	found, _ = productDB.FindById(p.ID.String())
+	assert.Equal(t, 3, found.Version)
+	assert.Empty(t, found.UpdatedBy)
+}
+
+func Test_ProductDelete_Version(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	p, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, productDB.Create(p))
+	p.Name = "Renamed"
+	assert.NoError(t, productDB.Update(p))
+
+	assert.ErrorIs(t, productDB.Delete(p.ID.String(), "user-1", 1), ErrVersionConflict)
+	assert.NoError(t, productDB.Delete(p.ID.String(), "user-1", 2))
+	assert.ErrorIs(t, productDB.Delete(p.ID.String(), "user-1", 3), gorm.ErrRecordNotFound)
+}
+
+func Test_ProductCreateMany(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	var products []*entity.Product
+	for i := 0; i < createBatchSize+5; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), brl(1000))
+		assert.NoError(t, err)
+		product.Tags = []entity.Tag{{Name: "bulk"}}
+		products = append(products, product)
+	}
+	assert.NoError(t, productDB.CreateMany(products))
+
+	found, err := productDB.FindAll(0, 0, "asc", ProductFilter{Tags: []string{"bulk"}})
+	assert.NoError(t, err)
+	assert.Len(t, found, createBatchSize+5)
+
+	// A duplicate id makes the whole batch fail.
+	again, _ := entity.NewProduct("Again", brl(1000))
+	assert.Error(t, productDB.CreateMany([]*entity.Product{again, products[0]}))
+	_, err = productDB.FindById(again.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func Test_ProductTransaction(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	p, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, productDB.Create(p))
+
+	created, _ := entity.NewProduct("Product 2", brl(1000))
+	err := productDB.Transaction(func(tx ProductInterface) error {
+		if err := tx.Create(created); err != nil {
+			return err
+		}
+		return tx.Delete(p.ID.String(), "user-1", 5)
+	})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	_, err = productDB.FindById(created.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	err = productDB.Transaction(func(tx ProductInterface) error {
+		if err := tx.Create(created); err != nil {
+			return err
+		}
+		return tx.Delete(p.ID.String(), "user-1", 1)
+	})
+	assert.NoError(t, err)
+	_, err = productDB.FindById(created.ID.String())
+	assert.NoError(t, err)
+	_, err = productDB.FindById(p.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func Test_ProductFindByNames(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	var names []string
+	for i := 0; i < createBatchSize+10; i++ {
+		p, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), brl(1000))
+		assert.NoError(t, productDB.Create(p))
+		names = append(names, p.Name)
+	}
+	products, err := productDB.FindByNames(append(names, "Missing"))
+	assert.NoError(t, err)
+	assert.Len(t, products, createBatchSize+10)
+
+	products, err = productDB.FindByNames([]string{"Product 3"})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Product 3", products[0].Name)
+}
+
+func Test_ProductStream(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	createdAt := time.Now()
+	for i := 0; i < 25; i++ {
+		p, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), brl(1000))
+		// Several products share a creation time, so the id breaks ties.
+		p.CreatedAt = createdAt.Add(time.Duration(i/3) * time.Second)
+		if i%2 == 0 {
+			p.Status = entity.ProductPublished
+		}
+		assert.NoError(t, productDB.Create(p))
+	}
+
+	var batches []int
+	seen := map[string]bool{}
+	err := productDB.Stream(ProductFilter{}, 10, func(products []entity.Product) error {
+		batches = append(batches, len(products))
+		for _, p := range products {
+			assert.False(t, seen[p.ID.String()])
+			seen[p.ID.String()] = true
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 10, 5}, batches)
+	assert.Len(t, seen, 25)
+
+	count := 0
+	err = productDB.Stream(ProductFilter{Statuses: []string{entity.ProductPublished}}, 5, func(products []entity.Product) error {
+		count += len(products)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 13, count)
+	total, err := productDB.Count(ProductFilter{Statuses: []string{entity.ProductPublished}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), total)
+
+	stop := errors.New("stop")
+	err = productDB.Stream(ProductFilter{}, 10, func(products []entity.Product) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+}
+
+func Test_ProductMatcher(t *testing.T) {
+	db := createTestDB(t)
+	categoryDB := NewCategory(db)
+	productDB := NewProduct(db)
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	phones, _ := entity.NewCategory("Phones", &electronics.ID)
+	assert.NoError(t, categoryDB.Create(electronics))
+	assert.NoError(t, categoryDB.Create(phones))
+	weight, _ := entity.NewAttributeDefinition("weight", "Weight", entity.AttributeNumber, "kg", entity.AttributeRules{})
+	definitions := []entity.AttributeDefinition{*weight}
+
+	tv, _ := entity.NewProduct("TV", brl(10000))
+	tv.Status = entity.ProductPublished
+	tv.Categories = []entity.Category{*electronics}
+	tv.AddTags([]entity.Tag{{Name: "sale"}, {Name: "new"}})
+	tv.Attributes, _ = entity.NewAttributeValues(tv.ID, map[string]interface{}{"weight": 12.0}, definitions)
+	phone, _ := entity.NewProduct("Phone", brl(5000))
+	phone.Categories = []entity.Category{*phones}
+	phone.AddTags([]entity.Tag{{Name: "sale"}})
+	phone.Attributes, _ = entity.NewAttributeValues(phone.ID, map[string]interface{}{"weight": 0.2}, definitions)
+	book, _ := entity.NewProduct("Book", brl(1000))
+	book.LowStockThreshold = 1
+	for _, p := range []*entity.Product{tv, phone, book} {
+		assert.NoError(t, productDB.Create(p))
+	}
+	// Stock only changes through movements, so it is set directly here.
+	assert.NoError(t, db.Model(&entity.Product{}).Where("id = ?", tv.ID).Update("stock", 3).Error)
+
+	min := 1.0
+	yes, no := true, false
+	filters := []ProductFilter{
+		{},
+		{Statuses: []string{entity.ProductPublished}},
+		{CategoryID: electronics.ID.String()},
+		{CategoryID: electronics.ID.String(), IncludeDescendants: true},
+		{Tags: []string{"sale", "new"}},
+		{Tags: []string{"sale", "new"}, MatchAllTags: true},
+		{InStock: &yes},
+		{InStock: &no},
+		{LowStock: true},
+		{Attributes: []AttributeFilter{{Code: "weight", Min: &min}}},
+		{Attributes: []AttributeFilter{{Code: "weight", Values: []string{"0.2"}}}},
+	}
+	for i, filter := range filters {
+		found, err := productDB.FindAll(0, 0, "asc", filter)
+		assert.NoError(t, err)
+		var want []string
+		for _, p := range found {
+			want = append(want, p.Name)
+		}
+		matches, err := productDB.Matcher(filter)
+		assert.NoError(t, err)
+		var got []string
+		for _, p := range []*entity.Product{tv, phone, book} {
+			// Match products as they are read back from events.
+			stored, err := productDB.FindById(p.ID.String())
+			assert.NoError(t, err)
+			data, err := json.Marshal(stored)
+			assert.NoError(t, err)
+			var decoded entity.Product
+			assert.NoError(t, json.Unmarshal(data, &decoded))
+			if matches(&decoded) {
+				got = append(got, decoded.Name)
+			}
+		}
+		assert.ElementsMatch(t, want, got, "filter %d", i)
+	}
+}
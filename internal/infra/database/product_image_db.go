@@ -0,0 +1,120 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ProductImage struct {
+	DB *gorm.DB
+	// Source is who the changes are recorded in the audit log as made by.
+	Source entity.AuditSource
+}
+
+func NewProductImage(db *gorm.DB) *ProductImage {
+	return &ProductImage{DB: db}
+}
+
+// As returns a copy of the repository whose changes are audited as made by
+// source.
+func (p *ProductImage) As(source entity.AuditSource) ProductImageInterface {
+	return &ProductImage{DB: p.DB, Source: source}
+}
+
+// Create adds the image after the last image of its product, publishing
+// the change to the product as a product.updated event.
+func (p *ProductImage) Create(image *entity.ProductImage) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.ProductImage{}).Where("product_id = ?", image.ProductID).Count(&count).Error; err != nil {
+			return err
+		}
+		image.Position = int(count)
+		if err := tx.Create(image).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, p.Source, entity.AuditCreate, entity.AuditProductImage, image.ID.String(), nil, image); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, image.ProductID.String())
+	})
+}
+
+func (p *ProductImage) FindById(productID, id string) (*entity.ProductImage, error) {
+	var image entity.ProductImage
+	if err := p.DB.First(&image, "product_id = ? AND id = ?", productID, id).Error; err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (p *ProductImage) FindByProductId(productID string) ([]entity.ProductImage, error) {
+	images := []entity.ProductImage{}
+	err := p.DB.Where("product_id = ?", productID).Order("position asc").Find(&images).Error
+	return images, err
+}
+
+// Reorder moves the images of the product to the order of ids, auditing
+// every image that moved, and returns them sorted. The product.updated
+// event is only recorded when any image moved.
+func (p *ProductImage) Reorder(productID string, ids []string) ([]entity.ProductImage, error) {
+	var images []entity.ProductImage
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Find(&images).Error; err != nil {
+			return err
+		}
+		before := make(map[string]entity.ProductImage, len(images))
+		for _, image := range images {
+			before[image.ID.String()] = image
+		}
+		if err := entity.ReorderImages(images, ids); err != nil {
+			return err
+		}
+		moved := false
+		for _, image := range images {
+			previous := before[image.ID.String()]
+			if previous.Position == image.Position {
+				continue
+			}
+			moved = true
+			if err := tx.Model(&image).Update("position", image.Position).Error; err != nil {
+				return err
+			}
+			if err := recordAudit(tx, p.Source, entity.AuditUpdate, entity.AuditProductImage, image.ID.String(), &previous, &image); err != nil {
+				return err
+			}
+		}
+		if !moved {
+			return nil
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// Delete removes the image and moves the images after it one position up,
+// along with a product.updated event.
+func (p *ProductImage) Delete(productID, id string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var image entity.ProductImage
+		if err := tx.First(&image, "product_id = ? AND id = ?", productID, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&entity.ProductImage{}).
+			Where("product_id = ? AND position > ?", productID, image.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(tx, p.Source, entity.AuditDelete, entity.AuditProductImage, id, &image, nil); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, productID)
+	})
+}
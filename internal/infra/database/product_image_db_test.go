@@ -0,0 +1,91 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createImageProduct(t *testing.T) (*ProductImage, *Product, *entity.Product) {
+	productDB := NewProduct(createTestDB(t))
+	product, _ := entity.NewProduct("Camera", brl(99900))
+	assert.NoError(t, productDB.Create(product))
+	return NewProductImage(productDB.DB), productDB, product
+}
+
+func createImages(t *testing.T, imageDB *ProductImage, product *entity.Product, count int) []*entity.ProductImage {
+	var images []*entity.ProductImage
+	for i := 0; i < count; i++ {
+		image, err := entity.NewProductImage(product.ID, "image/jpeg", 1024, 800, 600, "user-1")
+		assert.NoError(t, err)
+		assert.NoError(t, imageDB.Create(image))
+		images = append(images, image)
+	}
+	return images
+}
+
+func TestProductImageCreateAndFind(t *testing.T) {
+	imageDB, productDB, product := createImageProduct(t)
+	images := createImages(t, imageDB, product, 2)
+	assert.Equal(t, 0, images[0].Position)
+	assert.Equal(t, 1, images[1].Position)
+
+	found, err := imageDB.FindById(product.ID.String(), images[1].ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, images[1].Key, found.Key)
+	assert.Equal(t, images[1].ThumbnailKeys, found.ThumbnailKeys)
+	_, err = imageDB.FindById(pkgEntity.NewID().String(), images[1].ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	p, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, p.Images, 2)
+	assert.Equal(t, images[0].ID, p.Images[0].ID)
+}
+
+func TestProductImageReorder(t *testing.T) {
+	imageDB, productDB, product := createImageProduct(t)
+	images := createImages(t, imageDB, product, 3)
+
+	ordered, err := imageDB.Reorder(product.ID.String(), []string{images[2].ID.String(), images[0].ID.String(), images[1].ID.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, images[2].ID, ordered[0].ID)
+
+	p, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, images[2].ID, p.Images[0].ID)
+	assert.Equal(t, images[0].ID, p.Images[1].ID)
+	assert.Equal(t, images[1].ID, p.Images[2].ID)
+
+	_, err = imageDB.Reorder(product.ID.String(), []string{images[0].ID.String()})
+	assert.ErrorIs(t, err, entity.ErrInvalidImageOrder)
+}
+
+func TestProductImageDelete(t *testing.T) {
+	imageDB, productDB, product := createImageProduct(t)
+	images := createImages(t, imageDB, product, 3)
+
+	assert.ErrorIs(t, imageDB.Delete(pkgEntity.NewID().String(), images[0].ID.String()), gorm.ErrRecordNotFound)
+	assert.NoError(t, imageDB.Delete(product.ID.String(), images[0].ID.String()))
+	remaining, err := imageDB.FindByProductId(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 2)
+	assert.Equal(t, images[1].ID, remaining[0].ID)
+	assert.Equal(t, 0, remaining[0].Position)
+	assert.Equal(t, 1, remaining[1].Position)
+
+	// Images added later still go last.
+	last := createImages(t, imageDB, product, 1)[0]
+	assert.Equal(t, 2, last.Position)
+
+	assert.NoError(t, productDB.Delete(product.ID.String(), "user-1", 0))
+	_, err = productDB.Purge(time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	remaining, err = imageDB.FindByProductId(product.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
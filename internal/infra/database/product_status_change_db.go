@@ -0,0 +1,25 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ProductStatusChange struct {
+	DB *gorm.DB
+}
+
+func NewProductStatusChange(db *gorm.DB) *ProductStatusChange {
+	return &ProductStatusChange{DB: db}
+}
+
+// FindByProductId lists the status history of a product, newest first.
+func (p *ProductStatusChange) FindByProductId(productID string, page, limit int) ([]entity.ProductStatusChange, error) {
+	changes := []entity.ProductStatusChange{}
+	query := p.DB.Where("product_id = ?", productID).Order("changed_at desc")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&changes).Error
+	return changes, err
+}
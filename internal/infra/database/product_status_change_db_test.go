@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductStatusChange_RecordedOnProductUpdate(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	product, _ := entity.NewProduct("Mouse", brl(3999))
+	assert.NoError(t, productDB.Create(product))
+
+	product.Name = "Gaming mouse"
+	assert.NoError(t, productDB.Update(product))
+	assert.NoError(t, product.ChangeStatus(entity.ProductPublished))
+	product.UpdatedBy = "user-1"
+	assert.NoError(t, productDB.Update(product))
+	assert.NoError(t, product.ChangeStatus(entity.ProductArchived))
+	product.UpdatedBy = "user-2"
+	assert.NoError(t, productDB.Update(product))
+
+	statusChangeDB := NewProductStatusChange(productDB.DB)
+	changes, err := statusChangeDB.FindByProductId(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, entity.ProductPublished, changes[0].FromStatus)
+	assert.Equal(t, entity.ProductArchived, changes[0].ToStatus)
+	assert.Equal(t, "user-2", changes[0].ChangedBy)
+	assert.Equal(t, entity.ProductDraft, changes[1].FromStatus)
+	assert.Equal(t, "user-1", changes[1].ChangedBy)
+
+	changes, err = statusChangeDB.FindByProductId(product.ID.String(), 2, 1)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "user-1", changes[0].ChangedBy)
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ProductArchived, found.Status)
+}
+
+func TestProductFindAll_FilterByStatus(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	draft, _ := entity.NewProduct("Draft", brl(1000))
+	published, _ := entity.NewProduct("Published", brl(1000))
+	published.Status = entity.ProductPublished
+	archived, _ := entity.NewProduct("Archived", brl(1000))
+	archived.Status = entity.ProductArchived
+	for _, product := range []*entity.Product{draft, published, archived} {
+		assert.NoError(t, productDB.Create(product))
+	}
+
+	products, err := productDB.FindAll(0, 0, "asc", ProductFilter{Statuses: []string{entity.ProductPublished}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, published.ID, products[0].ID)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{Statuses: []string{entity.ProductDraft, entity.ProductArchived}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, products, 3)
+}
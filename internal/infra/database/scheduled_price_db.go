@@ -0,0 +1,141 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ScheduledPrice struct {
+	DB *gorm.DB
+}
+
+func NewScheduledPrice(db *gorm.DB) *ScheduledPrice {
+	return &ScheduledPrice{DB: db}
+}
+
+// Create saves the scheduled price unless it overlaps another pending or
+// active scheduled price of the product.
+func (s *ScheduledPrice) Create(scheduledPrice *entity.ScheduledPrice) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var scheduled []entity.ScheduledPrice
+		err := tx.Where("product_id = ? AND status IN ?", scheduledPrice.ProductID, []string{entity.SchedulePending, entity.ScheduleActive}).
+			Find(&scheduled).Error
+		if err != nil {
+			return err
+		}
+		for i := range scheduled {
+			if scheduledPrice.Overlaps(&scheduled[i]) {
+				return entity.ErrScheduleOverlaps
+			}
+		}
+		return tx.Create(scheduledPrice).Error
+	})
+}
+
+func (s *ScheduledPrice) FindById(id string) (*entity.ScheduledPrice, error) {
+	var scheduledPrice entity.ScheduledPrice
+	if err := s.DB.First(&scheduledPrice, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &scheduledPrice, nil
+}
+
+// FindByProductId lists the scheduled prices of a product by start date.
+func (s *ScheduledPrice) FindByProductId(productID string) ([]entity.ScheduledPrice, error) {
+	scheduled := []entity.ScheduledPrice{}
+	err := s.DB.Where("product_id = ?", productID).Order("effective_from asc").Find(&scheduled).Error
+	return scheduled, err
+}
+
+func (s *ScheduledPrice) Update(scheduledPrice *entity.ScheduledPrice) error {
+	return s.DB.Save(scheduledPrice).Error
+}
+
+// ApplyDue starts the pending scheduled prices that took effect and ends the
+// active ones that expired by now, returning how many it applied. Each one
+// is applied in its own transaction together with the product price and
+// the price history. The scheduled prices of products in the trash wait
+// until they are restored, or are deleted with them when they are purged.
+func (s *ScheduledPrice) ApplyDue(now time.Time) (int, error) {
+	now = now.UTC()
+	applied := 0
+	trashed := s.DB.Unscoped().Model(&entity.Product{}).Select("id").Where("deleted_at IS NOT NULL")
+	var due []entity.ScheduledPrice
+	err := s.DB.Where("status = ? AND effective_from <= ? AND product_id NOT IN (?)", entity.SchedulePending, now, trashed).
+		Order("effective_from asc").Find(&due).Error
+	if err != nil {
+		return applied, err
+	}
+	for i := range due {
+		ok, err := s.apply(&due[i], (*entity.ScheduledPrice).Start)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied++
+		}
+	}
+	var expired []entity.ScheduledPrice
+	err = s.DB.Where("status = ? AND effective_until <= ? AND product_id NOT IN (?)", entity.ScheduleActive, now, trashed).
+		Order("effective_until asc").Find(&expired).Error
+	if err != nil {
+		return applied, err
+	}
+	for i := range expired {
+		ok, err := s.apply(&expired[i], (*entity.ScheduledPrice).End)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// apply runs the step of the scheduled price, unless its product went to the
+// trash in the meantime, telling whether it did. Price changes are audited
+// and published like any other update.
+func (s *ScheduledPrice) apply(scheduledPrice *entity.ScheduledPrice, step func(*entity.ScheduledPrice, *entity.Product) *entity.PriceChange) (bool, error) {
+	applied := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var product entity.Product
+		err := tx.Unscoped().First(&product, "id = ?", scheduledPrice.ProductID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("cancelling scheduled price %s of deleted product %s", scheduledPrice.ID, scheduledPrice.ProductID)
+			scheduledPrice.Status = entity.ScheduleCancelled
+			return tx.Save(scheduledPrice).Error
+		}
+		if err != nil {
+			return err
+		}
+		if product.DeletedAt.Valid {
+			return nil
+		}
+		before := product
+		if change := step(scheduledPrice, &product); change != nil {
+			product.Version++
+			err = tx.Model(&product).Select("price_amount", "price_currency", "updated_by", "updated_at", "version").Updates(&product).Error
+			if err != nil {
+				return err
+			}
+			if err = tx.Create(change).Error; err != nil {
+				return err
+			}
+			source := entity.AuditSource{Actor: entity.PriceChangedByScheduler}
+			if err = recordAudit(tx, source, entity.AuditUpdate, entity.AuditProduct, product.ID.String(), &before, &product); err != nil {
+				return err
+			}
+			if err = recordProductEvent(tx, entity.EventProductUpdated, product.ID.String()); err != nil {
+				return err
+			}
+		}
+		applied = true
+		return tx.Save(scheduledPrice).Error
+	})
+	return applied, err
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledPriceCreate_RejectsOverlap(t *testing.T) {
+	db := createTestDB(t)
+	product, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, NewProduct(db).Create(product))
+	scheduledPriceDB := NewScheduledPrice(db)
+
+	from := time.Now().Add(time.Hour)
+	until := from.Add(48 * time.Hour)
+	sale, _ := entity.NewScheduledPrice(product, brl(800), from, &until, "user-1")
+	assert.NoError(t, scheduledPriceDB.Create(sale))
+
+	overlapping, _ := entity.NewScheduledPrice(product, brl(700), from.Add(24*time.Hour), nil, "user-1")
+	assert.ErrorIs(t, scheduledPriceDB.Create(overlapping), entity.ErrScheduleOverlaps)
+
+	assert.NoError(t, sale.Cancel())
+	assert.NoError(t, scheduledPriceDB.Update(sale))
+	assert.NoError(t, scheduledPriceDB.Create(overlapping))
+
+	scheduled, err := scheduledPriceDB.FindByProductId(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, scheduled, 2)
+}
+
+func TestScheduledPriceApplyDue(t *testing.T) {
+	db := createTestDB(t)
+	productDB := NewProduct(db)
+	product, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, productDB.Create(product))
+	scheduledPriceDB := NewScheduledPrice(db)
+
+	now := time.Now()
+	until := now.Add(time.Hour)
+	sale, _ := entity.NewScheduledPrice(product, brl(800), now.Add(time.Minute), &until, "user-1")
+	assert.NoError(t, scheduledPriceDB.Create(sale))
+
+	applied, err := scheduledPriceDB.ApplyDue(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	applied, err = scheduledPriceDB.ApplyDue(now.Add(2 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	found, _ := productDB.FindById(product.ID.String())
+	assert.Equal(t, brl(800), found.Price)
+	assert.Equal(t, entity.PriceChangedByScheduler, found.UpdatedBy)
+
+	applied, err = scheduledPriceDB.ApplyDue(now.Add(2 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	found, _ = productDB.FindById(product.ID.String())
+	assert.Equal(t, brl(1000), found.Price)
+	sale, _ = scheduledPriceDB.FindById(sale.ID.String())
+	assert.Equal(t, entity.ScheduleCompleted, sale.Status)
+
+	changes, err := NewPriceChange(db).FindByProductId(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, brl(1000), changes[0].NewPrice)
+	assert.Equal(t, sale.ID, *changes[0].ScheduledPriceID)
+}
+
+func TestScheduledPriceApplyDue_DeletedProduct(t *testing.T) {
+	db := createTestDB(t)
+	productDB := NewProduct(db)
+	product, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, productDB.Create(product))
+	scheduledPriceDB := NewScheduledPrice(db)
+	sale, _ := entity.NewScheduledPrice(product, brl(800), time.Now(), nil, "user-1")
+	assert.NoError(t, scheduledPriceDB.Create(sale))
+	assert.NoError(t, productDB.Delete(product.ID.String(), "user-1", 0))
+
+	// Scheduled prices wait while their product is in the trash.
+	applied, err := scheduledPriceDB.ApplyDue(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+	sale, _ = scheduledPriceDB.FindById(sale.ID.String())
+	assert.Equal(t, entity.SchedulePending, sale.Status)
+
+	assert.NoError(t, productDB.Restore(product.ID.String()))
+	applied, err = scheduledPriceDB.ApplyDue(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	found, _ := productDB.FindById(product.ID.String())
+	assert.Equal(t, brl(800), found.Price)
+}
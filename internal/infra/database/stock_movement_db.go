@@ -0,0 +1,78 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+// ErrStockChanged is returned when the stock of a product changed while a
+// movement was being applied.
+var ErrStockChanged = errors.New("stock changed concurrently, try again")
+
+type StockMovement struct {
+	DB *gorm.DB
+	// Source is who the changes are recorded in the audit log as made by.
+	Source entity.AuditSource
+}
+
+func NewStockMovement(db *gorm.DB) *StockMovement {
+	return &StockMovement{DB: db}
+}
+
+// As returns a copy of the repository whose changes are audited as made by
+// source.
+func (s *StockMovement) As(source entity.AuditSource) StockMovementInterface {
+	return &StockMovement{DB: s.DB, Source: source}
+}
+
+// Apply records the movement in the ledger and updates the product stock in
+// the same transaction, along with a product.updated event and the audit
+// entry of the product, returning the updated product.
+func (s *StockMovement) Apply(movement *entity.StockMovement) (*entity.Product, error) {
+	var product entity.Product
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&product, "id = ?", movement.ProductID).Error; err != nil {
+			return err
+		}
+		before := product
+		stock, reserved := product.Stock, product.Reserved
+		if err := product.ApplyMovement(movement); err != nil {
+			return err
+		}
+		// Only update when the quantities we read are still current, so two
+		// concurrent movements can't both succeed from the same stock.
+		result := tx.Model(&entity.Product{}).
+			Where("id = ? AND stock = ? AND reserved = ?", product.ID, stock, reserved).
+			Updates(map[string]interface{}{"stock": product.Stock, "reserved": product.Reserved})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStockChanged
+		}
+		if err := tx.Create(movement).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, s.Source, entity.AuditUpdate, entity.AuditProduct, product.ID.String(), &before, &product); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, product.ID.String())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// FindByProductId lists the movements of a product, newest first.
+func (s *StockMovement) FindByProductId(productID string, page, limit int) ([]entity.StockMovement, error) {
+	movements := []entity.StockMovement{}
+	query := s.DB.Where("product_id = ?", productID).Order("created_at desc")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&movements).Error
+	return movements, err
+}
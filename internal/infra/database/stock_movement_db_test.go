@@ -0,0 +1,96 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockMovementApply(t *testing.T) {
+	db := createTestDB(t)
+	productDB := NewProduct(db)
+	stockDB := NewStockMovement(db)
+	p, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, productDB.Create(p))
+
+	receipt, _ := entity.NewStockMovement(p.ID, entity.MovementReceipt, 10, "", "user-1")
+	product, err := stockDB.Apply(receipt)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, product.Stock)
+	assert.Equal(t, 10, receipt.StockAfter)
+
+	sale, _ := entity.NewStockMovement(p.ID, entity.MovementSale, 11, "", "user-1")
+	product, err = stockDB.Apply(sale)
+	assert.Equal(t, entity.ErrInsufficientStock, err)
+	assert.Nil(t, product)
+
+	sale, _ = entity.NewStockMovement(p.ID, entity.MovementSale, 3, "", "user-1")
+	_, err = stockDB.Apply(sale)
+	assert.NoError(t, err)
+
+	found, err := productDB.FindById(p.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 7, found.Stock)
+
+	movements, err := stockDB.FindByProductId(p.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, movements, 2)
+	assert.Equal(t, entity.MovementSale, movements[0].Type)
+	assert.Equal(t, 7, movements[0].StockAfter)
+	assert.Equal(t, "user-1", movements[0].CreatedBy)
+}
+
+func Test_ProductUpdate_KeepsStock(t *testing.T) {
+	db := createTestDB(t)
+	productDB := NewProduct(db)
+	p, _ := entity.NewProduct("Product 1", brl(1000))
+	assert.NoError(t, productDB.Create(p))
+	receipt, _ := entity.NewStockMovement(p.ID, entity.MovementReceipt, 10, "", "user-1")
+	_, err := NewStockMovement(db).Apply(receipt)
+	assert.NoError(t, err)
+
+	// p still holds the stock read before the receipt.
+	p.Name = "Updated product"
+	assert.NoError(t, productDB.Update(p))
+	found, err := productDB.FindById(p.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Updated product", found.Name)
+	assert.Equal(t, 10, found.Stock)
+}
+
+func Test_ProductFindAll_FilterByStock(t *testing.T) {
+	db := createTestDB(t)
+	productDB := NewProduct(db)
+	stockDB := NewStockMovement(db)
+	inStock, _ := entity.NewProduct("In stock", brl(1000))
+	inStock.LowStockThreshold = 2
+	reserved, _ := entity.NewProduct("Reserved", brl(1000))
+	empty, _ := entity.NewProduct("Empty", brl(1000))
+	for _, p := range []*entity.Product{inStock, reserved, empty} {
+		assert.NoError(t, productDB.Create(p))
+	}
+	for _, m := range []struct {
+		product  *entity.Product
+		kind     string
+		quantity int
+	}{{inStock, entity.MovementReceipt, 5}, {reserved, entity.MovementReceipt, 2}, {reserved, entity.MovementReservation, 2}} {
+		movement, _ := entity.NewStockMovement(m.product.ID, m.kind, m.quantity, "", "user-1")
+		_, err := stockDB.Apply(movement)
+		assert.NoError(t, err)
+	}
+
+	yes, no := true, false
+	products, err := productDB.FindAll(0, 0, "asc", ProductFilter{InStock: &yes})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "In stock", products[0].Name)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{InStock: &no})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{LowStock: true})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+}
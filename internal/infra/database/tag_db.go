@@ -0,0 +1,27 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Tag struct {
+	DB *gorm.DB
+}
+
+func NewTag(db *gorm.DB) *Tag {
+	return &Tag{DB: db}
+}
+
+// FindAll lists the tags in use together with how many products carry them,
+// most used first. Products in the trash aren't counted.
+func (t *Tag) FindAll() ([]entity.TagUsage, error) {
+	usages := []entity.TagUsage{}
+	err := t.DB.Table("product_tags").
+		Joins("JOIN products ON products.id = product_tags.product_id AND products.deleted_at IS NULL").
+		Select("tag_name AS name, COUNT(*) AS count").
+		Group("tag_name").
+		Order("count desc, name asc").
+		Scan(&usages).Error
+	return usages, err
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTaggedProduct(t *testing.T, productDB *Product, name string, tags ...string) *entity.Product {
+	product, err := entity.NewProduct(name, brl(1000))
+	assert.NoError(t, err)
+	product.Tags, err = entity.NormalizeTags(tags)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.Create(product))
+	return product
+}
+
+func TestTagFindAll(t *testing.T) {
+	db := createTestDB(t)
+	productDB := NewProduct(db)
+	createTaggedProduct(t, productDB, "Product 1", "new", "sale")
+	createTaggedProduct(t, productDB, "Product 2", "sale")
+	removed := createTaggedProduct(t, productDB, "Product 3", "eco")
+	assert.NoError(t, productDB.Delete(removed.ID.String(), "user-1", 0))
+
+	tags, err := NewTag(db).FindAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.TagUsage{{Name: "sale", Count: 2}, {Name: "new", Count: 1}}, tags)
+}
+
+func Test_ProductFindAll_FilterByTags(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	createTaggedProduct(t, productDB, "Product 1", "new", "sale")
+	createTaggedProduct(t, productDB, "Product 2", "sale")
+	createTaggedProduct(t, productDB, "Product 3")
+
+	products, err := productDB.FindAll(0, 0, "asc", ProductFilter{Tags: []string{"new", "sale"}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = productDB.FindAll(0, 0, "asc", ProductFilter{Tags: []string{"new", "sale"}, MatchAllTags: true})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Product 1", products[0].Name)
+	assert.Len(t, products[0].Tags, 2)
+}
+
+func Test_ProductUpdate_ReplacesTags(t *testing.T) {
+	productDB := NewProduct(createTestDB(t))
+	product := createTaggedProduct(t, productDB, "Product 1", "new", "sale")
+
+	assert.True(t, product.RemoveTag("new"))
+	product.AddTags([]entity.Tag{{Name: "eco"}})
+	assert.NoError(t, productDB.Update(product))
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []entity.Tag{{Name: "sale"}, {Name: "eco"}}, found.Tags)
+}
@@ -7,14 +7,32 @@ import (
 
 type User struct {
 	DB *gorm.DB
+	// Source is who the changes are recorded in the audit log as made by.
+	Source entity.AuditSource
 }
 
 func NewUser(db *gorm.DB) *User {
 	return &User{DB: db}
 }
 
+// As returns a copy of the repository whose changes are audited as made by
+// source.
+func (u *User) As(source entity.AuditSource) UserInterface {
+	return &User{DB: u.DB, Source: source}
+}
+
+// Create saves the user along with a user.registered event and its audit
+// entry.
 func (u *User) Create(user *entity.User) error {
-	return u.DB.Create(user).Error
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, u.Source, entity.AuditCreate, entity.AuditUser, user.ID.String(), nil, user); err != nil {
+			return err
+		}
+		return recordEvent(tx, entity.EventUserRegistered, user.ID.String(), user)
+	})
 }
 
 func (u *User) FindByEmail(email string) (*entity.User, error) {
@@ -24,3 +42,57 @@ func (u *User) FindByEmail(email string) (*entity.User, error) {
 	}
 	return &user, nil
 }
+
+func (u *User) FindById(id string) (*entity.User, error) {
+	var user entity.User
+	if err := u.DB.First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindAll returns a page of users whose name or email contains search,
+// together with the total number of matching users.
+func (u *User) FindAll(page, limit int, search string) ([]entity.User, int64, error) {
+	query := u.DB.Model(&entity.User{})
+	if search != "" {
+		like := "%" + search + "%"
+		query = query.Where("name LIKE ? OR email LIKE ?", like, like)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []entity.User
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Order("created_at asc").Find(&users).Error
+	return users, total, err
+}
+
+func (u *User) Update(user *entity.User) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := NewUser(tx).FindById(user.ID.String())
+		if err != nil {
+			return err
+		}
+		if err = tx.Save(user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, u.Source, entity.AuditUpdate, entity.AuditUser, user.ID.String(), before, user)
+	})
+}
+
+func (u *User) Delete(id string) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := NewUser(tx).FindById(id)
+		if err != nil {
+			return err
+		}
+		if err = tx.Delete(user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, u.Source, entity.AuditDelete, entity.AuditUser, id, user, nil)
+	})
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
@@ -14,7 +15,7 @@ func TestCreateUser(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	db.AutoMigrate(&entity.User{})
+	db.AutoMigrate(&entity.User{}, &entity.Event{}, &entity.AuditEntry{}, &entity.AuditLock{})
 	user, _ := entity.NewUser("Matheus", "matheus@abc.com", "123456")
 	userDb := NewUser(db)
 	err = userDb.Create(user)
@@ -34,7 +35,7 @@ func TestFindByEmail(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	db.AutoMigrate(&entity.User{})
+	db.AutoMigrate(&entity.User{}, &entity.Event{}, &entity.AuditEntry{}, &entity.AuditLock{})
 	user, _ := entity.NewUser("Matheus", "matheus@abc.com", "123456")
 	userDb := NewUser(db)
 	err = userDb.Create(user)
@@ -49,3 +50,51 @@ func TestFindByEmail(t *testing.T) {
 	assert.True(t, userFound.ValidatePassword("123456"))
 	assert.NotEqual(t, userFound.Password, "123456")
 }
+
+func TestUserFindAll(t *testing.T) {
+	db := createTestDB(t)
+	userDb := NewUser(db)
+	for i := 0; i < 5; i++ {
+		user, _ := entity.NewUser(fmt.Sprintf("User %d", i), fmt.Sprintf("user%d@abc.com", i), "123456")
+		assert.NoError(t, userDb.Create(user))
+	}
+	user, _ := entity.NewUser("Matheus", "matheus@abc.com", "123456")
+	assert.NoError(t, userDb.Create(user))
+
+	users, total, err := userDb.FindAll(1, 4, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), total)
+	assert.Len(t, users, 4)
+
+	users, total, err = userDb.FindAll(2, 4, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), total)
+	assert.Len(t, users, 2)
+
+	users, total, err = userDb.FindAll(0, 0, "matheus")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, users, 1)
+	assert.Equal(t, user.ID, users[0].ID)
+}
+
+func TestUserUpdateAndDelete(t *testing.T) {
+	db := createTestDB(t)
+	user, _ := entity.NewUser("Matheus", "matheus@abc.com", "123456")
+	userDb := NewUser(db)
+	assert.NoError(t, userDb.Create(user))
+
+	user.Disable()
+	assert.NoError(t, user.ChangeRole(entity.RoleAdmin))
+	assert.NoError(t, userDb.Update(user))
+
+	userFound, err := userDb.FindById(user.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, userFound.Disabled)
+	assert.Equal(t, entity.RoleAdmin, userFound.Role)
+
+	assert.NoError(t, userDb.Delete(user.ID.String()))
+	userFound, err = userDb.FindById(user.ID.String())
+	assert.Error(t, err)
+	assert.Nil(t, userFound)
+}
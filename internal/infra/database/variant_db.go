@@ -0,0 +1,133 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Variant struct {
+	DB *gorm.DB
+	// Source is who the changes are recorded in the audit log as made by.
+	Source entity.AuditSource
+}
+
+func NewVariant(db *gorm.DB) *Variant {
+	return &Variant{DB: db}
+}
+
+// As returns a copy of the repository whose changes are audited as made by
+// source.
+func (v *Variant) As(source entity.AuditSource) VariantInterface {
+	return &Variant{DB: v.DB, Source: source}
+}
+
+// Create saves the variant, publishing the change to its product as a
+// product.updated event.
+func (v *Variant) Create(variant *entity.Variant) error {
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkVariant(tx, variant); err != nil {
+			return err
+		}
+		if err := tx.Create(variant).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, v.Source, entity.AuditCreate, entity.AuditVariant, variant.ID.String(), nil, variant); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, variant.ProductID.String())
+	})
+}
+
+func (v *Variant) FindById(productID, id string) (*entity.Variant, error) {
+	var variant entity.Variant
+	if err := v.DB.First(&variant, "product_id = ? AND id = ?", productID, id).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (v *Variant) FindByProductId(productID string) ([]entity.Variant, error) {
+	variants := []entity.Variant{}
+	err := v.DB.Where("product_id = ?", productID).Order("created_at asc").Find(&variants).Error
+	return variants, err
+}
+
+func (v *Variant) FindBySKU(sku string) (*entity.Variant, error) {
+	var variant entity.Variant
+	if err := v.DB.First(&variant, "sku = ?", entity.NormalizeSKU(sku)).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// Update saves the variant along with a product.updated event.
+func (v *Variant) Update(variant *entity.Variant) error {
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := NewVariant(tx).FindById(variant.ProductID.String(), variant.ID.String())
+		if err != nil {
+			return err
+		}
+		if err = checkVariant(tx, variant); err != nil {
+			return err
+		}
+		if err = tx.Save(variant).Error; err != nil {
+			return err
+		}
+		if err = recordAudit(tx, v.Source, entity.AuditUpdate, entity.AuditVariant, variant.ID.String(), before, variant); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, variant.ProductID.String())
+	})
+}
+
+// Delete removes the variant along with a product.updated event.
+func (v *Variant) Delete(productID, id string) error {
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		variant, err := NewVariant(tx).FindById(productID, id)
+		if err != nil {
+			return err
+		}
+		if err = tx.Delete(variant).Error; err != nil {
+			return err
+		}
+		if err = recordAudit(tx, v.Source, entity.AuditDelete, entity.AuditVariant, id, variant, nil); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, productID)
+	})
+}
+
+// checkVariant makes sure the SKU and barcode of the variant aren't used by
+// another variant and that its product doesn't have another variant with
+// the same options.
+func checkVariant(tx *gorm.DB, variant *entity.Variant) error {
+	var other entity.Variant
+	err := tx.Where("sku = ? AND id <> ?", variant.SKU, variant.ID).First(&other).Error
+	if err == nil {
+		return entity.ErrDuplicateSKU
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if variant.Barcode != nil {
+		err = tx.Where("barcode = ? AND id <> ?", *variant.Barcode, variant.ID).First(&other).Error
+		if err == nil {
+			return entity.ErrDuplicateBarcode
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	var siblings []entity.Variant
+	if err = tx.Where("product_id = ? AND id <> ?", variant.ProductID, variant.ID).Find(&siblings).Error; err != nil {
+		return err
+	}
+	for i := range siblings {
+		if variant.SameOptions(&siblings[i]) {
+			return entity.ErrDuplicateVariantOptions
+		}
+	}
+	return nil
+}
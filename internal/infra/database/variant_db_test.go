@@ -0,0 +1,86 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createVariantProduct(t *testing.T) (*Variant, *Product, *entity.Product) {
+	productDB := NewProduct(createTestDB(t))
+	product, _ := entity.NewProduct("T-shirt", brl(4990))
+	assert.NoError(t, productDB.Create(product))
+	return NewVariant(productDB.DB), productDB, product
+}
+
+func TestVariantCreateAndFind(t *testing.T) {
+	variantDB, productDB, product := createVariantProduct(t)
+	barcode := "4006381333931"
+	price := brl(5490)
+	small, _ := entity.NewVariant(product, "TSHIRT-S", nil, map[string]string{"size": "S"}, nil)
+	large, _ := entity.NewVariant(product, "TSHIRT-L", &barcode, map[string]string{"size": "L"}, &price)
+	assert.NoError(t, variantDB.Create(small))
+	assert.NoError(t, variantDB.Create(large))
+
+	found, err := variantDB.FindById(product.ID.String(), large.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "TSHIRT-L", found.SKU)
+	assert.Equal(t, barcode, *found.Barcode)
+	assert.Equal(t, map[string]string{"size": "L"}, found.Options)
+	assert.Equal(t, &price, found.Price)
+
+	found, err = variantDB.FindBySKU("tshirt-s")
+	assert.NoError(t, err)
+	assert.Nil(t, found.Price)
+	assert.Nil(t, found.Barcode)
+
+	variants, err := variantDB.FindByProductId(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, variants, 2)
+
+	p, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, p.Variants, 2)
+	assert.Equal(t, small.ID, p.Variants[0].ID)
+}
+
+func TestVariantCreate_Duplicates(t *testing.T) {
+	variantDB, _, product := createVariantProduct(t)
+	barcode := "4006381333931"
+	v, _ := entity.NewVariant(product, "TSHIRT-S", &barcode, map[string]string{"size": "S"}, nil)
+	assert.NoError(t, variantDB.Create(v))
+
+	sameSKU, _ := entity.NewVariant(product, "tshirt-s", nil, map[string]string{"size": "M"}, nil)
+	assert.ErrorIs(t, variantDB.Create(sameSKU), entity.ErrDuplicateSKU)
+	sameBarcode, _ := entity.NewVariant(product, "TSHIRT-M", &barcode, map[string]string{"size": "M"}, nil)
+	assert.ErrorIs(t, variantDB.Create(sameBarcode), entity.ErrDuplicateBarcode)
+	sameOptions, _ := entity.NewVariant(product, "TSHIRT-M", nil, map[string]string{"size": "S"}, nil)
+	assert.ErrorIs(t, variantDB.Create(sameOptions), entity.ErrDuplicateVariantOptions)
+
+	// A variant can be saved with its own SKU, barcode and options.
+	assert.NoError(t, variantDB.Update(v))
+}
+
+func TestVariantDelete(t *testing.T) {
+	variantDB, productDB, product := createVariantProduct(t)
+	v, _ := entity.NewVariant(product, "TSHIRT-S", nil, map[string]string{"size": "S"}, nil)
+	assert.NoError(t, variantDB.Create(v))
+
+	assert.ErrorIs(t, variantDB.Delete(pkgEntity.NewID().String(), v.ID.String()), gorm.ErrRecordNotFound)
+	assert.NoError(t, variantDB.Delete(product.ID.String(), v.ID.String()))
+	_, err := variantDB.FindById(product.ID.String(), v.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	other, _ := entity.NewVariant(product, "TSHIRT-M", nil, map[string]string{"size": "M"}, nil)
+	assert.NoError(t, variantDB.Create(other))
+	assert.NoError(t, productDB.Delete(product.ID.String(), "user-1", 0))
+	_, err = productDB.Purge(time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	variants, err := variantDB.FindByProductId(product.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, variants)
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Webhook struct {
+	DB *gorm.DB
+}
+
+func NewWebhook(db *gorm.DB) *Webhook {
+	return &Webhook{DB: db}
+}
+
+func (w *Webhook) Create(webhook *entity.Webhook) error {
+	return w.DB.Create(webhook).Error
+}
+
+func (w *Webhook) FindById(id string) (*entity.Webhook, error) {
+	var webhook entity.Webhook
+	if err := w.DB.First(&webhook, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// FindAll lists the webhooks, oldest first.
+func (w *Webhook) FindAll(page, limit int) ([]entity.Webhook, error) {
+	webhooks := []entity.Webhook{}
+	query := w.DB.Order("created_at asc")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&webhooks).Error
+	return webhooks, err
+}
+
+// FindSubscribed lists the active webhooks subscribed to events of the
+// type.
+func (w *Webhook) FindSubscribed(eventType string) ([]entity.Webhook, error) {
+	var active []entity.Webhook
+	if err := w.DB.Where("active = ?", true).Find(&active).Error; err != nil {
+		return nil, err
+	}
+	webhooks := []entity.Webhook{}
+	for _, webhook := range active {
+		if webhook.Wants(eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (w *Webhook) Update(webhook *entity.Webhook) error {
+	return w.DB.Save(webhook).Error
+}
+
+// Delete deletes the webhook along with its deliveries.
+func (w *Webhook) Delete(id string) error {
+	return w.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.Webhook{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Delete(&entity.WebhookDelivery{}, "webhook_id = ?", id).Error
+	})
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestWebhook(t *testing.T) {
+	db := createTestDB(t)
+	webhookDB := NewWebhook(db)
+	created, _ := entity.NewWebhook("https://a.example.com", []string{entity.EventProductCreated}, "", "user-1")
+	all, _ := entity.NewWebhook("https://b.example.com", []string{entity.WebhookAllEvents}, "", "user-1")
+	inactive, _ := entity.NewWebhook("https://c.example.com", []string{entity.EventProductCreated}, "", "user-1")
+	inactive.Active = false
+	for _, webhook := range []*entity.Webhook{created, all, inactive} {
+		assert.NoError(t, webhookDB.Create(webhook))
+	}
+
+	found, err := webhookDB.FindById(created.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{entity.EventProductCreated}, found.EventTypes)
+	assert.Equal(t, created.Secret, found.Secret)
+
+	webhooks, err := webhookDB.FindAll(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, webhooks, 3)
+	subscribed, err := webhookDB.FindSubscribed(entity.EventProductCreated)
+	assert.NoError(t, err)
+	assert.Len(t, subscribed, 2)
+	subscribed, err = webhookDB.FindSubscribed(entity.EventProductDeleted)
+	assert.NoError(t, err)
+	assert.Len(t, subscribed, 1)
+	assert.Equal(t, all.ID, subscribed[0].ID)
+
+	delivery := entity.NewWebhookDelivery(created.ID, "event-1", entity.EventProductCreated, []byte(`{}`))
+	assert.NoError(t, NewWebhookDelivery(db).Enqueue([]*entity.WebhookDelivery{delivery}))
+	assert.NoError(t, webhookDB.Delete(created.ID.String()))
+	_, err = webhookDB.FindById(created.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = NewWebhookDelivery(db).FindById(created.ID.String(), delivery.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, webhookDB.Delete(created.ID.String()), gorm.ErrRecordNotFound)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	db := createTestDB(t)
+	deliveryDB := NewWebhookDelivery(db)
+	webhook, _ := entity.NewWebhook("https://a.example.com", []string{entity.WebhookAllEvents}, "", "user-1")
+
+	first := entity.NewWebhookDelivery(webhook.ID, "event-1", entity.EventProductCreated, []byte(`{}`))
+	second := entity.NewWebhookDelivery(webhook.ID, "event-2", entity.EventProductUpdated, []byte(`{}`))
+	assert.NoError(t, deliveryDB.Enqueue([]*entity.WebhookDelivery{first, second}))
+	// Events published again aren't queued twice.
+	again := entity.NewWebhookDelivery(webhook.ID, "event-1", entity.EventProductCreated, []byte(`{}`))
+	assert.NoError(t, deliveryDB.Enqueue([]*entity.WebhookDelivery{again}))
+
+	now := time.Now()
+	due, err := deliveryDB.FindDue(now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 2)
+
+	first.Fail(500, "oops", "", now, time.Minute)
+	assert.NoError(t, deliveryDB.Update(first))
+	second.Succeed(204, "", now)
+	assert.NoError(t, deliveryDB.Update(second))
+	due, err = deliveryDB.FindDue(now, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = deliveryDB.FindDue(now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+	assert.Equal(t, 500, due[0].ResponseStatus)
+
+	deliveries, err := deliveryDB.FindByWebhookId(webhook.ID.String(), "", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+	deliveries, err = deliveryDB.FindByWebhookId(webhook.ID.String(), entity.DeliverySucceeded, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, second.ID, deliveries[0].ID)
+
+	deleted, err := deliveryDB.DeleteFinished(now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
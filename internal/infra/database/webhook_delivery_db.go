@@ -0,0 +1,70 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookDelivery struct {
+	DB *gorm.DB
+}
+
+func NewWebhookDelivery(db *gorm.DB) *WebhookDelivery {
+	return &WebhookDelivery{DB: db}
+}
+
+// Enqueue saves the deliveries, skipping the ones of events already queued
+// for the same webhook, so an event published twice is only sent once.
+func (d *WebhookDelivery) Enqueue(deliveries []*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return d.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(deliveries).Error
+}
+
+func (d *WebhookDelivery) FindById(webhookID, id string) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	if err := d.DB.First(&delivery, "webhook_id = ? AND id = ?", webhookID, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// FindByWebhookId lists the deliveries of a webhook, newest first, only
+// the ones in status unless it is empty.
+func (d *WebhookDelivery) FindByWebhookId(webhookID, status string, page, limit int) ([]entity.WebhookDelivery, error) {
+	deliveries := []entity.WebhookDelivery{}
+	query := d.DB.Where("webhook_id = ?", webhookID).Order("created_at desc")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindDue lists up to limit pending deliveries whose next attempt is due
+// at now, the longest waiting first.
+func (d *WebhookDelivery) FindDue(now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	deliveries := []entity.WebhookDelivery{}
+	err := d.DB.Where("status = ? AND next_attempt_at <= ?", entity.DeliveryPending, now).
+		Order("next_attempt_at asc").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (d *WebhookDelivery) Update(delivery *entity.WebhookDelivery) error {
+	return d.DB.Save(delivery).Error
+}
+
+// DeleteFinished deletes the deliveries that succeeded or failed for good
+// before finishedBefore, returning how many there were.
+func (d *WebhookDelivery) DeleteFinished(finishedBefore time.Time) (int64, error) {
+	result := d.DB.Where("status <> ? AND updated_at < ?", entity.DeliveryPending, finishedBefore).
+		Delete(&entity.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+)
+
+// Buffer keeps the last events of some types, to be subscribed to through
+// Add, and passes them on to its own subscribers. Subscribers can resume
+// after the last event they saw as long as it is still kept, so clients
+// that reconnect don't miss the events in between.
+type Buffer struct {
+	mu    sync.Mutex
+	types []string
+	// events holds up to size events in a ring starting at start, and
+	// positions where each of them is in the ring.
+	events    []entity.Event
+	start     int
+	size      int
+	positions map[string]int
+	next      int
+	queues    map[int]chan entity.Event
+}
+
+// NewBuffer keeps the last size events of the types, or of every type when
+// none is given.
+func NewBuffer(size int, types ...string) *Buffer {
+	return &Buffer{
+		types:     types,
+		size:      size,
+		events:    make([]entity.Event, 0, size),
+		positions: make(map[string]int, size),
+		queues:    map[int]chan entity.Event{},
+	}
+}
+
+// Add keeps the event, dropping the oldest one when the buffer is full, and
+// queues it for every subscriber. Events already kept are ignored, since
+// they may be published more than once. Subscribers whose queue is full are
+// unsubscribed and their queue is closed, so a slow subscriber can't hold
+// the publisher up; they can subscribe again after the last event they got.
+func (b *Buffer) Add(event entity.Event) {
+	if len(b.types) > 0 && !containsType(b.types, event.Type) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := event.ID.String()
+	if _, ok := b.positions[id]; ok || b.size <= 0 {
+		return
+	}
+	if len(b.events) < b.size {
+		b.positions[id] = len(b.events)
+		b.events = append(b.events, event)
+	} else {
+		delete(b.positions, b.events[b.start].ID.String())
+		b.events[b.start] = event
+		b.positions[id] = b.start
+		b.start = (b.start + 1) % b.size
+	}
+	for key, queue := range b.queues {
+		select {
+		case queue <- event:
+		default:
+			delete(b.queues, key)
+			close(queue)
+		}
+	}
+}
+
+// Subscribe returns the events kept after the one with lastEventID, and a
+// queue of up to queueSize events getting the ones added from then on,
+// until unsubscribe is called or the queue is closed because it was full.
+// With an empty lastEventID no event is missed. Resumed is false when the
+// event isn't kept anymore, and events may have been missed.
+func (b *Buffer) Subscribe(lastEventID string, queueSize int) (missed []entity.Event, resumed bool, queue <-chan entity.Event, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	resumed = true
+	if lastEventID != "" {
+		var position int
+		position, resumed = b.positions[lastEventID]
+		if resumed {
+			// Positions count from the start of the slice, not of the ring.
+			age := (position - b.start + len(b.events)) % len(b.events)
+			for i := age + 1; i < len(b.events); i++ {
+				missed = append(missed, b.events[(b.start+i)%len(b.events)])
+			}
+		}
+	}
+	key := b.next
+	b.next++
+	events := make(chan entity.Event, queueSize)
+	b.queues[key] = events
+	return missed, resumed, events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.queues[key]; ok {
+			delete(b.queues, key)
+			close(events)
+		}
+	}
+}
+
+func containsType(types []string, eventType string) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func bufferedEvents(t *testing.T, b *Buffer, names ...string) []*entity.Event {
+	var added []*entity.Event
+	for _, name := range names {
+		event, err := entity.NewEvent(entity.EventProductUpdated, name, nil)
+		assert.NoError(t, err)
+		b.Add(*event)
+		added = append(added, event)
+	}
+	return added
+}
+
+func entityIDs(events []entity.Event) []string {
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.EntityID)
+	}
+	return ids
+}
+
+func TestBuffer_Subscribe(t *testing.T) {
+	b := NewBuffer(3, entity.EventProductUpdated)
+	added := bufferedEvents(t, b, "p1", "p2")
+
+	missed, resumed, _, _ := b.Subscribe(added[0].ID.String(), 10)
+	assert.True(t, resumed)
+	assert.Equal(t, []string{"p2"}, entityIDs(missed))
+
+	// Once full, the oldest events are dropped.
+	added = append(added, bufferedEvents(t, b, "p3", "p4", "p5")...)
+	missed, resumed, _, _ = b.Subscribe(added[2].ID.String(), 10)
+	assert.True(t, resumed)
+	assert.Equal(t, []string{"p4", "p5"}, entityIDs(missed))
+	_, resumed, _, _ = b.Subscribe(added[1].ID.String(), 10)
+	assert.False(t, resumed)
+	missed, resumed, _, _ = b.Subscribe("", 10)
+	assert.True(t, resumed)
+	assert.Empty(t, missed)
+
+	// Events of other types, or already kept, are ignored.
+	user, _ := entity.NewEvent(entity.EventUserRegistered, "u1", nil)
+	b.Add(*user)
+	b.Add(*added[4])
+	missed, _, _, _ = b.Subscribe(added[2].ID.String(), 10)
+	assert.Equal(t, []string{"p4", "p5"}, entityIDs(missed))
+}
+
+func TestBuffer_Queue(t *testing.T) {
+	b := NewBuffer(10)
+	_, _, fast, unsubscribe := b.Subscribe("", 10)
+	_, _, slow, _ := b.Subscribe("", 1)
+	bufferedEvents(t, b, "p1", "p2")
+
+	assert.Equal(t, "p1", (<-fast).EntityID)
+	assert.Equal(t, "p2", (<-fast).EntityID)
+	// The slow subscriber only had room for the first event.
+	assert.Equal(t, "p1", (<-slow).EntityID)
+	_, open := <-slow
+	assert.False(t, open)
+
+	unsubscribe()
+	_, open = <-fast
+	assert.False(t, open)
+	unsubscribe()
+	bufferedEvents(t, b, "p3")
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+)
+
+// Bus publishes events to the handlers subscribed to it in the same
+// process.
+type Bus struct {
+	mu       sync.RWMutex
+	next     int
+	handlers map[int]func(event entity.Event)
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[int]func(event entity.Event){}}
+}
+
+// Subscribe calls handler with every event published from then on, until
+// unsubscribe is called. Handlers are called in the goroutine publishing
+// the event, so they must not block.
+func (b *Bus) Subscribe(handler func(event entity.Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+func (b *Bus) Publish(ctx context.Context, event *entity.Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(*event)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var first, second []string
+	unsubscribe := bus.Subscribe(func(event entity.Event) {
+		first = append(first, event.EntityID)
+	})
+	bus.Subscribe(func(event entity.Event) {
+		second = append(second, event.EntityID)
+	})
+
+	event, _ := entity.NewEvent(entity.EventProductCreated, "product-1", nil)
+	assert.NoError(t, bus.Publish(context.Background(), event))
+	unsubscribe()
+	event, _ = entity.NewEvent(entity.EventProductUpdated, "product-2", nil)
+	assert.NoError(t, bus.Publish(context.Background(), event))
+
+	assert.Equal(t, []string{"product-1"}, first)
+	assert.Equal(t, []string{"product-1", "product-2"}, second)
+}
@@ -0,0 +1,16 @@
+// Package events publishes the domain events relayed from the outbox.
+package events
+
+import (
+	"context"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+)
+
+// Publisher delivers events to whoever consumes them. Events may be
+// published more than once, such as when the relay stops after publishing
+// an event but before recording it, so consumers should ignore the ids they
+// already saw.
+type Publisher interface {
+	Publish(ctx context.Context, event *entity.Event) error
+}
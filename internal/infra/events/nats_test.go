@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+// runNATS starts an in-process NATS server with JetStream enabled.
+func runNATS(t *testing.T) *server.Server {
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true})
+	assert.NoError(t, err)
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server didn't start")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func TestNATS_Publish(t *testing.T) {
+	s := runNATS(t)
+	n, err := NewNATS(s.ClientURL(), "catalog", time.Second)
+	assert.NoError(t, err)
+	defer n.Close()
+	_, err = n.js.AddStream(&nats.StreamConfig{Name: "CATALOG", Subjects: []string{"catalog.>"}})
+	assert.NoError(t, err)
+
+	event, _ := entity.NewEvent(entity.EventProductCreated, "product-1", map[string]string{"name": "Mouse"})
+	assert.NoError(t, n.Publish(context.Background(), event))
+	message, err := n.js.GetLastMsg("CATALOG", "catalog.product.created")
+	assert.NoError(t, err)
+	assert.Equal(t, event.ID.String(), message.Header.Get(nats.MsgIdHdr))
+	var published entity.Event
+	assert.NoError(t, json.Unmarshal(message.Data, &published))
+	assert.Equal(t, event.ID, published.ID)
+	assert.JSONEq(t, `{"name":"Mouse"}`, string(published.Payload))
+
+	// The stream keeps an event published twice once.
+	assert.NoError(t, n.Publish(context.Background(), event))
+	info, err := n.js.StreamInfo("CATALOG")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), info.State.Msgs)
+}
+
+func TestNATS_PublishWithoutStream(t *testing.T) {
+	s := runNATS(t)
+	n, err := NewNATS(s.ClientURL(), "catalog", time.Second)
+	assert.NoError(t, err)
+	defer n.Close()
+
+	// Nothing stores the event, so it isn't acknowledged.
+	event, _ := entity.NewEvent(entity.EventProductCreated, "product-1", nil)
+	assert.Error(t, n.Publish(context.Background(), event))
+}
+
+func TestNATS_PublishUnreachable(t *testing.T) {
+	s := runNATS(t)
+	url := s.ClientURL()
+	s.Shutdown()
+
+	n, err := NewNATS(url, "catalog", 100*time.Millisecond)
+	assert.NoError(t, err)
+	defer n.Close()
+	event, _ := entity.NewEvent(entity.EventProductCreated, "product-1", nil)
+	assert.Error(t, n.Publish(context.Background(), event))
+}
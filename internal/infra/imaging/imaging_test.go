@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	img, err := Decode(encodePNG(t, 40, 20))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+
+	_, err = Decode([]byte("not an image"))
+	assert.Error(t, err)
+}
+
+func TestThumbnail(t *testing.T) {
+	img, _ := Decode(encodePNG(t, 400, 100))
+	assert.Equal(t, image.Rect(0, 0, 200, 50), Thumbnail(img, 200).Bounds())
+
+	img, _ = Decode(encodePNG(t, 100, 400))
+	assert.Equal(t, image.Rect(0, 0, 50, 200), Thumbnail(img, 200).Bounds())
+
+	// Small images aren't scaled up.
+	img, _ = Decode(encodePNG(t, 40, 20))
+	assert.Equal(t, image.Rect(0, 0, 40, 20), Thumbnail(img, 200).Bounds())
+}
+
+func TestEncode(t *testing.T) {
+	img, _ := Decode(encodePNG(t, 40, 20))
+	for _, contentType := range []string{"image/jpeg", "image/png", "image/gif"} {
+		body, err := Encode(img, contentType)
+		assert.NoError(t, err)
+		decoded, err := Decode(body)
+		assert.NoError(t, err)
+		assert.Equal(t, img.Bounds(), decoded.Bounds())
+	}
+	_, err := Encode(img, "image/webp")
+	assert.Error(t, err)
+}
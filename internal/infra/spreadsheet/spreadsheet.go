@@ -0,0 +1,65 @@
+// Package spreadsheet reads the rows of CSV and Excel (XLSX) files, and
+// writes XLSX files.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+)
+
+const (
+	CSVType  = "text/csv"
+	XLSXType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+var ErrUnsupportedType = errors.New("file must be a CSV or XLSX spreadsheet")
+
+// Read reads every row of a CSV or XLSX file. The format is told by
+// contentType, or by the content of the file when it is missing or generic.
+func Read(body []byte, contentType string) ([][]string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case CSVType, "application/csv":
+		return ReadCSV(bytes.NewReader(body))
+	case XLSXType:
+		return ReadXLSX(bytes.NewReader(body))
+	case "", "application/octet-stream", "text/plain":
+		// XLSX files are ZIP archives.
+		if bytes.HasPrefix(body, []byte("PK\x03\x04")) {
+			return ReadXLSX(bytes.NewReader(body))
+		}
+		return ReadCSV(bytes.NewReader(body))
+	}
+	return nil, ErrUnsupportedType
+}
+
+// IsSupportedType tells whether Read accepts files of contentType.
+func IsSupportedType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case CSVType, "application/csv", XLSXType, "", "application/octet-stream", "text/plain":
+		return true
+	}
+	return false
+}
+
+// ReadCSV reads a comma separated file, whose rows may have any number of
+// fields.
+func ReadCSV(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// Excel saves UTF-8 files with a byte order mark.
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	return rows, nil
+}
@@ -0,0 +1,105 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildXLSX zips the parts of a minimal workbook whose first sheet is
+// sheet.
+func buildXLSX(t *testing.T, sharedStrings, sheet string) []byte {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`,
+		"_rels/.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`,
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Products" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/styles.xml":            `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"/>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheet + `</sheetData></worksheet>`,
+	}
+	if sharedStrings != "" {
+		parts["xl/sharedStrings.xml"] = `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + sharedStrings + `</sst>`
+	}
+	for name, content := range parts {
+		part, err := archive.Create(name)
+		assert.NoError(t, err)
+		part.Write([]byte(content))
+	}
+	assert.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+func TestReadCSV(t *testing.T) {
+	rows, err := Read([]byte("\ufeffname,price\nMouse, 39.99\n\"Keyboard, wireless\",99.90,extra\n"), "text/csv; charset=utf-8")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"name", "price"}, {"Mouse", "39.99"}, {"Keyboard, wireless", "99.90", "extra"}}, rows)
+}
+
+func TestReadXLSX(t *testing.T) {
+	body := buildXLSX(t,
+		`<si><t>name</t></si><si><t>price</t></si><si><r><t>Mou</t></r><r><t>se</t></r></si>`,
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>`+
+			`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>39.990000000000002</v></c></row>`+
+			`<row r="4"><c r="A4" t="inlineStr"><is><t>Cable</t></is></c><c r="C4" t="b"><v>1</v></c></row>`)
+	rows, err := Read(body, "")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"name", "price"},
+		{"Mouse", "39.99"},
+		{},
+		{"Cable", "", "TRUE"},
+	}, rows)
+
+	rows, err = Read(body, XLSXType)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+}
+
+func TestReadXLSX_Invalid(t *testing.T) {
+	_, err := Read([]byte("PK\x03\x04 not really a zip"), XLSXType)
+	assert.ErrorIs(t, err, ErrInvalidXLSX)
+
+	// A ZIP archive that isn't a workbook has no sheets.
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	part, _ := archive.Create("products.csv")
+	part.Write([]byte("name,price\n"))
+	assert.NoError(t, archive.Close())
+	_, err = Read(buf.Bytes(), XLSXType)
+	assert.ErrorIs(t, err, ErrInvalidXLSX)
+
+	_, err = Read([]byte("{}"), "application/json")
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewXLSXWriter(&buf, "Products & more")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.WriteRow([]interface{}{"name", "price", "stock", "active"}))
+	assert.NoError(t, writer.WriteRow([]interface{}{"Mouse <wireless>", 39.99, 12, true}))
+	assert.NoError(t, writer.WriteRow(nil))
+	values := make([]interface{}, 28)
+	values[27] = int64(7)
+	assert.NoError(t, writer.WriteRow(values))
+	assert.NoError(t, writer.Close())
+
+	rows, err := Read(buf.Bytes(), XLSXType)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, []string{"name", "price", "stock", "active"}, rows[0])
+	assert.Equal(t, []string{"Mouse <wireless>", "39.99", "12", "TRUE"}, rows[1])
+	assert.Empty(t, rows[2])
+	assert.Equal(t, "", rows[3][0])
+	assert.Equal(t, "7", rows[3][27])
+}
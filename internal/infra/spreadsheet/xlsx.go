@@ -0,0 +1,95 @@
+package spreadsheet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxUnzipSize limits how much of the archive is decompressed, so a small
+// compressed file can't make the server read gigabytes.
+const maxUnzipSize = 100 << 20
+
+var ErrInvalidXLSX = errors.New("file isn't a valid XLSX spreadsheet")
+
+// ReadXLSX reads the rows of the first sheet of an Excel workbook. Numbers
+// are formatted in their shortest form, e.g. 39.99 rather than the
+// 39.990000000000002 Excel may store.
+func ReadXLSX(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r, excelize.Options{UnzipSizeLimit: maxUnzipSize})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+	defer file.Close()
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("%w: workbook has no sheets", ErrInvalidXLSX)
+	}
+	rows, err := file.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+	for i := range rows {
+		if rows[i] == nil {
+			rows[i] = []string{}
+		}
+	}
+	return rows, nil
+}
+
+// XLSXWriter writes the rows of a single sheet workbook as they come.
+// Rows past the first few thousand are kept in a temporary file rather than
+// in memory, and the workbook is written out when it is closed.
+type XLSXWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	rows   int
+}
+
+// NewXLSXWriter starts a workbook with a sheet called name.
+func NewXLSXWriter(w io.Writer, name string) (*XLSXWriter, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName(file.GetSheetName(0), name); err != nil {
+		file.Close()
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(name)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &XLSXWriter{w: w, file: file, stream: stream}, nil
+}
+
+// WriteRow adds a row after the last one. Integers and floats are written
+// as numbers, booleans as booleans, nil as an empty cell and anything else
+// as text.
+func (x *XLSXWriter) WriteRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, x.rows+1)
+	if err != nil {
+		return err
+	}
+	if err = x.stream.SetRow(cell, values); err != nil {
+		return err
+	}
+	x.rows++
+	return nil
+}
+
+// Close writes the workbook out. It doesn't close the underlying writer.
+func (x *XLSXWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.w)
+	return err
+}
+
+// Flush does nothing, since the workbook is only written out once closed.
+func (x *XLSXWriter) Flush() error {
+	return nil
+}
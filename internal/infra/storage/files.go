@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps private files, such as the results of background jobs,
+// in a directory of the local filesystem. Unlike Local, its files aren't
+// served by the server and are only read back through Open.
+type FileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// Write stores what write writes under key. The file is written to a
+// temporary name first and only takes the place of key once write returns
+// without error, so readers never see a partially written file.
+func (f *FileStore) Write(key string, write func(w io.Writer) error) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	path := filepath.Join(f.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".write-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err = write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Open opens the file stored under key for reading. It returns an error
+// wrapping fs.ErrNotExist when there is none.
+func (f *FileStore) Open(key string) (*os.File, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	return os.Open(filepath.Join(f.Dir, filepath.FromSlash(key)))
+}
+
+// Delete removes the file. Deleting a file that doesn't exist isn't an error.
+func (f *FileStore) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(filepath.Join(f.Dir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	files := NewFileStore(dir)
+
+	err := files.Write("jobs/1/result.csv", func(w io.Writer) error {
+		_, err := io.WriteString(w, "id,name\n")
+		return err
+	})
+	assert.NoError(t, err)
+	file, err := files.Open("jobs/1/result.csv")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "id,name\n", string(body))
+
+	assert.NoError(t, files.Delete("jobs/1/result.csv"))
+	_, err = files.Open("jobs/1/result.csv")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, files.Delete("jobs/1/result.csv"))
+}
+
+func TestFileStore_WriteFails(t *testing.T) {
+	dir := t.TempDir()
+	files := NewFileStore(dir)
+	failure := errors.New("disk full")
+
+	err := files.Write("jobs/1/result.csv", func(w io.Writer) error {
+		io.WriteString(w, "id,name\n")
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	entries, _ := os.ReadDir(filepath.Join(dir, "jobs", "1"))
+	assert.Empty(t, entries)
+
+	assert.ErrorIs(t, files.Write("../result.csv", func(w io.Writer) error { return nil }), ErrInvalidKey)
+	_, err = files.Open("/etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
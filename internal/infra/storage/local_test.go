@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir, "http://localhost:8000/uploads/")
+	ctx := context.Background()
+
+	assert.NoError(t, local.Put(ctx, "products/1/image.png", []byte("png"), "image/png"))
+	body, err := os.ReadFile(filepath.Join(dir, "products", "1", "image.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(body))
+	assert.Equal(t, "http://localhost:8000/uploads/products/1/image.png", local.URL("products/1/image.png"))
+
+	assert.NoError(t, local.Delete(ctx, "products/1/image.png"))
+	_, err = os.Stat(filepath.Join(dir, "products", "1", "image.png"))
+	assert.True(t, os.IsNotExist(err))
+	assert.NoError(t, local.Delete(ctx, "products/1/image.png"))
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	local := NewLocal(t.TempDir(), "/uploads")
+	ctx := context.Background()
+	for _, key := range []string{"", "/etc/passwd", "../secret", "products/../../secret", "products//image.png", `products\image.png`} {
+		assert.ErrorIs(t, local.Put(ctx, key, []byte("x"), "image/png"), ErrInvalidKey, key)
+		assert.ErrorIs(t, local.Delete(ctx, key), ErrInvalidKey, key)
+	}
+}
+
+func TestDeleteAll(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir, "/uploads")
+	ctx := context.Background()
+	assert.NoError(t, local.Put(ctx, "a.png", []byte("a"), "image/png"))
+	assert.NoError(t, local.Put(ctx, "b.png", []byte("b"), "image/png"))
+
+	assert.ErrorIs(t, DeleteAll(ctx, local, []string{"a.png", "../c.png", "b.png"}), ErrInvalidKey)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
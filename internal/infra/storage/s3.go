@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 stores files in a bucket of an S3 compatible object storage, such as
+// AWS S3 or MinIO, addressing it path-style: <Endpoint>/<Bucket>/<key>.
+type S3 struct {
+	Endpoint string
+	Bucket   string
+	// PublicURL is where the objects of the bucket are served from. It
+	// defaults to the bucket URL.
+	PublicURL string
+	client    *minio.Client
+}
+
+// NewS3 connects to the storage at endpoint, such as https://s3.amazonaws.com
+// or http://minio:9000, whose scheme tells whether TLS is used.
+func NewS3(endpoint, region, bucket, accessKey, secretKey, publicURL string) (*S3, error) {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure:       u.Scheme == "https",
+		Region:       region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if publicURL == "" {
+		publicURL = endpoint + "/" + bucket
+	}
+	return &S3{
+		Endpoint:  endpoint,
+		Bucket:    bucket,
+		PublicURL: strings.TrimSuffix(publicURL, "/"),
+		client:    client,
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	_, err := s.client.PutObject(ctx, s.Bucket, key, bytes.NewReader(body), int64(len(body)), minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// Delete removes the object. S3 doesn't fail when it doesn't exist.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	return s.client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) URL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s.PublicURL + "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a local stand-in for an S3 compatible object storage that keeps
+// objects in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readBody(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = body
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// readBody reads the body of the request, decoding the chunks it is
+// streamed in over plain HTTP.
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var body []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _, _ := strings.Cut(line, ";")
+		length, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, length+2)
+		if _, err = io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		if length == 0 {
+			return body, nil
+		}
+		body = append(body, chunk[:length]...)
+	}
+}
+
+func newFakeS3(t *testing.T, accessKey string) (*S3, *fakeS3) {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	s3, err := NewS3(server.URL, "us-east-1", "images", accessKey, "secret", "")
+	assert.NoError(t, err)
+	return s3, fake
+}
+
+func TestS3(t *testing.T) {
+	s3, fake := newFakeS3(t, "access")
+	ctx := context.Background()
+
+	assert.NoError(t, s3.Put(ctx, "products/1/my image.png", []byte("png"), "image/png"))
+	assert.Equal(t, []byte("png"), fake.objects["/images/products/1/my image.png"])
+	assert.Equal(t, "image/png", fake.types["/images/products/1/my image.png"])
+	assert.Equal(t, s3.Endpoint+"/images/products/1/my%20image.png", s3.URL("products/1/my image.png"))
+
+	assert.NoError(t, s3.Delete(ctx, "products/1/my image.png"))
+	assert.Empty(t, fake.objects)
+
+	assert.ErrorIs(t, s3.Put(ctx, "../image.png", []byte("png"), "image/png"), ErrInvalidKey)
+}
+
+func TestS3_Error(t *testing.T) {
+	s3, _ := newFakeS3(t, "other")
+	err := s3.Put(context.Background(), "products/1/image.png", []byte("png"), "image/png")
+	assert.Equal(t, http.StatusForbidden, minio.ToErrorResponse(err).StatusCode)
+}
+
+func TestS3_PublicURL(t *testing.T) {
+	s3, err := NewS3("http://minio:9000/", "us-east-1", "images", "access", "secret", "https://cdn.example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/products/1/image.png", s3.URL("products/1/image.png"))
+}
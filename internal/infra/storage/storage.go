@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps uploaded files under slash separated keys such as
+// "products/<id>/<file>" and tells the URL each file is served from.
+type Storage interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// DeleteAll deletes every key, carrying on after a failure and returning
+// the first error.
+func DeleteAll(ctx context.Context, s Storage, keys []string) error {
+	var first error
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// SetImageURLs fills in where the images and their thumbnails are served
+// from.
+func SetImageURLs(s Storage, images []entity.ProductImage) {
+	for i := range images {
+		SetImageURL(s, &images[i])
+	}
+}
+
+func SetImageURL(s Storage, image *entity.ProductImage) {
+	image.URL = s.URL(image.Key)
+	image.Thumbnails = make(map[string]string, len(image.ThumbnailKeys))
+	for name, key := range image.ThumbnailKeys {
+		image.Thumbnails[name] = s.URL(key)
+	}
+}
+
+// validKey rejects keys that are empty, absolute or try to leave the
+// storage root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/chi/v5"
+)
+
+type AttributeHandler struct {
+	AttributeDefinitionDB database.AttributeDefinitionInterface
+}
+
+func NewAttributeHandler(db database.AttributeDefinitionInterface) *AttributeHandler {
+	return &AttributeHandler{
+		AttributeDefinitionDB: db,
+	}
+}
+
+// Create attribute godoc
+// @Summary     Create attribute
+// @Description Define a custom product attribute. Options only apply to enums, min and max to numbers and max_length and pattern to strings.
+// @Tags        attributes
+// @Accept      json
+// @Produce     json
+// @Param       request       body        dto.CreateAttributeInput   true    "Attribute definition"
+// @Success     201           {object}    entity.AttributeDefinition
+// @Failure     400           {object}    Error
+// @Failure     409           {object}    Error
+// @Router      /attributes   [post]
+// @Security    ApiKeyAuth
+func (handler *AttributeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var createAttributeInput dto.CreateAttributeInput
+	if err := json.NewDecoder(r.Body).Decode(&createAttributeInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	definition, err := entity.NewAttributeDefinition(
+		createAttributeInput.Code,
+		createAttributeInput.Name,
+		createAttributeInput.Type,
+		createAttributeInput.Unit,
+		attributeRules(createAttributeInput),
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if _, err = handler.AttributeDefinitionDB.FindByCode(definition.Code); err == nil {
+		w.WriteHeader(http.StatusConflict)
+		errorObj := Error{Message: "an attribute with this code already exists"}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.AttributeDefinitionDB.Create(definition); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(definition)
+}
+
+// List attributes godoc
+// @Summary     List attributes
+// @Description List the custom product attribute definitions
+// @Tags        attributes
+// @Accept      json
+// @Produce     json
+// @Success     200           {array}     entity.AttributeDefinition
+// @Failure     500           {object}    Error
+// @Router      /attributes   [get]
+// @Security    ApiKeyAuth
+func (handler *AttributeHandler) GetAttributes(w http.ResponseWriter, r *http.Request) {
+	definitions, err := handler.AttributeDefinitionDB.FindAll()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(definitions)
+}
+
+// Get attribute godoc
+// @Summary     Get attribute
+// @Description Get a custom product attribute definition
+// @Tags        attributes
+// @Accept      json
+// @Produce     json
+// @Param       code          path        string   true    "Attribute code"
+// @Success     200           {object}    entity.AttributeDefinition
+// @Failure     404           {object}    Error
+// @Router      /attributes/{code}    [get]
+// @Security    ApiKeyAuth
+func (handler *AttributeHandler) GetAttribute(w http.ResponseWriter, r *http.Request) {
+	definition, err := handler.AttributeDefinitionDB.FindByCode(chi.URLParam(r, "code"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(definition)
+}
+
+// Update attribute godoc
+// @Summary     Update attribute
+// @Description Change the name, unit and rules of an attribute. Its type can't change and stored values aren't checked again.
+// @Tags        attributes
+// @Accept      json
+// @Produce     json
+// @Param       code          path        string                     true    "Attribute code"
+// @Param       request       body        dto.UpdateAttributeInput   true    "Attribute definition"
+// @Success     200           {object}    entity.AttributeDefinition
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /attributes/{code}    [put]
+// @Security    ApiKeyAuth
+func (handler *AttributeHandler) UpdateAttribute(w http.ResponseWriter, r *http.Request) {
+	var updateAttributeInput dto.UpdateAttributeInput
+	if err := json.NewDecoder(r.Body).Decode(&updateAttributeInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	definition, err := handler.AttributeDefinitionDB.FindByCode(chi.URLParam(r, "code"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	err = definition.Change(
+		updateAttributeInput.Name,
+		updateAttributeInput.Type,
+		updateAttributeInput.Unit,
+		attributeRules(updateAttributeInput),
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.AttributeDefinitionDB.Update(definition); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(definition)
+}
+
+// Delete attribute godoc
+// @Summary     Delete attribute
+// @Description Delete an attribute definition and the values products have for it
+// @Tags        attributes
+// @Accept      json
+// @Produce     json
+// @Param       code          path        string   true    "Attribute code"
+// @Success     200
+// @Failure     404           {object}    Error
+// @Router      /attributes/{code}    [delete]
+// @Security    ApiKeyAuth
+func (handler *AttributeHandler) DeleteAttribute(w http.ResponseWriter, r *http.Request) {
+	if err := handler.AttributeDefinitionDB.Delete(chi.URLParam(r, "code")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func attributeRules(input dto.CreateAttributeInput) entity.AttributeRules {
+	return entity.AttributeRules{
+		Options:   input.Options,
+		Min:       input.Min,
+		Max:       input.Max,
+		MaxLength: input.MaxLength,
+		Pattern:   input.Pattern,
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+)
+
+var errInvalidAuditTime = errors.New("from and to must be RFC 3339 timestamps")
+
+type AuditHandler struct {
+	AuditDB database.AuditInterface
+}
+
+func NewAuditHandler(db database.AuditInterface) *AuditHandler {
+	return &AuditHandler{AuditDB: db}
+}
+
+// List audit log godoc
+// @Summary     List audit log
+// @Description List the changes made to products and users, newest first, with who made them, from where and the fields they changed.
+// @Tags        admin
+// @Produce     json
+// @Param       entity_type   query       string   false    "product, variant, product_image or user"
+// @Param       entity_id     query       string   false    "Only changes to this entity"
+// @Param       actor         query       string   false    "Only changes made by this user or client"
+// @Param       action        query       string   false    "create, update, delete, restore or purge"
+// @Param       request_id    query       string   false    "Only changes made by this request"
+// @Param       from          query       string   false    "Only changes made at or after this time, RFC 3339"
+// @Param       to            query       string   false    "Only changes made before this time, RFC 3339"
+// @Param       page          query       string   false    "Page number"
+// @Param       limit         query       string   false    "Entries per page"
+// @Success     200           {array}     entity.AuditEntry
+// @Failure     400           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /audit    [get]
+// @Security    ApiKeyAuth
+func (handler *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	filter := database.AuditFilter{
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+		Actor:      query.Get("actor"),
+		Action:     query.Get("action"),
+		RequestID:  query.Get("request_id"),
+	}
+	if filter.From, err = auditTime(query.Get("from")); err == nil {
+		filter.To, err = auditTime(query.Get("to"))
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	entries, err := handler.AuditDB.FindAll(filter, page, limit)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// Verify audit log godoc
+// @Summary     Verify audit log
+// @Description Check the hash chain of the audit log, telling the first entry that was changed or doesn't follow the one before it, as when
+// @Description entries are edited or removed.
+// @Tags        admin
+// @Produce     json
+// @Success     200           {object}    dto.AuditVerifyOutput
+// @Failure     500           {object}    Error
+// @Router      /audit/verify    [get]
+// @Security    ApiKeyAuth
+func (handler *AuditHandler) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	checked, broken, err := handler.AuditDB.Verify()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	output := dto.AuditVerifyOutput{Valid: broken == nil, Checked: checked}
+	if broken != nil {
+		output.BrokenAt = broken.Sequence
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// auditTime parses a time filter of the audit log, which is nil when empty.
+func auditTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errInvalidAuditTime
+	}
+	return &t, nil
+}
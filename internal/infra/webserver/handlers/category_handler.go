@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+type CategoryHandler struct {
+	CategoryDB database.CategoryInterface
+}
+
+func NewCategoryHandler(db database.CategoryInterface) *CategoryHandler {
+	return &CategoryHandler{
+		CategoryDB: db,
+	}
+}
+
+// Create category godoc
+// @Summary     Create category
+// @Description Create a category, optionally below a parent category
+// @Tags        categories
+// @Accept      json
+// @Produce     json
+// @Param       request       body        dto.CreateCategoryInput   true    "Category request payload"
+// @Success     201           {object}    entity.Category
+// @Failure     400           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /category     [post]
+// @Security    ApiKeyAuth
+func (handler *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var createCategoryInput dto.CreateCategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&createCategoryInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	parentID, err := parseParentID(createCategoryInput.ParentID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	category, err := entity.NewCategory(createCategoryInput.Name, parentID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.CategoryDB.Create(category); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// List categories godoc
+// @Summary     List categories
+// @Description List every category as a flat list ordered by name
+// @Tags        categories
+// @Accept      json
+// @Produce     json
+// @Success     200           {array}     entity.Category
+// @Failure     500           {object}    Error
+// @Router      /category     [get]
+// @Security    ApiKeyAuth
+func (handler *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := handler.CategoryDB.FindAll()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(categories)
+}
+
+// Category tree godoc
+// @Summary     Category tree
+// @Description List the root categories with their subcategories nested in children
+// @Tags        categories
+// @Accept      json
+// @Produce     json
+// @Success     200           {array}     entity.Category
+// @Failure     500           {object}    Error
+// @Router      /category/tree    [get]
+// @Security    ApiKeyAuth
+func (handler *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	categories, err := handler.CategoryDB.FindAll()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entity.BuildCategoryTree(categories))
+}
+
+// Get category godoc
+// @Summary     Get category
+// @Description Get a category by id with its subcategories nested in children
+// @Tags        categories
+// @Accept      json
+// @Produce     json
+// @Param       category_id   path        string   true    "Category Id"
+// @Success     200           {object}    entity.Category
+// @Failure     404           {object}    Error
+// @Router      /category/{category_id}   [get]
+// @Security    ApiKeyAuth
+func (handler *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	subtree, err := handler.CategoryDB.FindSubtree(id)
+	if err == nil && len(subtree) == 0 {
+		err = errors.New("category not found")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entity.BuildCategoryTree(subtree)[0])
+}
+
+// Update category godoc
+// @Summary     Update category
+// @Description Rename a category or move it below another parent
+// @Tags        categories
+// @Accept      json
+// @Produce     json
+// @Param       category_id   path        string                    true    "Category Id"
+// @Param       request       body        dto.UpdateCategoryInput   true    "Category payload"
+// @Success     200           {object}    entity.Category
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Router      /category/{category_id}   [put]
+// @Security    ApiKeyAuth
+func (handler *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var updateCategoryInput dto.UpdateCategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&updateCategoryInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	category, err := handler.CategoryDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if category.ParentID, err = parseParentID(updateCategoryInput.ParentID); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	category.Name = updateCategoryInput.Name
+	if err = category.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.CategoryDB.Update(category); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(category)
+}
+
+// Delete category godoc
+// @Summary     Delete category
+// @Description Delete a category without subcategories. Products are only detached from it.
+// @Tags        categories
+// @Accept      json
+// @Produce     json
+// @Param       category_id   path        string   true    "Category Id"
+// @Success     200
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /category/{category_id}   [delete]
+// @Security    ApiKeyAuth
+func (handler *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := handler.CategoryDB.FindById(id); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err := handler.CategoryDB.Delete(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, entity.ErrCategoryHasChildren) {
+			status = http.StatusConflict
+		}
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseParentID(parentID *string) (*pkgEntity.ID, error) {
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+	id, err := pkgEntity.ParseID(*parentID)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
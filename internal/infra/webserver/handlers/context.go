@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth"
+)
+
+// actor identifies who is performing the request: the id of the user or of
+// the OAuth client the access token was issued to.
+func actor(r *http.Request) string {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return ""
+	}
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+// auditSource tells who performs the request and where it comes from, for
+// the audit log.
+func auditSource(r *http.Request) entity.AuditSource {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return entity.AuditSource{
+		Actor:     actor(r),
+		RequestID: middleware.GetReqID(r.Context()),
+		IP:        ip,
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+)
+
+var (
+	errPreconditionRequired = errors.New("If-Match header with the ETag of the product is required")
+	errPreconditionFailed   = errors.New("product was changed since it was read, reload it and try again")
+)
+
+// productETag is the entity tag of the current version of a product.
+func productETag(product *entity.Product) string {
+	return `"` + strconv.Itoa(product.Version) + `"`
+}
+
+// setProductETag sends the entity tag of the product with the response.
+func setProductETag(w http.ResponseWriter, product *entity.Product) {
+	w.Header().Set("ETag", productETag(product))
+}
+
+// checkIfMatch makes sure the If-Match header of a request that changes the
+// product names its current version, or is "*". The header may only be
+// left out when required is false.
+func checkIfMatch(r *http.Request, product *entity.Product, required bool) error {
+	header := r.Header.Get("If-Match")
+	if strings.TrimSpace(header) == "" {
+		if required {
+			return errPreconditionRequired
+		}
+		return nil
+	}
+	current := productETag(product)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// Weak tags never match: If-Match uses the strong comparison.
+		if tag == "*" || tag == current {
+			return nil
+		}
+	}
+	return errPreconditionFailed
+}
+
+func preconditionStatus(err error) int {
+	if errors.Is(err, errPreconditionRequired) {
+		return http.StatusPreconditionRequired
+	}
+	return http.StatusPreconditionFailed
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type ExchangeRateHandler struct {
+	ExchangeRateDB database.ExchangeRateInterface
+}
+
+func NewExchangeRateHandler(db database.ExchangeRateInterface) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		ExchangeRateDB: db,
+	}
+}
+
+// List exchange rates godoc
+// @Summary     List exchange rates
+// @Description List the exchange rates used to convert product prices
+// @Tags        exchange-rates
+// @Accept      json
+// @Produce     json
+// @Success     200           {array}     entity.ExchangeRate
+// @Failure     500           {object}    Error
+// @Router      /admin/exchange-rates     [get]
+// @Security    ApiKeyAuth
+func (handler *ExchangeRateHandler) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := handler.ExchangeRateDB.FindAll()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rates)
+}
+
+// Set exchange rate godoc
+// @Summary     Set exchange rate
+// @Description Create or replace the rate converting one unit of base into quote
+// @Tags        exchange-rates
+// @Accept      json
+// @Produce     json
+// @Param       base          path        string                     true    "Base currency"
+// @Param       quote         path        string                     true    "Quote currency"
+// @Param       request       body        dto.SetExchangeRateInput   true    "Exchange rate"
+// @Success     200           {object}    entity.ExchangeRate
+// @Failure     400           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/exchange-rates/{base}/{quote}    [put]
+// @Security    ApiKeyAuth
+func (handler *ExchangeRateHandler) SetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var setExchangeRateInput dto.SetExchangeRateInput
+	if err := json.NewDecoder(r.Body).Decode(&setExchangeRateInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	rate, err := entity.NewExchangeRate(chi.URLParam(r, "base"), chi.URLParam(r, "quote"), setExchangeRateInput.Rate)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.ExchangeRateDB.Save(rate); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rate)
+}
+
+// Delete exchange rate godoc
+// @Summary     Delete exchange rate
+// @Description Delete the rate of a currency pair
+// @Tags        exchange-rates
+// @Accept      json
+// @Produce     json
+// @Param       base          path        string   true    "Base currency"
+// @Param       quote         path        string   true    "Quote currency"
+// @Success     204
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/exchange-rates/{base}/{quote}    [delete]
+// @Security    ApiKeyAuth
+func (handler *ExchangeRateHandler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	base := strings.ToUpper(chi.URLParam(r, "base"))
+	quote := strings.ToUpper(chi.URLParam(r, "quote"))
+	if err := handler.ExchangeRateDB.Delete(base, quote); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Import exchange rates godoc
+// @Summary     Import exchange rates
+// @Description Create or replace exchange rates from a CSV file with base,quote,rate columns and an optional header row. Nothing is imported when any row is invalid.
+// @Tags        exchange-rates
+// @Accept      text/csv
+// @Produce     json
+// @Param       request       body        string   true    "CSV file"
+// @Success     200           {object}    dto.ImportExchangeRatesOutput
+// @Failure     400           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/exchange-rates/import    [post]
+// @Security    ApiKeyAuth
+func (handler *ExchangeRateHandler) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := parseExchangeRatesCSV(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.ExchangeRateDB.SaveAll(rates); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ImportExchangeRatesOutput{Imported: len(rates)})
+}
+
+// parseExchangeRatesCSV reads base,quote,rate rows. A repeated pair keeps
+// the last rate given for it.
+func parseExchangeRatesCSV(body io.Reader) ([]entity.ExchangeRate, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	var rates []entity.ExchangeRate
+	index := map[string]int{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "base") {
+			continue
+		}
+		rate, err := entity.NewExchangeRate(record[0], record[1], record[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		pair := rate.Base + "/" + rate.Quote
+		if i, ok := index[pair]; ok {
+			rates[i] = *rate
+			continue
+		}
+		index[pair] = len(rates)
+		rates = append(rates, *rate)
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("no exchange rates to import")
+	}
+	return rates, nil
+}
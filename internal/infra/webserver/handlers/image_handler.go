@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	goimage "image"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/imaging"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+var errInvalidImageForm = errors.New("request must be a multipart form with an image file")
+
+type ImageHandler struct {
+	ProductDB      database.ProductInterface
+	ProductImageDB database.ProductImageInterface
+	Storage        storage.Storage
+	// MaxSize is the largest image file accepted, in bytes.
+	MaxSize int64
+}
+
+func NewImageHandler(productDB database.ProductInterface, productImageDB database.ProductImageInterface, store storage.Storage, maxSize int64) *ImageHandler {
+	return &ImageHandler{
+		ProductDB:      productDB,
+		ProductImageDB: productImageDB,
+		Storage:        store,
+		MaxSize:        maxSize,
+	}
+}
+
+// Upload product image godoc
+// @Summary     Upload product image
+// @Description Upload a JPEG, PNG, GIF or WebP image of a product, which is added after its other images. Thumbnails are generated in every size.
+// @Tags        images
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Param       image         formData    file     true    "Image file"
+// @Success     201           {object}    entity.ProductImage
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     413           {object}    Error
+// @Failure     415           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/images    [post]
+// @Security    ApiKeyAuth
+func (handler *ImageHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	body, contentType, err := handler.readImage(w, r)
+	if err != nil {
+		w.WriteHeader(imageErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	img, err := imaging.Decode(body)
+	if err != nil {
+		if !errors.Is(err, imaging.ErrTooManyPixels) {
+			err = entity.ErrInvalidImage
+		}
+		w.WriteHeader(imageErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	bounds := img.Bounds()
+	image, err := entity.NewProductImage(product.ID, contentType, int64(len(body)), bounds.Dx(), bounds.Dy(), actor(r))
+	if err != nil {
+		w.WriteHeader(imageErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.putFiles(r, image, body, img); err != nil {
+		handler.deleteFiles(r, image)
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.ProductImageDB.As(auditSource(r)).Create(image); err != nil {
+		handler.deleteFiles(r, image)
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURL(handler.Storage, image)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(image)
+}
+
+// List product images godoc
+// @Summary     List product images
+// @Description List the images of a product in display order
+// @Tags        images
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Success     200           {array}     entity.ProductImage
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/images    [get]
+// @Security    ApiKeyAuth
+func (handler *ImageHandler) GetImages(w http.ResponseWriter, r *http.Request) {
+	if _, err := findVisibleProduct(r, handler.ProductDB, chi.URLParam(r, "id")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	images, err := handler.ProductImageDB.FindByProductId(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, images)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(images)
+}
+
+// Reorder product images godoc
+// @Summary     Reorder product images
+// @Description Set the display order of the images of a product, listing every image id once
+// @Tags        images
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string                       true    "Product Id"
+// @Param       request       body        dto.ReorderImagesInput       true    "Image ids in display order"
+// @Success     200           {array}     entity.ProductImage
+// @Failure     400           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/images/order    [put]
+// @Security    ApiKeyAuth
+func (handler *ImageHandler) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	var reorderImagesInput dto.ReorderImagesInput
+	if err := json.NewDecoder(r.Body).Decode(&reorderImagesInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	images, err := handler.ProductImageDB.As(auditSource(r)).Reorder(chi.URLParam(r, "id"), reorderImagesInput.ImageIDs)
+	if err != nil {
+		w.WriteHeader(imageErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, images)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(images)
+}
+
+// Delete product image godoc
+// @Summary     Delete product image
+// @Description Delete an image of a product along with its thumbnails
+// @Tags        images
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Param       image_id      path        string   true    "Image Id"
+// @Success     200
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/images/{image_id}    [delete]
+// @Security    ApiKeyAuth
+func (handler *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	image, err := handler.ProductImageDB.FindById(chi.URLParam(r, "id"), chi.URLParam(r, "imageId"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.ProductImageDB.As(auditSource(r)).Delete(image.ProductID.String(), image.ID.String()); err != nil {
+		w.WriteHeader(imageErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.deleteFiles(r, image)
+	w.WriteHeader(http.StatusOK)
+}
+
+// readImage reads the "image" file of the multipart form, telling its
+// content type from its first bytes rather than trusting the client.
+func (handler *ImageHandler) readImage(w http.ResponseWriter, r *http.Request) ([]byte, string, error) {
+	// The form may carry a few other fields besides the image.
+	r.Body = http.MaxBytesReader(w, r.Body, handler.MaxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, "", entity.ErrImageTooLarge
+		}
+		return nil, "", fmt.Errorf("%w: %v", errInvalidImageForm, err)
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, header, err := r.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, "", entity.ErrImageIsRequired
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errInvalidImageForm, err)
+	}
+	defer file.Close()
+	if header.Size > handler.MaxSize {
+		return nil, "", entity.ErrImageTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(file, handler.MaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(body)) > handler.MaxSize {
+		return nil, "", entity.ErrImageTooLarge
+	}
+	if len(body) == 0 {
+		return nil, "", entity.ErrImageIsRequired
+	}
+	contentType := http.DetectContentType(body)
+	if !entity.IsSupportedImageType(contentType) {
+		return nil, "", entity.ErrUnsupportedImageType
+	}
+	// Clients that don't know the type send application/octet-stream.
+	if declared, _, err := mime.ParseMediaType(header.Header.Get("Content-Type")); err == nil &&
+		declared != "application/octet-stream" && declared != contentType {
+		return nil, "", entity.ErrImageTypeMismatch
+	}
+	return body, contentType, nil
+}
+
+// putFiles puts the original image and its thumbnails in the storage.
+func (handler *ImageHandler) putFiles(r *http.Request, image *entity.ProductImage, body []byte, img goimage.Image) error {
+	if err := handler.Storage.Put(r.Context(), image.Key, body, image.ContentType); err != nil {
+		return err
+	}
+	thumbnailType := entity.ThumbnailContentType(image.ContentType)
+	for name, key := range image.ThumbnailKeys {
+		thumbnail, err := imaging.Encode(imaging.Thumbnail(img, entity.ThumbnailSizes[name]), thumbnailType)
+		if err != nil {
+			return err
+		}
+		if err = handler.Storage.Put(r.Context(), key, thumbnail, thumbnailType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteFiles removes the files of the image from the storage. Failures
+// are only logged: the image is already gone from the product.
+func (handler *ImageHandler) deleteFiles(r *http.Request, image *entity.ProductImage) {
+	if err := storage.DeleteAll(r.Context(), handler.Storage, image.Keys()); err != nil {
+		log.Println(err)
+	}
+}
+
+func imageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrImageTooLarge), errors.Is(err, imaging.ErrTooManyPixels):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, entity.ErrUnsupportedImageType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, entity.ErrImageIsRequired), errors.Is(err, entity.ErrImageTypeMismatch),
+		errors.Is(err, entity.ErrInvalidImage), errors.Is(err, entity.ErrInvalidImageOrder), errors.Is(err, errInvalidImageForm):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
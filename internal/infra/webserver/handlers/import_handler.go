@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/importer"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/spreadsheet"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+)
+
+var (
+	errImportTooLarge = errors.New("spreadsheet is too large")
+	errInvalidDryRun  = errors.New("dry_run must be true or false")
+)
+
+type ImportHandler struct {
+	Importer *importer.Importer
+	// MaxSize is the largest spreadsheet accepted, in bytes.
+	MaxSize int64
+	// Currency is the currency of prices when the spreadsheet doesn't say.
+	Currency string
+}
+
+func NewImportHandler(productDB database.ProductInterface, maxSize int64, currency string) *ImportHandler {
+	return &ImportHandler{
+		Importer: importer.NewImporter(productDB),
+		MaxSize:  maxSize,
+		Currency: currency,
+	}
+}
+
+// Import products godoc
+// @Summary     Import products
+// @Description Create and update products from a CSV or XLSX spreadsheet whose first row holds the column headers. Rows are matched to existing products
+// @Description by name: matching products get the price, low stock threshold and tags of the row, and new products are created for the others.
+// @Description Nothing is imported when any row is invalid, and a dry run only reports what would be done.
+// @Tags        products
+// @Accept      text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce     json
+// @Param       request       body        string   true    "Spreadsheet"
+// @Param       dry_run       query       bool     false   "Check every row without importing anything"
+// @Param       map           query       []string false   "Column a field is read from, as field=Column, with field one of name, price, currency, low_stock_threshold or tags" collectionFormat(multi)
+// @Param       currency      query       string   false   "Currency of prices when there is no currency column, DEFAULT_CURRENCY by default"
+// @Success     200           {object}    dto.ProductImportOutput
+// @Failure     400           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     413           {object}    Error
+// @Failure     415           {object}    Error
+// @Failure     422           {object}    dto.ProductImportOutput
+// @Failure     500           {object}    Error
+// @Router      /product/import    [post]
+// @Security    ApiKeyAuth
+func (handler *ImportHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	options, err := importOptions(r, handler.Currency)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, handler.MaxSize))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			err = errImportTooLarge
+		}
+		w.WriteHeader(importErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	rows, err := spreadsheet.Read(body, r.Header.Get("Content-Type"))
+	if err != nil {
+		w.WriteHeader(importErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	report, err := handler.Importer.Import(rows, options)
+	if errors.Is(err, importer.ErrInvalidRows) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(importErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// importOptions reads the dry_run, currency and map parameters of an
+// import. Prices are in currency when the spreadsheet doesn't say.
+func importOptions(r *http.Request, currency string) (importer.Options, error) {
+	query := r.URL.Query()
+	options := importer.Options{Currency: currency, Source: auditSource(r)}
+	var err error
+	if dryRun := query.Get("dry_run"); dryRun != "" {
+		if options.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return options, errInvalidDryRun
+		}
+	}
+	if currency := query.Get("currency"); currency != "" {
+		options.Currency = strings.ToUpper(currency)
+		if !pkgEntity.IsValidCurrency(options.Currency) {
+			return options, pkgEntity.ErrInvalidCurrency
+		}
+	}
+	options.Mapping, err = importer.ParseMapping(query["map"])
+	return options, err
+}
+
+func importErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errImportTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, spreadsheet.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, database.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, importer.ErrMissingColumn), errors.Is(err, importer.ErrNoRows),
+		errors.Is(err, spreadsheet.ErrInvalidXLSX):
+		return http.StatusBadRequest
+	}
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		return http.StatusBadRequest
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
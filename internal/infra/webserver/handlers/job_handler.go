@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/exporter"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/spreadsheet"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/webserver/middlewares"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/worker"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+var errNoJobResult = errors.New("job has no result")
+
+type JobHandler struct {
+	JobDB  database.JobInterface
+	Files  *storage.FileStore
+	Runner *worker.JobRunner
+	// Products reads the listing filters of export jobs.
+	Products *ProductHandler
+	// ImportMaxSize is the largest spreadsheet accepted, in bytes.
+	ImportMaxSize int64
+	// Currency is the currency of imported prices when the spreadsheet
+	// doesn't say.
+	Currency string
+}
+
+func NewJobHandler(
+	jobDB database.JobInterface,
+	files *storage.FileStore,
+	runner *worker.JobRunner,
+	products *ProductHandler,
+	importMaxSize int64,
+	currency string,
+) *JobHandler {
+	return &JobHandler{
+		JobDB:         jobDB,
+		Files:         files,
+		Runner:        runner,
+		Products:      products,
+		ImportMaxSize: importMaxSize,
+		Currency:      currency,
+	}
+}
+
+// Create import job godoc
+// @Summary     Create import job
+// @Description Import products from a CSV or XLSX spreadsheet in the background, the same way POST /product/import does. The job can be followed
+// @Description at GET /jobs/{id}, and its result is the import report, also when it fails because some rows are invalid.
+// @Description The spreadsheet is streamed to disk rather than read whole, so an Idempotency-Key header is ignored.
+// @Tags        jobs
+// @Accept      text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce     json
+// @Param       request       body        string   true    "Spreadsheet"
+// @Param       dry_run       query       bool     false   "Check every row without importing anything"
+// @Param       map           query       []string false   "Column a field is read from, as field=Column, with field one of name, price, currency, low_stock_threshold or tags" collectionFormat(multi)
+// @Param       currency      query       string   false   "Currency of prices when there is no currency column, DEFAULT_CURRENCY by default"
+// @Success     202           {object}    entity.Job
+// @Header      202           {string}    Location    "/jobs/{id}"
+// @Failure     400           {object}    Error
+// @Failure     413           {object}    Error
+// @Failure     415           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /jobs/product-import    [post]
+// @Security    ApiKeyAuth
+func (handler *JobHandler) CreateImportJob(w http.ResponseWriter, r *http.Request) {
+	options, err := importOptions(r, handler.Currency)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if !spreadsheet.IsSupportedType(contentType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		errorObj := Error{Message: spreadsheet.ErrUnsupportedType.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	params := worker.ImportParams{
+		ContentType: contentType,
+		Mapping:     options.Mapping,
+		Currency:    options.Currency,
+		DryRun:      options.DryRun,
+	}
+	job, err := entity.NewJob(entity.JobProductImport, "", actor(r))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	params.InputKey = "jobs/" + job.ID.String() + "/input"
+	body := http.MaxBytesReader(w, r.Body, handler.ImportMaxSize)
+	err = handler.Files.Write(params.InputKey, func(file io.Writer) error {
+		_, err := io.Copy(file, body)
+		return err
+	})
+	if err != nil {
+		handler.Files.Delete(params.InputKey)
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			err = errImportTooLarge
+		}
+		w.WriteHeader(importErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.queue(w, job, params)
+}
+
+// Create export job godoc
+// @Summary     Create export job
+// @Description Export the products that match the filters of the listing in the background, as GET /product/export does. The job can be followed
+// @Description at GET /jobs/{id}, and the file is downloaded from GET /jobs/{id}/result once it succeeded.
+// @Tags        jobs
+// @Produce     json
+// @Param       format        query       string   false    "csv (default), ndjson or xlsx"
+// @Param       status        query       []string false    "Only products in these statuses (draft, published or archived), ignored for viewers" collectionFormat(multi)
+// @Param       category      query       string   false    "Only products in this category"
+// @Param       include_descendants  query  bool   false    "Also match products in subcategories of category"
+// @Param       tag           query       []string false    "Only products with these tags" collectionFormat(multi)
+// @Param       tag_match     query       string   false    "any (default) or all of the tags"
+// @Param       in_stock      query       bool     false    "Only products with (true) or without (false) available stock"
+// @Param       low_stock     query       bool     false    "Only products at or below their low stock threshold"
+// @Param       attr.{code}   query       string   false    "Only products whose attribute has this value, repeat for any of several values"
+// @Success     202           {object}    entity.Job
+// @Header      202           {string}    Location    "/jobs/{id}"
+// @Failure     400           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /jobs/product-export    [post]
+// @Security    ApiKeyAuth
+func (handler *JobHandler) CreateExportJob(w http.ResponseWriter, r *http.Request) {
+	params := worker.ExportParams{Format: exporter.CSV}
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		if _, ok := exporter.ContentTypes[format]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			errorObj := Error{Message: exporter.ErrInvalidFormat.Error()}
+			json.NewEncoder(w).Encode(errorObj)
+			return
+		}
+		params.Format = format
+	}
+	var err error
+	if params.Filter, err = handler.Products.parseProductFilter(r); err != nil {
+		w.WriteHeader(attributeErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if params.Filter.Statuses, err = productStatuses(r); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	job, err := entity.NewJob(entity.JobProductExport, "", actor(r))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.queue(w, job, params)
+}
+
+// queue saves the job with its params and wakes a worker up to run it.
+func (handler *JobHandler) queue(w http.ResponseWriter, job *entity.Job, params interface{}) {
+	encoded, err := json.Marshal(params)
+	if err == nil {
+		job.Params = string(encoded)
+		err = handler.JobDB.Create(job)
+	}
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.Runner.Notify()
+	w.Header().Set("Location", "/jobs/"+job.ID.String())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// Get job godoc
+// @Summary     Get job
+// @Description Get the status and progress of a job, and why it failed. Jobs are only visible to whoever created them and to admins.
+// @Tags        jobs
+// @Produce     json
+// @Param       id            path        string   true     "Job Id"
+// @Success     200           {object}    entity.Job
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /jobs/{id}    [get]
+// @Security    ApiKeyAuth
+func (handler *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := handler.findJob(r)
+	if err != nil {
+		w.WriteHeader(jobErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
+
+// Download job result godoc
+// @Summary     Download job result
+// @Description Download the file produced by a job: the products of an export job or the report of an import job.
+// @Tags        jobs
+// @Produce     json,text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param       id            path        string   true     "Job Id"
+// @Success     200           {file}      file
+// @Header      200           {string}    Content-Disposition    "attachment; filename=<file>"
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /jobs/{id}/result    [get]
+// @Security    ApiKeyAuth
+func (handler *JobHandler) GetJobResult(w http.ResponseWriter, r *http.Request) {
+	job, err := handler.findJob(r)
+	if err == nil && job.ResultKey == "" {
+		err = errNoJobResult
+	}
+	if err != nil {
+		w.WriteHeader(jobErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	file, err := handler.Files.Open(job.ResultKey)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", job.ResultContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": job.ResultFilename}))
+	var modified time.Time
+	if job.FinishedAt != nil {
+		modified = *job.FinishedAt
+	}
+	http.ServeContent(w, r, job.ResultFilename, modified, file)
+}
+
+// findJob loads the job of the request, which is reported as not found to
+// anyone but its creator and admins.
+func (handler *JobHandler) findJob(r *http.Request) (*entity.Job, error) {
+	job, err := handler.JobDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, err
+	}
+	if user := middlewares.UserFromContext(r.Context()); job.CreatedBy != actor(r) && (user == nil || !user.IsAdmin()) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if job.ResultKey != "" {
+		job.ResultURL = "/jobs/" + job.ID.String() + "/result"
+	}
+	return job, nil
+}
+
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, errNoJobResult):
+		return http.StatusNotFound
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/events"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/go-chi/jwtauth"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// liveQueueSize is how many events can wait to be handled for a
+	// connection before the client is considered too slow and disconnected.
+	liveQueueSize = 256
+	// liveWriteTimeout bounds writing each message to a client.
+	liveWriteTimeout = 10 * time.Second
+	// liveReadLimit is the largest request read from a client, in bytes.
+	liveReadLimit = 64 << 10
+)
+
+// Kinds of change told in live updates.
+const (
+	liveChangePrice = "price"
+	liveChangeStock = "stock"
+)
+
+var errInvalidLiveAction = errors.New("action must be subscribe or unsubscribe")
+
+// liveUpgrader accepts connections from any origin, since clients
+// authenticate with an access token rather than a cookie other sites could
+// send for them. Failed handshakes are answered with an Error.
+var liveUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+	Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+		w.WriteHeader(status)
+		errorObj := Error{Message: reason.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+	},
+}
+
+type LiveHandler struct {
+	ProductDB  database.ProductInterface
+	CategoryDB database.CategoryInterface
+	// Events keeps the last product events, which are passed on to the
+	// clients subscribed to their products.
+	Events *events.Buffer
+	// MaxSubscriptions is how many products and categories each connection
+	// can be subscribed to, and PingInterval how often clients are pinged.
+	// Clients that don't answer within two intervals are disconnected.
+	MaxSubscriptions int
+	PingInterval     time.Duration
+}
+
+func NewLiveHandler(
+	productDB database.ProductInterface,
+	categoryDB database.CategoryInterface,
+	productEvents *events.Buffer,
+	maxSubscriptions int,
+	pingInterval time.Duration,
+) *LiveHandler {
+	return &LiveHandler{
+		ProductDB:        productDB,
+		CategoryDB:       categoryDB,
+		Events:           productEvents,
+		MaxSubscriptions: maxSubscriptions,
+		PingInterval:     pingInterval,
+	}
+}
+
+// Live channel godoc
+// @Summary     Live price and stock updates
+// @Description Open a WebSocket, authenticated with the access token in the Authorization header or, for browsers, the jwt query parameter.
+// @Description Clients send {"action": "subscribe", "products": [ids], "categories": [ids]}, or "unsubscribe", and get a "subscribed" message listing
+// @Description their subscriptions, a "snapshot" of the price and stock of each product subscribed to, directly or through its category or
+// @Description subcategories, then an "update" whenever the price or stock of one changes. Products deleted, no longer visible or moved out of the
+// @Description categories subscribed to are "removed". Requests that can't be honored, such as going over the subscription limit, get an "error". The server pings clients
+// @Description and disconnects the ones that don't answer, the ones too slow to read their updates and the ones whose token expired.
+// @Tags        products
+// @Param       jwt           query       string   false    "Access token, when the Authorization header can't be sent"
+// @Param       status        query       []string false    "Only products in these statuses (draft, published or archived), ignored for viewers" collectionFormat(multi)
+// @Success     101           {object}    dto.LiveMessage
+// @Failure     400           {object}    Error
+// @Failure     405           {object}    Error
+// @Router      /ws           [get]
+// @Security    ApiKeyAuth
+func (handler *LiveHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	statuses, err := productStatuses(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	conn, err := liveUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(liveReadLimit)
+	var expiresAt time.Time
+	if token, _, err := jwtauth.FromContext(r.Context()); err == nil && token != nil {
+		expiresAt = token.Expiration()
+	}
+	client := &liveClient{
+		handler:    handler,
+		conn:       conn,
+		statuses:   statuses,
+		products:   map[string]bool{},
+		categories: map[string]*liveCategory{},
+		known:      map[string]liveState{},
+	}
+	client.run(expiresAt)
+}
+
+// liveState is the price and stock of a product a client was told about.
+type liveState struct {
+	price    pkgEntity.Money
+	prices   []pkgEntity.Money
+	stock    int
+	reserved int
+}
+
+func newLiveState(product *entity.Product) liveState {
+	state := liveState{price: product.Price, stock: product.Stock, reserved: product.Reserved}
+	for _, price := range product.Prices {
+		state.prices = append(state.prices, price.Money())
+	}
+	return state
+}
+
+// changes tells what differs from the state the client was told about.
+func (s liveState) changes(previous liveState) []string {
+	var changes []string
+	if s.price != previous.price || !equalMoney(s.prices, previous.prices) {
+		changes = append(changes, liveChangePrice)
+	}
+	if s.stock != previous.stock || s.reserved != previous.reserved {
+		changes = append(changes, liveChangeStock)
+	}
+	return changes
+}
+
+func equalMoney(a, b []pkgEntity.Money) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// liveCategory is a category a client is subscribed to: what products it
+// matches and the ones the client was told about through it.
+type liveCategory struct {
+	matches  func(product *entity.Product) bool
+	products map[string]bool
+}
+
+// liveClient is a connection to the live channel. Its subscriptions and
+// the states it was told about are only used by the goroutine running it,
+// which does all the writing, so messages are never reordered.
+type liveClient struct {
+	handler    *LiveHandler
+	conn       *websocket.Conn
+	statuses   []string
+	products   map[string]bool
+	categories map[string]*liveCategory
+	known      map[string]liveState
+}
+
+// run passes the updates on to the client and handles its requests until
+// it disconnects, is too slow or its token expires.
+func (c *liveClient) run(expiresAt time.Time) {
+	_, _, queue, unsubscribe := c.handler.Events.Subscribe("", liveQueueSize)
+	defer unsubscribe()
+	requests := make(chan []byte)
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- c.read(requests, stop)
+	}()
+	ping := time.NewTicker(c.handler.PingInterval)
+	defer ping.Stop()
+	var expired <-chan time.Time
+	if !expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	code, reason := websocket.CloseNormalClosure, ""
+	for code == websocket.CloseNormalClosure {
+		var err error
+		select {
+		case <-done:
+			// The client closed the connection, and its close frame was
+			// answered while reading.
+			c.conn.Close()
+			return
+		case request := <-requests:
+			err = c.handle(request)
+		case event, ok := <-queue:
+			if !ok {
+				code, reason = websocket.CloseTryAgainLater, "client too slow"
+				break
+			}
+			err = c.update(event)
+		case <-ping.C:
+			err = c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout))
+		case <-expired:
+			code, reason = websocket.ClosePolicyViolation, "token expired"
+		}
+		if err != nil {
+			break
+		}
+	}
+	// A close frame that can't be sent doesn't keep the connection open.
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(liveWriteTimeout))
+	c.conn.Close()
+	close(stop)
+	<-done
+}
+
+// read reads the requests of the client and hands them to run. It returns
+// once the connection is closed, the client stops answering pings or run
+// stops.
+func (c *liveClient) read(requests chan<- []byte, stop <-chan struct{}) error {
+	timeout := 2 * c.handler.PingInterval
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		select {
+		case requests <- message:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// handle answers a request of the client, telling it about the ones that
+// aren't valid JSON.
+func (c *liveClient) handle(message []byte) error {
+	var request dto.LiveRequest
+	if err := json.Unmarshal(message, &request); err != nil {
+		return c.write(dto.LiveMessage{Type: "error", Message: err.Error()})
+	}
+	switch request.Action {
+	case "subscribe":
+		return c.subscribe(request.Products, request.Categories)
+	case "unsubscribe":
+		var dropped []string
+		for _, id := range request.Products {
+			delete(c.products, id)
+			dropped = append(dropped, id)
+		}
+		for _, id := range request.Categories {
+			if category := c.categories[id]; category != nil {
+				delete(c.categories, id)
+				for productID := range category.products {
+					dropped = append(dropped, productID)
+				}
+			}
+		}
+		// Products still subscribed to some other way stay known.
+		for _, id := range dropped {
+			if !c.tracked(id) {
+				delete(c.known, id)
+			}
+		}
+		return c.writeSubscriptions()
+	}
+	return c.write(dto.LiveMessage{Type: "error", Message: errInvalidLiveAction.Error()})
+}
+
+// subscribe adds the products and categories to the subscriptions of the
+// client and sends it a snapshot of the products and of the visible
+// products in the categories, or nothing at all when any of them can't be
+// found or they go over the subscription limit.
+func (c *liveClient) subscribe(productIds, categoryIds []string) error {
+	var products []*entity.Product
+	for _, id := range uniqueStrings(productIds) {
+		if c.products[id] {
+			continue
+		}
+		product, err := c.handler.ProductDB.FindById(id)
+		if err != nil || !c.visible(product) {
+			return c.write(dto.LiveMessage{Type: "error", Message: fmt.Sprintf("product %s not found", id)})
+		}
+		products = append(products, product)
+	}
+	categories := map[string]*liveCategory{}
+	var inCategories []entity.Product
+	for _, id := range uniqueStrings(categoryIds) {
+		if c.categories[id] != nil {
+			continue
+		}
+		if _, err := c.handler.CategoryDB.FindById(id); err != nil {
+			return c.write(dto.LiveMessage{Type: "error", Message: fmt.Sprintf("category %s not found", id)})
+		}
+		filter := database.ProductFilter{CategoryID: id, IncludeDescendants: true}
+		matches, err := c.handler.ProductDB.Matcher(filter)
+		if err != nil {
+			log.Println(err)
+			return c.write(dto.LiveMessage{Type: "error", Message: err.Error()})
+		}
+		filter.Statuses = c.statuses
+		found, err := c.handler.ProductDB.FindAll(0, 0, "", filter)
+		if err != nil {
+			log.Println(err)
+			return c.write(dto.LiveMessage{Type: "error", Message: err.Error()})
+		}
+		category := &liveCategory{matches: matches, products: map[string]bool{}}
+		for _, product := range found {
+			category.products[product.ID.String()] = true
+		}
+		categories[id] = category
+		inCategories = append(inCategories, found...)
+	}
+	if len(c.products)+len(c.categories)+len(products)+len(categories) > c.handler.MaxSubscriptions {
+		message := fmt.Sprintf("at most %d products and categories can be subscribed to", c.handler.MaxSubscriptions)
+		return c.write(dto.LiveMessage{Type: "error", Message: message})
+	}
+	for _, product := range products {
+		c.products[product.ID.String()] = true
+	}
+	for id, category := range categories {
+		c.categories[id] = category
+	}
+	if err := c.writeSubscriptions(); err != nil {
+		return err
+	}
+	for i := range inCategories {
+		products = append(products, &inCategories[i])
+	}
+	sent := map[string]bool{}
+	for _, product := range products {
+		id := product.ID.String()
+		if sent[id] {
+			continue
+		}
+		sent[id] = true
+		state := newLiveState(product)
+		c.known[id] = state
+		if err := c.write(liveProductMessage("snapshot", product, state, nil)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update tells the client about the event when it changed the price or
+// stock of a product it is subscribed to, or when the product was removed
+// or is no longer subscribed to, having moved out of the categories the
+// client was told about it through.
+func (c *liveClient) update(event entity.Event) error {
+	var product entity.Product
+	if err := json.Unmarshal(event.Payload, &product); err != nil {
+		log.Println(err)
+		return nil
+	}
+	id := product.ID.String()
+	previous, known := c.known[id]
+	removed := event.Type == entity.EventProductDeleted || !c.visible(&product)
+	if removed || !c.track(&product) {
+		c.untrack(id)
+		if !known {
+			return nil
+		}
+		delete(c.known, id)
+		return c.write(dto.LiveMessage{Type: "removed", ProductID: id, EventID: event.ID.String()})
+	}
+	state := newLiveState(&product)
+	changes := []string{liveChangePrice, liveChangeStock}
+	if known {
+		if changes = state.changes(previous); len(changes) == 0 {
+			return nil
+		}
+	}
+	c.known[id] = state
+	message := liveProductMessage("update", &product, state, changes)
+	message.EventID = event.ID.String()
+	return c.write(message)
+}
+
+// track records which of the categories subscribed to the product is in,
+// telling whether it is subscribed to, directly or through any of them.
+func (c *liveClient) track(product *entity.Product) bool {
+	id := product.ID.String()
+	subscribed := c.products[id]
+	for _, category := range c.categories {
+		if category.matches(product) {
+			category.products[id] = true
+			subscribed = true
+		} else {
+			delete(category.products, id)
+		}
+	}
+	return subscribed
+}
+
+// untrack forgets the product was told about through any category.
+func (c *liveClient) untrack(id string) {
+	for _, category := range c.categories {
+		delete(category.products, id)
+	}
+}
+
+// tracked tells whether the product is still subscribed to, directly or
+// through a category the client was told about it through.
+func (c *liveClient) tracked(id string) bool {
+	if c.products[id] {
+		return true
+	}
+	for _, category := range c.categories {
+		if category.products[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// visible tells whether the product is in the statuses the client can see.
+func (c *liveClient) visible(product *entity.Product) bool {
+	if len(c.statuses) == 0 {
+		return true
+	}
+	for _, status := range c.statuses {
+		if product.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *liveClient) writeSubscriptions() error {
+	message := dto.LiveMessage{Type: "subscribed", Products: []string{}, Categories: []string{}}
+	for id := range c.products {
+		message.Products = append(message.Products, id)
+	}
+	for id := range c.categories {
+		message.Categories = append(message.Categories, id)
+	}
+	sort.Strings(message.Products)
+	sort.Strings(message.Categories)
+	return c.write(message)
+}
+
+func (c *liveClient) write(message dto.LiveMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func liveProductMessage(messageType string, product *entity.Product, state liveState, changes []string) dto.LiveMessage {
+	available := state.stock - state.reserved
+	return dto.LiveMessage{
+		Type:      messageType,
+		ProductID: product.ID.String(),
+		Changes:   changes,
+		Price:     &state.price,
+		Prices:    state.prices,
+		Stock:     &state.stock,
+		Reserved:  &state.reserved,
+		Available: &available,
+	}
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/events"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type liveTestClient struct {
+	conn *websocket.Conn
+}
+
+func dialLive(t *testing.T, server *httptest.Server) *liveTestClient {
+	conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return &liveTestClient{conn: conn}
+}
+
+func (c *liveTestClient) send(t *testing.T, request dto.LiveRequest) {
+	assert.NoError(t, c.conn.WriteJSON(request))
+}
+
+func (c *liveTestClient) receive(t *testing.T) dto.LiveMessage {
+	var message dto.LiveMessage
+	assert.NoError(t, c.conn.ReadJSON(&message))
+	return message
+}
+
+func TestLiveHandler_Categories(t *testing.T) {
+	db := createTestDB(t)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	productDB := database.NewProduct(db)
+	categoryDB := database.NewCategory(db)
+	category, _ := entity.NewCategory("Peripherals", nil)
+	assert.NoError(t, categoryDB.Create(category))
+	mouse, _ := entity.NewProduct("Mouse", pkgEntity.Money{Amount: 3999, Currency: "BRL"})
+	mouse.Categories = []entity.Category{*category}
+	keyboard, _ := entity.NewProduct("Keyboard", pkgEntity.Money{Amount: 9990, Currency: "BRL"})
+	// Clients without the products:write scope only see published products.
+	mouse.Status = entity.ProductPublished
+	keyboard.Status = entity.ProductPublished
+	assert.NoError(t, productDB.Create(mouse))
+	assert.NoError(t, productDB.Create(keyboard))
+	productEvents := events.NewBuffer(10)
+	handler := NewLiveHandler(productDB, categoryDB, productEvents, 10, time.Minute)
+	server := httptest.NewServer(http.HandlerFunc(handler.Connect))
+	t.Cleanup(server.Close)
+	update := func(product *entity.Product, categories []entity.Category) {
+		product.Categories = categories
+		assert.NoError(t, productDB.Update(product))
+		saved, _ := productDB.FindById(product.ID.String())
+		event, _ := entity.NewEvent(entity.EventProductUpdated, product.ID.String(), saved)
+		productEvents.Add(*event)
+	}
+
+	// Subscribing to a category sends a snapshot of its products.
+	client := dialLive(t, server)
+	client.send(t, dto.LiveRequest{Action: "subscribe", Categories: []string{category.ID.String()}})
+	message := client.receive(t)
+	assert.Equal(t, "subscribed", message.Type)
+	assert.Equal(t, []string{category.ID.String()}, message.Categories)
+	message = client.receive(t)
+	assert.Equal(t, "snapshot", message.Type)
+	assert.Equal(t, mouse.ID.String(), message.ProductID)
+
+	// Products moved into the category are updates, and the ones moved out
+	// are removed.
+	update(keyboard, []entity.Category{*category})
+	message = client.receive(t)
+	assert.Equal(t, "update", message.Type)
+	assert.Equal(t, keyboard.ID.String(), message.ProductID)
+	update(mouse, nil)
+	message = client.receive(t)
+	assert.Equal(t, "removed", message.Type)
+	assert.Equal(t, mouse.ID.String(), message.ProductID)
+
+	// Once unsubscribed, the products of the category aren't told about.
+	client.send(t, dto.LiveRequest{Action: "unsubscribe", Categories: []string{category.ID.String()}})
+	message = client.receive(t)
+	assert.Equal(t, "subscribed", message.Type)
+	assert.Empty(t, message.Categories)
+	update(keyboard, nil)
+	client.send(t, dto.LiveRequest{Action: "subscribe", Products: []string{mouse.ID.String()}})
+	message = client.receive(t)
+	assert.Equal(t, "subscribed", message.Type)
+	assert.Equal(t, []string{mouse.ID.String()}, message.Products)
+}
+
+func TestLiveHandler_InvalidRequests(t *testing.T) {
+	db := createTestDB(t)
+	handler := NewLiveHandler(database.NewProduct(db), database.NewCategory(db), events.NewBuffer(10), 10, time.Minute)
+	server := httptest.NewServer(http.HandlerFunc(handler.Connect))
+	t.Cleanup(server.Close)
+
+	// Requests that aren't a WebSocket handshake get an error.
+	res := serve(http.HandlerFunc(handler.Connect), http.MethodGet, "/ws", "")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), `"message"`)
+
+	// So do messages that aren't JSON, or ask for an unknown action, without
+	// closing the connection.
+	client := dialLive(t, server)
+	assert.NoError(t, client.conn.WriteMessage(websocket.TextMessage, []byte("subscribe")))
+	message := client.receive(t)
+	assert.Equal(t, "error", message.Type)
+	client.send(t, dto.LiveRequest{Action: "watch"})
+	message = client.receive(t)
+	assert.Equal(t, "error", message.Type)
+	assert.Equal(t, errInvalidLiveAction.Error(), message.Message)
+	client.send(t, dto.LiveRequest{Action: "subscribe", Products: []string{"missing"}})
+	message = client.receive(t)
+	assert.Equal(t, "error", message.Type)
+	assert.Equal(t, "product missing not found", message.Message)
+}
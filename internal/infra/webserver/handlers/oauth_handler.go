@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/jwtauth"
+)
+
+// OAuthError is the error response defined by RFC 6749 section 5.2.
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type OAuthHandler struct {
+	ClientDB     database.ClientInterface
+	Jwt          *jwtauth.JWTAuth
+	JwtExpiresIn int
+}
+
+func NewOAuthHandler(db database.ClientInterface, Jwt *jwtauth.JWTAuth, JwtExpiresIn int) *OAuthHandler {
+	return &OAuthHandler{
+		ClientDB:     db,
+		Jwt:          Jwt,
+		JwtExpiresIn: JwtExpiresIn,
+	}
+}
+
+// Create client godoc
+// @Summary     Register OAuth2 client
+// @Description Registers a client for the client_credentials grant. Only admins can register clients, and the secret is only returned once.
+// @Tags        oauth
+// @Accept      json
+// @Produce     json
+// @Param       request   body      dto.CreateClientInput   true    "Client request payload"
+// @Success     201       {object}  dto.CreateClientOutput
+// @Failure     400       {object}  Error
+// @Failure     403       {object}  Error
+// @Failure     500       {object}  Error
+// @Router      /oauth/clients    [post]
+// @Security    ApiKeyAuth
+func (handler *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var createClientInput dto.CreateClientInput
+	if err := json.NewDecoder(r.Body).Decode(&createClientInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	client, secret, err := entity.NewClient(createClientInput.Name, createClientInput.Scope)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.ClientDB.Create(client); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateClientOutput{
+		ClientID:     client.ID.String(),
+		ClientSecret: secret,
+		Name:         client.Name,
+		Scope:        client.Scope,
+	})
+}
+
+// Token godoc
+// @Summary     Get a client access token
+// @Description OAuth2 token endpoint supporting the client_credentials grant (RFC 6749 section 4.4). Clients authenticate with HTTP Basic or with client_id and client_secret form parameters.
+// @Tags        oauth
+// @Accept      x-www-form-urlencoded
+// @Produce     json
+// @Param       grant_type      formData    string   true     "Must be client_credentials"
+// @Param       scope           formData    string   false    "Space-delimited scopes, defaults to every scope of the client"
+// @Param       client_id       formData    string   false    "Client id when not using HTTP Basic"
+// @Param       client_secret   formData    string   false    "Client secret when not using HTTP Basic"
+// @Success     200             {object}    dto.OAuthTokenOutput
+// @Failure     400             {object}    OAuthError
+// @Failure     401             {object}    OAuthError
+// @Failure     500             {object}    OAuthError
+// @Router      /oauth/token    [post]
+func (handler *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	grantType := r.PostForm.Get("grant_type")
+	if grantType == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	}
+	if grantType != "client_credentials" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+	}
+
+	clientID, clientSecret, usingBasic := r.BasicAuth()
+	if usingBasic {
+		if r.PostForm.Get("client_id") != "" || r.PostForm.Get("client_secret") != "" {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "only one client authentication method may be used")
+			return
+		}
+		// Basic credentials are form-urlencoded before being base64 encoded.
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		writeInvalidClient(w, usingBasic)
+		return
+	}
+	client, err := handler.ClientDB.FindById(clientID)
+	if err != nil || !client.ValidateSecret(clientSecret) {
+		writeInvalidClient(w, usingBasic)
+		return
+	}
+	scope, err := client.GrantScope(r.PostForm.Get("scope"))
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	}
+
+	claims := make(map[string]interface{})
+	claims["sub"] = client.ID.String()
+	claims["client_id"] = client.ID.String()
+	claims["scope"] = scope
+	token, err := signToken(handler.Jwt, handler.JwtExpiresIn, claims)
+	if err != nil {
+		log.Println(err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.OAuthTokenOutput{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   handler.JwtExpiresIn,
+		Scope:       scope,
+	})
+}
+
+func writeInvalidClient(w http.ResponseWriter, usingBasic bool) {
+	if usingBasic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(OAuthError{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func createOAuthHandler(t *testing.T) (*OAuthHandler, *entity.Client, string) {
+	clientDB := database.NewClient(createTestDB(t))
+	client, secret, err := entity.NewClient("Storefront", entity.ScopeProductsRead)
+	assert.NoError(t, err)
+	assert.NoError(t, clientDB.Create(client))
+	return NewOAuthHandler(clientDB, jwtauth.New("HS256", []byte("secret"), nil), 300), client, secret
+}
+
+// postToken sends the form to the token endpoint.
+func postToken(handler *OAuthHandler, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := httptest.NewRecorder()
+	handler.Token(res, req)
+	return res
+}
+
+func TestOAuthHandler_Token(t *testing.T) {
+	handler, client, secret := createOAuthHandler(t)
+
+	res := postToken(handler, url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ID.String()}, "client_secret": {secret}})
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+	var output dto.OAuthTokenOutput
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+	assert.Equal(t, "Bearer", output.TokenType)
+	assert.Equal(t, 300, output.ExpiresIn)
+	assert.Equal(t, entity.ScopeProductsRead, output.Scope)
+
+	token, err := jwtauth.VerifyToken(handler.Jwt, output.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, client.ID.String(), token.Subject())
+	scope, _ := token.Get("scope")
+	assert.Equal(t, entity.ScopeProductsRead, scope)
+}
+
+func TestOAuthHandler_TokenErrors(t *testing.T) {
+	handler, client, secret := createOAuthHandler(t)
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		status int
+		error  string
+	}{
+		{
+			name:   "wrong grant type",
+			form:   url.Values{"grant_type": {"password"}, "client_id": {client.ID.String()}, "client_secret": {secret}},
+			status: http.StatusBadRequest,
+			error:  "unsupported_grant_type",
+		},
+		{
+			name:   "bad client secret",
+			form:   url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ID.String()}, "client_secret": {"wrong"}},
+			status: http.StatusUnauthorized,
+			error:  "invalid_client",
+		},
+		{
+			name: "scope wider than the client's",
+			form: url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {client.ID.String()},
+				"client_secret": {secret},
+				"scope":         {entity.ScopeProductsRead + " " + entity.ScopeProductsWrite},
+			},
+			status: http.StatusBadRequest,
+			error:  "invalid_scope",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := postToken(handler, test.form)
+			assert.Equal(t, test.status, res.Code)
+			var output OAuthError
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+			assert.Equal(t, test.error, output.Error)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/patch"
+)
+
+var (
+	errUnsupportedPatchType = errors.New("patch must be sent as " + patch.MergePatchType + " or " + patch.JSONPatchType)
+	errInvalidPatchResult   = errors.New("patched product is invalid")
+)
+
+// productInput is the document patches of a product apply to: the fields
+// a client may change, as they are sent to update it.
+func productInput(product *entity.Product) dto.UpdateProductInput {
+	lowStockThreshold := product.LowStockThreshold
+	input := dto.UpdateProductInput{
+		Name:              product.Name,
+		Price:             product.Price,
+		LowStockThreshold: &lowStockThreshold,
+		CategoryIDs:       make([]string, len(product.Categories)),
+		Tags:              make([]string, len(product.Tags)),
+		Attributes:        make(map[string]interface{}, len(product.Attributes)),
+	}
+	for i, category := range product.Categories {
+		input.CategoryIDs[i] = category.ID.String()
+	}
+	for i, tag := range product.Tags {
+		input.Tags[i] = tag.Name
+	}
+	for _, attribute := range product.Attributes {
+		input.Attributes[attribute.Code] = attribute.Interface()
+	}
+	return input
+}
+
+// patchProductInput applies a patch of mediaType to the product, returning
+// what the client wants it to become. Lists taken out by the patch are
+// cleared rather than kept as they are.
+func patchProductInput(product *entity.Product, mediaType string, body []byte) (dto.UpdateProductInput, error) {
+	var input dto.UpdateProductInput
+	doc, err := json.Marshal(productInput(product))
+	if err != nil {
+		return input, err
+	}
+	if mediaType == patch.MergePatchType {
+		doc, err = patch.Merge(doc, body)
+	} else {
+		doc, err = patch.Apply(doc, body)
+	}
+	if err != nil {
+		return input, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&input); err != nil {
+		return input, fmt.Errorf("%w: %v", errInvalidPatchResult, err)
+	}
+	if input.CategoryIDs == nil {
+		input.CategoryIDs = []string{}
+	}
+	if input.Tags == nil {
+		input.Tags = []string{}
+	}
+	if input.Attributes == nil {
+		input.Attributes = map[string]interface{}{}
+	}
+	return input, nil
+}
+
+func patchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, patch.ErrInvalidPatch):
+		return http.StatusBadRequest
+	case errors.Is(err, patch.ErrPathNotFound), errors.Is(err, errInvalidPatchResult):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, patch.ErrTestFailed):
+		return http.StatusConflict
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/chi/v5"
+)
+
+type PriceHandler struct {
+	ProductDB        database.ProductInterface
+	PriceChangeDB    database.PriceChangeInterface
+	ScheduledPriceDB database.ScheduledPriceInterface
+}
+
+func NewPriceHandler(productDB database.ProductInterface, priceChangeDB database.PriceChangeInterface, scheduledPriceDB database.ScheduledPriceInterface) *PriceHandler {
+	return &PriceHandler{
+		ProductDB:        productDB,
+		PriceChangeDB:    priceChangeDB,
+		ScheduledPriceDB: scheduledPriceDB,
+	}
+}
+
+// List price history godoc
+// @Summary     List price history
+// @Description List the price changes of a product, newest first
+// @Tags        prices
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true     "Product Id"
+// @Param       page          query       string   false    "Page number"
+// @Param       limit         query       string   false    "Changes per page"
+// @Success     200           {array}     entity.PriceChange
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/prices    [get]
+// @Security    ApiKeyAuth
+func (handler *PriceHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	if _, err := findVisibleProduct(r, handler.ProductDB, chi.URLParam(r, "id")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	changes, err := handler.PriceChangeDB.FindByProductId(chi.URLParam(r, "id"), page, limit)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(changes)
+}
+
+// Schedule price godoc
+// @Summary     Schedule price
+// @Description Schedule a price for the product from effective_from. When effective_until is set the previous price is restored then.
+// @Tags        prices
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string                          true    "Product Id"
+// @Param       request       body        dto.CreateScheduledPriceInput   true    "Scheduled price"
+// @Success     201           {object}    entity.ScheduledPrice
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/scheduled-prices    [post]
+// @Security    ApiKeyAuth
+func (handler *PriceHandler) CreateScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	var createScheduledPriceInput dto.CreateScheduledPriceInput
+	if err := json.NewDecoder(r.Body).Decode(&createScheduledPriceInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	scheduledPrice, err := entity.NewScheduledPrice(
+		product,
+		createScheduledPriceInput.Price,
+		createScheduledPriceInput.EffectiveFrom,
+		createScheduledPriceInput.EffectiveUntil,
+		actor(r),
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.ScheduledPriceDB.Create(scheduledPrice); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, entity.ErrScheduleOverlaps) {
+			status = http.StatusConflict
+		}
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scheduledPrice)
+}
+
+// List scheduled prices godoc
+// @Summary     List scheduled prices
+// @Description List the scheduled prices of a product by start date
+// @Tags        prices
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true     "Product Id"
+// @Success     200           {array}     entity.ScheduledPrice
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/scheduled-prices    [get]
+// @Security    ApiKeyAuth
+func (handler *PriceHandler) GetScheduledPrices(w http.ResponseWriter, r *http.Request) {
+	if _, err := findVisibleProduct(r, handler.ProductDB, chi.URLParam(r, "id")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	scheduled, err := handler.ScheduledPriceDB.FindByProductId(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scheduled)
+}
+
+// Cancel scheduled price godoc
+// @Summary     Cancel scheduled price
+// @Description Cancel a scheduled price that didn't take effect yet
+// @Tags        prices
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Param       schedule_id   path        string   true    "Scheduled price Id"
+// @Success     200           {object}    entity.ScheduledPrice
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/scheduled-prices/{schedule_id}    [delete]
+// @Security    ApiKeyAuth
+func (handler *PriceHandler) CancelScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	scheduledPrice, err := handler.ScheduledPriceDB.FindById(chi.URLParam(r, "scheduleId"))
+	if err != nil || scheduledPrice.ProductID.String() != chi.URLParam(r, "id") {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: "scheduled price not found"}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = scheduledPrice.Cancel(); err != nil {
+		w.WriteHeader(http.StatusConflict)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.ScheduledPriceDB.Update(scheduledPrice); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scheduledPrice)
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"gorm.io/gorm"
+)
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+
+	batchCreate = "create"
+	batchUpdate = "update"
+	batchDelete = "delete"
+
+	// maxBatchOperations limits how many operations a single batch runs.
+	maxBatchOperations = 1000
+)
+
+var (
+	errInvalidBatchMode      = errors.New("mode must be atomic or best_effort")
+	errInvalidBatchOperation = errors.New("op must be create, update or delete")
+	errBatchTooLarge         = errors.New("batch can have up to 1000 operations")
+	errBatchProductRequired  = errors.New("product is required")
+	errBatchAborted          = errors.New("not applied because another operation of the batch failed")
+	errBatchDuplicateID      = errors.New("a product can only be updated or deleted once in an atomic batch")
+)
+
+// batchItem is an operation of a batch along with the product it creates
+// or updates and how it went.
+type batchItem struct {
+	op      dto.ProductBatchOperation
+	product *entity.Product
+	result  dto.ProductBatchResult
+}
+
+func (item *batchItem) fail(status int, err error) {
+	item.result.Status = status
+	item.result.Version = 0
+	item.result.Error = err.Error()
+}
+
+func (item *batchItem) failed() bool {
+	return item.result.Error != ""
+}
+
+func (item *batchItem) succeed(status int) {
+	item.result.Status = status
+	if item.product != nil {
+		item.result.Version = item.product.Version
+	}
+}
+
+// Batch products godoc
+// @Summary     Batch create, update and delete products
+// @Description Run up to 1000 create, update and delete operations. In atomic mode (the default) either every operation is applied or none is, and the
+// @Description response has the status of the first operation that failed. In best_effort mode each operation is applied on its own.
+// @Description Updates replace the product as PUT does, and the version of an operation plays the part of If-Match.
+// @Description An atomic batch can only update or delete each product once.
+// @Tags        products
+// @Accept      json
+// @Produce     json
+// @Param       request       body        dto.ProductBatchInput    true    "Operations to run"
+// @Success     200           {object}    dto.ProductBatchOutput
+// @Failure     400           {object}    dto.ProductBatchOutput
+// @Failure     404           {object}    dto.ProductBatchOutput
+// @Failure     412           {object}    dto.ProductBatchOutput
+// @Failure     413           {object}    Error
+// @Failure     428           {object}    dto.ProductBatchOutput
+// @Failure     500           {object}    dto.ProductBatchOutput
+// @Router      /product/batch   [post]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	var batchInput dto.ProductBatchInput
+	if err := json.NewDecoder(r.Body).Decode(&batchInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if batchInput.Mode == "" {
+		batchInput.Mode = batchAtomic
+	}
+	if batchInput.Mode != batchAtomic && batchInput.Mode != batchBestEffort {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: errInvalidBatchMode.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if len(batchInput.Operations) > maxBatchOperations {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		errorObj := Error{Message: errBatchTooLarge.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	items := make([]*batchItem, len(batchInput.Operations))
+	for i, op := range batchInput.Operations {
+		items[i] = handler.prepareBatchItem(r, i, op)
+	}
+	if batchInput.Mode == batchAtomic {
+		rejectDuplicateBatchIds(items)
+		handler.runAtomicBatch(r, items)
+	} else {
+		handler.runBestEffortBatch(r, items)
+	}
+	output := dto.ProductBatchOutput{Mode: batchInput.Mode, Results: make([]dto.ProductBatchResult, len(items))}
+	status := http.StatusOK
+	for i, item := range items {
+		output.Results[i] = item.result
+		if !item.failed() {
+			output.Succeeded++
+			continue
+		}
+		output.Failed++
+		if batchInput.Mode == batchAtomic && status == http.StatusOK && item.result.Status != http.StatusFailedDependency {
+			status = item.result.Status
+		}
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(output)
+}
+
+// prepareBatchItem checks an operation and builds the product it creates or
+// updates, without changing anything yet.
+func (handler *ProductHandler) prepareBatchItem(r *http.Request, index int, op dto.ProductBatchOperation) *batchItem {
+	item := &batchItem{op: op, result: dto.ProductBatchResult{Index: index, Op: op.Op, ID: op.ID}}
+	switch op.Op {
+	case batchCreate:
+		if op.Product == nil {
+			item.fail(http.StatusBadRequest, errBatchProductRequired)
+			return item
+		}
+		product, err := entity.NewProduct(op.Product.Name, op.Product.Price)
+		if err != nil {
+			item.fail(http.StatusBadRequest, err)
+			return item
+		}
+		if status, err := handler.applyProductInput(product, *op.Product); err != nil {
+			item.fail(status, err)
+			return item
+		}
+		product.UpdatedBy = actor(r)
+		item.product = product
+		item.result.ID = product.ID.String()
+	case batchUpdate:
+		if _, err := pkgEntity.ParseID(op.ID); err != nil {
+			item.fail(http.StatusBadRequest, err)
+			return item
+		}
+		if op.Product == nil {
+			item.fail(http.StatusBadRequest, errBatchProductRequired)
+			return item
+		}
+		if op.Version == 0 && handler.IfMatchRequired {
+			item.fail(http.StatusPreconditionRequired, errPreconditionRequired)
+			return item
+		}
+		product, err := handler.ProductDB.FindById(op.ID)
+		if err != nil {
+			item.fail(batchErrorStatus(err), err)
+			return item
+		}
+		if op.Version != 0 && op.Version != product.Version {
+			item.fail(http.StatusPreconditionFailed, errPreconditionFailed)
+			return item
+		}
+		if status, err := handler.applyProductInput(product, *op.Product); err != nil {
+			item.fail(status, err)
+			return item
+		}
+		product.UpdatedBy = actor(r)
+		item.product = product
+	case batchDelete:
+		if _, err := pkgEntity.ParseID(op.ID); err != nil {
+			item.fail(http.StatusBadRequest, err)
+			return item
+		}
+		if op.Version == 0 && handler.IfMatchRequired {
+			item.fail(http.StatusPreconditionRequired, errPreconditionRequired)
+			return item
+		}
+	default:
+		item.fail(http.StatusBadRequest, errInvalidBatchOperation)
+	}
+	return item
+}
+
+// runAtomicBatch applies every operation in a single transaction, or none
+// of them when any fails.
+func (handler *ProductHandler) runAtomicBatch(r *http.Request, items []*batchItem) {
+	if anyBatchItemFailed(items) {
+		abortBatch(items)
+		return
+	}
+	err := handler.ProductDB.As(auditSource(r)).Transaction(func(tx database.ProductInterface) error {
+		creates := batchCreates(items)
+		if err := tx.CreateMany(batchProducts(creates)); err != nil {
+			log.Println(err)
+			for _, item := range creates {
+				item.fail(http.StatusInternalServerError, err)
+			}
+			return err
+		}
+		for _, item := range creates {
+			item.succeed(http.StatusCreated)
+		}
+		for _, item := range items {
+			if item.op.Op != batchCreate {
+				if err := handler.runBatchItem(r, tx, item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		abortBatch(items)
+	}
+}
+
+// runBestEffortBatch applies each operation that could be prepared on its
+// own. Products are still created in batches, falling back to one at a
+// time only when a batch fails.
+func (handler *ProductHandler) runBestEffortBatch(r *http.Request, items []*batchItem) {
+	db := handler.ProductDB.As(auditSource(r))
+	creates := batchCreates(items)
+	if err := db.CreateMany(batchProducts(creates)); err != nil {
+		for _, item := range creates {
+			handler.runBatchItem(r, db, item)
+		}
+	} else {
+		for _, item := range creates {
+			item.succeed(http.StatusCreated)
+		}
+	}
+	for _, item := range items {
+		if item.op.Op != batchCreate && !item.failed() {
+			handler.runBatchItem(r, db, item)
+		}
+	}
+}
+
+// runBatchItem applies a single operation that was prepared, recording how
+// it went.
+func (handler *ProductHandler) runBatchItem(r *http.Request, db database.ProductInterface, item *batchItem) error {
+	var err error
+	status := http.StatusOK
+	switch item.op.Op {
+	case batchCreate:
+		err = db.Create(item.product)
+		status = http.StatusCreated
+	case batchUpdate:
+		err = db.Update(item.product)
+	case batchDelete:
+		err = db.Delete(item.op.ID, actor(r), item.op.Version)
+	}
+	if err != nil {
+		item.fail(batchErrorStatus(err), err)
+		return err
+	}
+	item.succeed(status)
+	return nil
+}
+
+func batchCreates(items []*batchItem) []*batchItem {
+	var creates []*batchItem
+	for _, item := range items {
+		if item.op.Op == batchCreate && !item.failed() {
+			creates = append(creates, item)
+		}
+	}
+	return creates
+}
+
+func batchProducts(items []*batchItem) []*entity.Product {
+	products := make([]*entity.Product, len(items))
+	for i, item := range items {
+		products[i] = item.product
+	}
+	return products
+}
+
+// rejectDuplicateBatchIds fails the operations on a product an earlier
+// operation of the batch already changes. Within a transaction the second
+// one would always find a newer version than it was sent.
+func rejectDuplicateBatchIds(items []*batchItem) {
+	seen := map[string]bool{}
+	for _, item := range items {
+		if item.op.Op == batchCreate || item.op.ID == "" {
+			continue
+		}
+		if seen[item.op.ID] {
+			item.fail(http.StatusBadRequest, errBatchDuplicateID)
+		}
+		seen[item.op.ID] = true
+	}
+}
+
+func anyBatchItemFailed(items []*batchItem) bool {
+	for _, item := range items {
+		if item.failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// abortBatch marks the operations that didn't fail themselves as not
+// applied because of the others.
+func abortBatch(items []*batchItem) {
+	for _, item := range items {
+		if !item.failed() {
+			item.fail(http.StatusFailedDependency, errBatchAborted)
+		}
+	}
+}
+
+func batchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, database.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
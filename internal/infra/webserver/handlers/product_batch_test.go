@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func createBatchTestHandler(t *testing.T) (*ProductHandler, *database.Product, *entity.Product) {
+	db := createTestDB(t)
+	productDB := database.NewProduct(db)
+	handler := NewProductHandler(productDB, database.NewCategory(db), database.NewExchangeRate(db), database.NewAttributeDefinition(db), nil, false)
+	mouse, _ := entity.NewProduct("Mouse", pkgEntity.Money{Amount: 3999, Currency: "BRL"})
+	assert.NoError(t, productDB.Create(mouse))
+	return handler, productDB, mouse
+}
+
+func serveBatch(t *testing.T, handler *ProductHandler, body string) (int, dto.ProductBatchOutput) {
+	res := serve(http.HandlerFunc(handler.Batch), "POST", "/product/batch", body)
+	var output dto.ProductBatchOutput
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+	return res.Code, output
+}
+
+func batchStatuses(output dto.ProductBatchOutput) []int {
+	statuses := make([]int, len(output.Results))
+	for i, result := range output.Results {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+func TestBatch_Atomic(t *testing.T) {
+	handler, productDB, mouse := createBatchTestHandler(t)
+	code, output := serveBatch(t, handler, fmt.Sprintf(`{"operations": [
+		{"op": "create", "product": {"name": "Keyboard", "price": {"amount": "99.90", "currency": "BRL"}}},
+		{"op": "update", "id": %q, "version": %d, "product": {"name": "Wireless mouse", "price": {"amount": "49.99", "currency": "BRL"}}}
+	]}`, mouse.ID, mouse.Version))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{http.StatusCreated, http.StatusOK}, batchStatuses(output))
+	assert.Equal(t, 2, output.Succeeded)
+	saved, _ := productDB.FindById(mouse.ID.String())
+	assert.Equal(t, "Wireless mouse", saved.Name)
+
+	// A stale version rolls back every operation.
+	code, output = serveBatch(t, handler, fmt.Sprintf(`{"operations": [
+		{"op": "create", "product": {"name": "Headset", "price": {"amount": "199.90", "currency": "BRL"}}},
+		{"op": "delete", "id": %q, "version": %d}
+	]}`, mouse.ID, mouse.Version))
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	assert.Equal(t, []int{http.StatusFailedDependency, http.StatusPreconditionFailed}, batchStatuses(output))
+	_, err := productDB.FindById(output.Results[0].ID)
+	assert.Error(t, err)
+	_, err = productDB.FindById(mouse.ID.String())
+	assert.NoError(t, err)
+}
+
+func TestBatch_AtomicDuplicateIds(t *testing.T) {
+	handler, productDB, mouse := createBatchTestHandler(t)
+	code, output := serveBatch(t, handler, fmt.Sprintf(`{"operations": [
+		{"op": "update", "id": %q, "product": {"name": "Wireless mouse", "price": {"amount": "49.99", "currency": "BRL"}}},
+		{"op": "delete", "id": %q}
+	]}`, mouse.ID, mouse.ID))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, []int{http.StatusFailedDependency, http.StatusBadRequest}, batchStatuses(output))
+	assert.Equal(t, errBatchDuplicateID.Error(), output.Results[1].Error)
+	saved, _ := productDB.FindById(mouse.ID.String())
+	assert.Equal(t, "Mouse", saved.Name)
+}
+
+func TestBatch_BestEffort(t *testing.T) {
+	handler, productDB, mouse := createBatchTestHandler(t)
+	code, output := serveBatch(t, handler, fmt.Sprintf(`{"mode": "best_effort", "operations": [
+		{"op": "create", "product": {"name": "Keyboard", "price": {"amount": "99.90", "currency": "BRL"}}},
+		{"op": "update", "id": %q, "product": {"name": "Headset", "price": {"amount": "199.90", "currency": "BRL"}}},
+		{"op": "delete", "id": %q},
+		{"op": "rename"}
+	]}`, pkgEntity.NewID(), mouse.ID))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{http.StatusCreated, http.StatusNotFound, http.StatusOK, http.StatusBadRequest}, batchStatuses(output))
+	assert.Equal(t, 2, output.Succeeded)
+	assert.Equal(t, 2, output.Failed)
+	_, err := productDB.FindById(output.Results[0].ID)
+	assert.NoError(t, err)
+	_, err = productDB.FindById(mouse.ID.String())
+	assert.Error(t, err)
+}
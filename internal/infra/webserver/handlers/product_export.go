@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/exporter"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/spreadsheet"
+)
+
+var errNotAcceptable = errors.New("products can only be exported as text/csv, application/x-ndjson or " + spreadsheet.XLSXType)
+
+// Export products godoc
+// @Summary     Export products
+// @Description Download every product that matches the filters of the listing, oldest first, as CSV, NDJSON (one product per line) or XLSX.
+// @Description The format is picked by the format parameter or else the Accept header, CSV by default. Viewers only get published products.
+// @Tags        products
+// @Produce     text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param       format        query       string   false    "csv, ndjson or xlsx, takes precedence over Accept"
+// @Param       status        query       []string false    "Only products in these statuses (draft, published or archived), ignored for viewers" collectionFormat(multi)
+// @Param       category      query       string   false    "Only products in this category"
+// @Param       include_descendants  query  bool   false    "Also match products in subcategories of category"
+// @Param       tag           query       []string false    "Only products with these tags" collectionFormat(multi)
+// @Param       tag_match     query       string   false    "any (default) or all of the tags"
+// @Param       in_stock      query       bool     false    "Only products with (true) or without (false) available stock"
+// @Param       low_stock     query       bool     false    "Only products at or below their low stock threshold"
+// @Param       attr.{code}   query       string   false    "Only products whose attribute has this value, repeat for any of several values"
+// @Success     200           {file}      file
+// @Header      200           {string}    Content-Disposition    "attachment; filename=products-<time>.<format>"
+// @Failure     400           {object}    Error
+// @Failure     406           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/export   [get]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errNotAcceptable) {
+			status = http.StatusNotAcceptable
+		}
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	filter, err := handler.parseProductFilter(r)
+	if err != nil {
+		w.WriteHeader(attributeErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if filter.Statuses, err = productStatuses(r); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	filename := "products-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	w.Header().Set("Content-Type", exporter.ContentTypes[format])
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Add("Vary", "Accept")
+	e, err := exporter.New(w, format, handler.Storage)
+	if err != nil {
+		abortExport(err)
+	}
+	err = exporter.Export(handler.ProductDB, filter, e, func(int) error {
+		// Send each batch to the client as soon as it is written.
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		abortExport(err)
+	}
+}
+
+// abortExport drops the connection when an export fails halfway, so the
+// client can tell the file it got is incomplete: the status was already
+// sent.
+func abortExport(err error) {
+	log.Println(err)
+	panic(http.ErrAbortHandler)
+}
+
+// exportFormat picks the format from the format parameter or else the first
+// supported type of the Accept header.
+func exportFormat(r *http.Request) (string, error) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		if _, ok := exporter.ContentTypes[format]; !ok {
+			return "", exporter.ErrInvalidFormat
+		}
+		return format, nil
+	}
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return exporter.CSV, nil
+	}
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "*/*", "text/*":
+			return exporter.CSV, nil
+		}
+		for format, contentType := range exporter.ContentTypes {
+			if mediaType == contentType {
+				return format, nil
+			}
+		}
+	}
+	return "", errNotAcceptable
+}
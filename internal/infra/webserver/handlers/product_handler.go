@@ -2,24 +2,54 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
 	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
 	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/webserver/middlewares"
 	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/pkg/patch"
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 type ProductHandler struct {
-	ProductDB database.ProductInterface
+	ProductDB             database.ProductInterface
+	CategoryDB            database.CategoryInterface
+	ExchangeRateDB        database.ExchangeRateInterface
+	AttributeDefinitionDB database.AttributeDefinitionInterface
+	Storage               storage.Storage
+	// IfMatchRequired makes updates and deletes fail without an If-Match
+	// header instead of overwriting whatever version is current.
+	IfMatchRequired bool
 }
 
-func NewProductHandler(db database.ProductInterface) *ProductHandler {
+func NewProductHandler(
+	db database.ProductInterface,
+	categoryDB database.CategoryInterface,
+	exchangeRateDB database.ExchangeRateInterface,
+	attributeDefinitionDB database.AttributeDefinitionInterface,
+	store storage.Storage,
+	ifMatchRequired bool,
+) *ProductHandler {
 	return &ProductHandler{
-		ProductDB: db,
+		ProductDB:             db,
+		CategoryDB:            categoryDB,
+		ExchangeRateDB:        exchangeRateDB,
+		AttributeDefinitionDB: attributeDefinitionDB,
+		Storage:               store,
+		IfMatchRequired:       ifMatchRequired,
 	}
 }
 
@@ -53,13 +83,43 @@ func (handler *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
-	if err = handler.ProductDB.Create(product); err != nil {
+	if createProductInput.LowStockThreshold != nil {
+		product.LowStockThreshold = *createProductInput.LowStockThreshold
+		if err = product.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			errorObj := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(errorObj)
+			return
+		}
+	}
+	if product.Categories, err = handler.CategoryDB.FindByIds(createProductInput.CategoryIDs); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: "category not found"}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if product.Tags, err = entity.NormalizeTags(createProductInput.Tags); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if product.Attributes, err = handler.attributeValues(product, createProductInput.Attributes); err != nil {
+		w.WriteHeader(attributeErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product.UpdatedBy = actor(r)
+	if err = handler.ProductDB.As(auditSource(r)).Create(product); err != nil {
 		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		errorObj := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
 	w.WriteHeader(http.StatusCreated)
 	if err = json.NewEncoder(w).Encode(&product); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -70,14 +130,18 @@ func (handler *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 // Get product godoc
 // @Summary     Get product
-// @Description Get product by id
+// @Description Get product by id. Viewers only get published products.
 // @Tags        products
 // @Accept      json
 // @Produce     json
 // @Param       product_id    path        string                   true    "Product Id"
+// @Param       currency      query       string                   false   "Currency of display_price, takes precedence over Accept-Currency"
+// @Param       Accept-Currency  header   string                   false   "Currency of display_price"
 // @Success     200           {object}    entity.Product
+// @Header      200           {string}    ETag    "Version of the product, to be sent in If-Match when updating it"
 // @Failure     400           {object}    Error
 // @Failure     404           {object}    Error
+// @Failure     422           {object}    Error
 // @Failure     500           {object}    Error
 // @Router      /product/{product_id}     [get]
 // @Security    ApiKeyAuth
@@ -90,7 +154,15 @@ func (handler *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
-	product, err := handler.ProductDB.FindById(id)
+	w.Header().Add("Vary", "Accept-Currency")
+	currency, err := requestedCurrency(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := findVisibleProduct(r, handler.ProductDB, id)
 	if err != nil {
 		log.Println(err)
 		w.WriteHeader(http.StatusNotFound)
@@ -98,6 +170,14 @@ func (handler *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
+	if err = handler.setDisplayPrice(product, currency, map[string]*entity.ExchangeRate{}); err != nil {
+		w.WriteHeader(displayPriceStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
 	w.WriteHeader(http.StatusOK)
 	if err = json.NewEncoder(w).Encode(&product); err != nil {
 		log.Println(err)
@@ -115,9 +195,14 @@ func (handler *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request
 // @Produce     json
 // @Param       product_id    path        string                   true    "Product Id to be updated"
 // @Param       payload       body        dto.UpdateProductInput   true    "Product payload"
+// @Param       If-Match      header      string                   false   "ETag of the product being updated, required unless IF_MATCH_OPTIONAL is set"
 // @Success     200           {object}    entity.Product
+// @Header      200           {string}    ETag    "Version of the product"
 // @Failure     400           {object}    Error
 // @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     412           {object}    Error
+// @Failure     428           {object}    Error
 // @Failure     500           {object}    Error
 // @Router      /product/{product_id}     [put]
 // @Security    ApiKeyAuth
@@ -154,17 +239,145 @@ func (handler *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Requ
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
+	if err = checkIfMatch(r, product, handler.IfMatchRequired); err != nil {
+		w.WriteHeader(preconditionStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
 	product.ID = productID
-	product.Name = updateProductInput.Name
-	product.Price = updateProductInput.Price
-	if err = handler.ProductDB.Update(product); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if status, err := handler.applyProductInput(product, updateProductInput); err != nil {
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.saveProduct(w, r, product)
+}
+
+// Patch product godoc
+// @Summary     Patch product
+// @Description Change some fields of a product with a JSON Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json).
+// @Description The patch applies to the fields of dto.UpdateProductInput. Removing category_ids, tags or attributes clears them.
+// @Tags        products
+// @Accept      application/merge-patch+json,application/json-patch+json
+// @Produce     json
+// @Param       product_id    path        string                   true    "Product Id to be patched"
+// @Param       payload       body        object                   true    "Merge patch or JSON Patch operations"
+// @Param       If-Match      header      string                   false   "ETag of the product being updated, required unless IF_MATCH_OPTIONAL is set"
+// @Success     200           {object}    entity.Product
+// @Header      200           {string}    ETag    "Version of the product"
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     412           {object}    Error
+// @Failure     415           {object}    Error
+// @Failure     422           {object}    Error
+// @Failure     428           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}     [patch]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := pkgEntity.ParseID(id); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != patch.MergePatchType && mediaType != patch.JSONPatchType {
+		w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		errorObj := Error{Message: errUnsupportedPatchType.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = checkIfMatch(r, product, handler.IfMatchRequired); err != nil {
+		w.WriteHeader(preconditionStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	patchedInput, err := patchProductInput(product, mediaType, body)
+	if err != nil {
+		w.WriteHeader(patchErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if status, err := handler.applyProductInput(product, patchedInput); err != nil {
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.saveProduct(w, r, product)
+}
+
+// applyProductInput changes the product to what the client sent, checking
+// the result. Categories, tags and attributes are only replaced when they
+// are sent. It returns the status to respond with when it fails.
+func (handler *ProductHandler) applyProductInput(product *entity.Product, input dto.UpdateProductInput) (int, error) {
+	var err error
+	product.Name = input.Name
+	product.Price = input.Price
+	if input.LowStockThreshold != nil {
+		product.LowStockThreshold = *input.LowStockThreshold
+	}
+	if err = product.Validate(); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if input.CategoryIDs != nil {
+		if product.Categories, err = handler.CategoryDB.FindByIds(input.CategoryIDs); err != nil {
+			return http.StatusBadRequest, errors.New("category not found")
+		}
+	}
+	if input.Tags != nil {
+		if product.Tags, err = entity.NormalizeTags(input.Tags); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if input.Attributes != nil {
+		if product.Attributes, err = handler.attributeValues(product, input.Attributes); err != nil {
+			return attributeErrorStatus(err), err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// saveProduct stores the changes made to the product and responds with it.
+func (handler *ProductHandler) saveProduct(w http.ResponseWriter, r *http.Request, product *entity.Product) {
+	product.UpdatedBy = actor(r)
+	if err := handler.ProductDB.As(auditSource(r)).Update(product); err != nil {
+		status := updateErrorStatus(err)
+		if errors.Is(err, database.ErrVersionConflict) {
+			// Someone else saved the product after the If-Match check.
+			status = http.StatusPreconditionFailed
+		}
+		w.WriteHeader(status)
 		errorObj := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
 	w.WriteHeader(http.StatusOK)
-	if err = json.NewEncoder(w).Encode(product); err != nil {
+	if err := json.NewEncoder(w).Encode(product); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		errorObj := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(errorObj)
@@ -173,13 +386,17 @@ func (handler *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Requ
 
 // Delete product godoc
 // @Summary     Delete products
-// @Description Delete products by id
+// @Description Move a product to the trash, from where it can be restored until it is purged
 // @Tags        products
 // @Accept      json
 // @Produce     json
 // @Param       product_id    path       string   true    "Product Id to be deleted"
+// @Param       If-Match      header     string   false   "ETag of the product being deleted, required unless IF_MATCH_OPTIONAL is set"
 // @Success     200
 // @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     412           {object}    Error
+// @Failure     428           {object}    Error
 // @Failure     500           {object}    Error
 // @Router      /product/{product_id}   [delete]
 // @Security    ApiKeyAuth
@@ -192,25 +409,130 @@ func (handler *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Requ
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
-	if err := handler.ProductDB.Delete(id); err != nil {
+	product, err := handler.ProductDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = checkIfMatch(r, product, handler.IfMatchRequired); err != nil {
+		w.WriteHeader(preconditionStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	// Without If-Match whatever version is current gets deleted.
+	version := 0
+	if r.Header.Get("If-Match") != "" {
+		version = product.Version
+	}
+	if err = handler.ProductDB.As(auditSource(r)).Delete(id, actor(r), version); err != nil {
+		status := trashErrorStatus(err)
+		if errors.Is(err, database.ErrVersionConflict) {
+			status = http.StatusPreconditionFailed
+		}
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Restore product godoc
+// @Summary     Restore product
+// @Description Take a deleted product out of the trash
+// @Tags        products
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Success     200           {object}    entity.Product
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/restore    [post]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := handler.ProductDB.As(auditSource(r)).Restore(id); err != nil {
+		w.WriteHeader(trashErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(id)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// List trash godoc
+// @Summary     List trash
+// @Description List the deleted products waiting to be purged, most recently deleted first
+// @Tags        products
+// @Accept      json
+// @Produce     json
+// @Param       page          query       string   false    "Page number"
+// @Param       limit         query       string   false    "Products per page"
+// @Success     200           {array}     entity.Product
+// @Failure     500           {object}    Error
+// @Router      /admin/trash    [get]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	products, err := handler.ProductDB.FindDeleted(page, limit)
+	if err != nil {
 		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		errorObj := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
+	for i := range products {
+		storage.SetImageURLs(handler.Storage, products[i].Images)
+	}
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(products)
 }
 
 // List products godoc
 // @Summary     List products
-// @Description List all products with support for pagination
+// @Description List products with support for pagination. Viewers only get published products while editors get products in any status.
 // @Tags        products
 // @Accept      json
 // @Produce     json
 // @Param       page          query       string   false    "Page number"
 // @Param       limit         query       string   false    "Products per page"
+// @Param       sort          query       string   false    "Sort by creation date (asc or desc)"
+// @Param       status        query       []string false    "Only products in these statuses (draft, published or archived), ignored for viewers" collectionFormat(multi)
+// @Param       category      query       string   false    "Only products in this category"
+// @Param       include_descendants  query  bool   false    "Also match products in subcategories of category"
+// @Param       tag           query       []string false    "Only products with these tags" collectionFormat(multi)
+// @Param       tag_match     query       string   false    "any (default) or all of the tags"
+// @Param       in_stock      query       bool     false    "Only products with (true) or without (false) available stock"
+// @Param       low_stock     query       bool     false    "Only products at or below their low stock threshold"
+// @Param       attr.{code}   query       string   false    "Only products whose attribute has this value, repeat for any of several values"
+// @Param       attr.{code}.min  query    number   false    "Only products whose number attribute is at least this value"
+// @Param       attr.{code}.max  query    number   false    "Only products whose number attribute is at most this value"
+// @Param       currency      query       string   false    "Currency of display_price, takes precedence over Accept-Currency. Products with no price or exchange rate in it are listed without display_price"
+// @Param       Accept-Currency  header   string   false    "Currency of display_price"
 // @Success     200           {array}    entity.Product
+// @Failure     400           {object}    Error
 // @Failure     500           {object}    Error
 // @Router      /product   [get]
 // @Security    ApiKeyAuth
@@ -224,14 +546,48 @@ func (handler *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Reques
 		limit = 0
 	}
 	sort := r.URL.Query().Get("sort")
+	filter, err := handler.parseProductFilter(r)
+	if err != nil {
+		w.WriteHeader(attributeErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if filter.Statuses, err = productStatuses(r); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Currency")
+	currency, err := requestedCurrency(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
 
-	product, err := handler.ProductDB.FindAll(page, limit, sort)
+	product, err := handler.ProductDB.FindAll(page, limit, sort, filter)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		errorObj := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
+	rates := map[string]*entity.ExchangeRate{}
+	for i := range product {
+		// One product the price can't be converted for doesn't fail the page:
+		// it is listed without display_price.
+		err = handler.setDisplayPrice(&product[i], currency, rates)
+		if err != nil && !errors.Is(err, entity.ErrNoExchangeRate) {
+			w.WriteHeader(displayPriceStatus(err))
+			errorObj := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(errorObj)
+			return
+		}
+		storage.SetImageURLs(handler.Storage, product[i].Images)
+	}
 	w.WriteHeader(http.StatusOK)
 	if err = json.NewEncoder(w).Encode(product); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -239,3 +595,405 @@ func (handler *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Reques
 		json.NewEncoder(w).Encode(errorObj)
 	}
 }
+
+// Add product tags godoc
+// @Summary     Add product tags
+// @Description Add tags to a product. Tags are normalized and the ones the product already has are ignored.
+// @Tags        products
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string                  true    "Product Id"
+// @Param       request       body        dto.ProductTagsInput    true    "Tags to add"
+// @Success     200           {object}    entity.Product
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/tags    [post]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) AddTags(w http.ResponseWriter, r *http.Request) {
+	var productTagsInput dto.ProductTagsInput
+	if err := json.NewDecoder(r.Body).Decode(&productTagsInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	tags, err := entity.NormalizeTags(productTagsInput.Tags)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product.AddTags(tags)
+	product.UpdatedBy = actor(r)
+	if err = handler.ProductDB.As(auditSource(r)).Update(product); err != nil {
+		w.WriteHeader(updateErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// Remove product tag godoc
+// @Summary     Remove product tag
+// @Description Remove a tag from a product
+// @Tags        products
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Param       tag           path        string   true    "Tag to remove"
+// @Success     200           {object}    entity.Product
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/tags/{tag}    [delete]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) RemoveTag(w http.ResponseWriter, r *http.Request) {
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	tag, err := entity.NormalizeTag(chi.URLParam(r, "tag"))
+	if err != nil || !product.RemoveTag(tag) {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: "product doesn't have this tag"}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product.UpdatedBy = actor(r)
+	if err = handler.ProductDB.As(auditSource(r)).Update(product); err != nil {
+		w.WriteHeader(updateErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// Set product price godoc
+// @Summary     Set product price
+// @Description Set the price of a product in a currency other than its base currency
+// @Tags        products
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string                     true    "Product Id"
+// @Param       currency      path        string                     true    "ISO 4217 currency code"
+// @Param       request       body        dto.SetProductPriceInput   true    "Price in the currency"
+// @Success     200           {object}    entity.Product
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/prices/{currency}    [put]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) SetPrice(w http.ResponseWriter, r *http.Request) {
+	var setProductPriceInput dto.SetProductPriceInput
+	if err := json.NewDecoder(r.Body).Decode(&setProductPriceInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	price, err := pkgEntity.ParseMoney(setProductPriceInput.Amount, strings.ToUpper(chi.URLParam(r, "currency")))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = product.SetPriceOverride(price); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product.UpdatedBy = actor(r)
+	if err = handler.ProductDB.As(auditSource(r)).Update(product); err != nil {
+		w.WriteHeader(updateErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// Delete product price godoc
+// @Summary     Delete product price
+// @Description Remove the price of a product in a currency, which then falls back to converting the base price
+// @Tags        products
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Param       currency      path        string   true    "ISO 4217 currency code"
+// @Success     200           {object}    entity.Product
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/prices/{currency}    [delete]
+// @Security    ApiKeyAuth
+func (handler *ProductHandler) DeletePrice(w http.ResponseWriter, r *http.Request) {
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if !product.RemovePriceOverride(strings.ToUpper(chi.URLParam(r, "currency"))) {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: "product doesn't have a price in this currency"}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product.UpdatedBy = actor(r)
+	if err = handler.ProductDB.As(auditSource(r)).Update(product); err != nil {
+		w.WriteHeader(updateErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// setDisplayPrice sets the price of the product in currency, looking up the
+// exchange rate when the product has no price of its own in it. rates
+// caches the rates found by base currency.
+func (handler *ProductHandler) setDisplayPrice(product *entity.Product, currency string, rates map[string]*entity.ExchangeRate) error {
+	if currency == "" {
+		return nil
+	}
+	price, err := product.PriceIn(currency, nil)
+	if errors.Is(err, entity.ErrNoExchangeRate) {
+		rate, ok := rates[product.Price.Currency]
+		if !ok {
+			rate, err = handler.ExchangeRateDB.Find(product.Price.Currency, currency)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			rates[product.Price.Currency] = rate
+		}
+		price, err = product.PriceIn(currency, rate)
+	}
+	if err != nil {
+		return err
+	}
+	product.DisplayPrice = &price
+	return nil
+}
+
+func updateErrorStatus(err error) int {
+	if errors.Is(err, database.ErrVersionConflict) {
+		return http.StatusConflict
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
+
+func trashErrorStatus(err error) int {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
+
+func displayPriceStatus(err error) int {
+	if errors.Is(err, entity.ErrNoExchangeRate) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// requestedCurrency reads the currency prices should be displayed in from
+// the currency query parameter or else the Accept-Currency header, which
+// may list several currencies in order of preference. It is empty when
+// neither is sent.
+func requestedCurrency(r *http.Request) (string, error) {
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		currency = strings.ToUpper(currency)
+		if !pkgEntity.IsValidCurrency(currency) {
+			return "", pkgEntity.ErrInvalidCurrency
+		}
+		return currency, nil
+	}
+	header := r.Header.Get("Accept-Currency")
+	if header == "" {
+		return "", nil
+	}
+	for _, value := range strings.Split(header, ",") {
+		currency, _, _ := strings.Cut(value, ";")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if pkgEntity.IsValidCurrency(currency) {
+			return currency, nil
+		}
+	}
+	return "", pkgEntity.ErrInvalidCurrency
+}
+
+// attributeValues checks the attributes sent for a product against their
+// definitions.
+func (handler *ProductHandler) attributeValues(product *entity.Product, attributes map[string]interface{}) (entity.AttributeValues, error) {
+	if len(attributes) == 0 {
+		return entity.AttributeValues{}, nil
+	}
+	definitions, err := handler.AttributeDefinitionDB.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewAttributeValues(product.ID, attributes, definitions)
+}
+
+// parseAttributeFilters reads the attr.<code>, attr.<code>.min and
+// attr.<code>.max query parameters.
+func (handler *ProductHandler) parseAttributeFilters(query url.Values) ([]database.AttributeFilter, error) {
+	var definitions map[string]*entity.AttributeDefinition
+	filters := map[string]*database.AttributeFilter{}
+	var codes []string
+	for key, values := range query {
+		if !strings.HasPrefix(key, "attr.") {
+			continue
+		}
+		if definitions == nil {
+			all, err := handler.AttributeDefinitionDB.FindAll()
+			if err != nil {
+				return nil, err
+			}
+			definitions = make(map[string]*entity.AttributeDefinition, len(all))
+			for i := range all {
+				definitions[all[i].Code] = &all[i]
+			}
+		}
+		code, bound, _ := strings.Cut(strings.TrimPrefix(key, "attr."), ".")
+		definition, ok := definitions[code]
+		if !ok || (bound != "" && bound != "min" && bound != "max") {
+			return nil, fmt.Errorf("%w: %s", entity.ErrUnknownAttribute, strings.TrimPrefix(key, "attr."))
+		}
+		filter, ok := filters[code]
+		if !ok {
+			filter = &database.AttributeFilter{Code: code}
+			filters[code] = filter
+			codes = append(codes, code)
+		}
+		for _, text := range values {
+			value, err := definition.ParseValue(text)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case bound == "":
+				filter.Values = append(filter.Values, value.Value)
+			case value.Number == nil:
+				return nil, fmt.Errorf("%w for %s", entity.ErrInvalidAttributeValue, code)
+			case bound == "min":
+				filter.Min = value.Number
+			default:
+				filter.Max = value.Number
+			}
+		}
+	}
+	sort.Strings(codes)
+	attributeFilters := make([]database.AttributeFilter, 0, len(codes))
+	for _, code := range codes {
+		attributeFilters = append(attributeFilters, *filters[code])
+	}
+	return attributeFilters, nil
+}
+
+func attributeErrorStatus(err error) int {
+	if errors.Is(err, entity.ErrUnknownAttribute) || errors.Is(err, entity.ErrInvalidAttributeValue) {
+		return http.StatusBadRequest
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
+
+// productStatuses reads the statuses products are listed in from the status
+// query parameter. Requests without the products:write scope only get
+// published products.
+func productStatuses(r *http.Request) ([]string, error) {
+	if !middlewares.HasScope(r, entity.ScopeProductsWrite) {
+		return []string{entity.ProductPublished}, nil
+	}
+	var statuses []string
+	for _, status := range r.URL.Query()["status"] {
+		status = strings.ToLower(strings.TrimSpace(status))
+		if !entity.IsValidProductStatus(status) {
+			return nil, entity.ErrInvalidProductStatus
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// findVisibleProduct finds the product with the id. Requests without the
+// products:write scope can't tell unpublished products from missing ones.
+func findVisibleProduct(r *http.Request, productDB database.ProductInterface, id string) (*entity.Product, error) {
+	product, err := productDB.FindById(id)
+	if err == nil && !product.IsPublished() && !middlewares.HasScope(r, entity.ScopeProductsWrite) {
+		err = gorm.ErrRecordNotFound
+	}
+	return product, err
+}
+
+// parseProductFilter reads the listing filters from the query string.
+func (handler *ProductHandler) parseProductFilter(r *http.Request) (database.ProductFilter, error) {
+	query := r.URL.Query()
+	includeDescendants, _ := strconv.ParseBool(query.Get("include_descendants"))
+	var tags []string
+	for _, tag := range query["tag"] {
+		// Unknown or invalid tags can't match anything, so they are kept as sent.
+		if normalized, err := entity.NormalizeTag(tag); err == nil {
+			tag = normalized
+		}
+		tags = append(tags, tag)
+	}
+	// Tags sent more than once, maybe spelled differently, count once when
+	// matching all of them.
+	tags = uniqueStrings(tags)
+	var inStock *bool
+	if value, err := strconv.ParseBool(query.Get("in_stock")); err == nil {
+		inStock = &value
+	}
+	lowStock, _ := strconv.ParseBool(query.Get("low_stock"))
+	attributes, err := handler.parseAttributeFilters(query)
+	if err != nil {
+		return database.ProductFilter{}, err
+	}
+	return database.ProductFilter{
+		CategoryID:         query.Get("category"),
+		IncludeDescendants: includeDescendants,
+		Tags:               tags,
+		MatchAllTags:       strings.ToLower(query.Get("tag_match")) == "all",
+		InStock:            inStock,
+		LowStock:           lowStock,
+		Attributes:         attributes,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProductFilter_RepeatedTags(t *testing.T) {
+	handler := &ProductHandler{}
+	r := httptest.NewRequest("GET", "/products?tag=Gaming&tag=gaming&tag=Wireless&tag_match=all", nil)
+	filter, err := handler.parseProductFilter(r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gaming", "wireless"}, filter.Tags)
+	assert.True(t, filter.MatchAllTags)
+}
+
+func TestProductDetails_HiddenFromViewersUntilPublished(t *testing.T) {
+	db := createTestDB(t)
+	productDB := database.NewProduct(db)
+	variantHandler := NewVariantHandler(productDB, database.NewVariant(db))
+	imageHandler := NewImageHandler(productDB, database.NewProductImage(db), nil, 0)
+	priceHandler := NewPriceHandler(productDB, database.NewPriceChange(db), database.NewScheduledPrice(db))
+	stockHandler := NewStockHandler(productDB, database.NewStockMovement(db))
+	r := chi.NewRouter()
+	r.Get("/product/{id}/variants", variantHandler.GetVariants)
+	r.Get("/product/{id}/variants/{variantId}", variantHandler.GetVariant)
+	r.Get("/product/{id}/images", imageHandler.GetImages)
+	r.Get("/product/{id}/prices", priceHandler.GetPriceHistory)
+	r.Get("/product/{id}/scheduled-prices", priceHandler.GetScheduledPrices)
+	r.Get("/product/{id}/stock/movements", stockHandler.GetMovements)
+
+	product, _ := entity.NewProduct("Mouse", pkgEntity.Money{Amount: 3999, Currency: "BRL"})
+	assert.NoError(t, productDB.Create(product))
+	variant, _ := entity.NewVariant(product, "MOUSE-BLACK", nil, map[string]string{"color": "black"}, nil)
+	assert.NoError(t, database.NewVariant(db).Create(variant))
+	targets := []string{
+		"/product/" + product.ID.String() + "/variants",
+		"/product/" + product.ID.String() + "/variants/" + variant.ID.String(),
+		"/product/" + product.ID.String() + "/images",
+		"/product/" + product.ID.String() + "/prices",
+		"/product/" + product.ID.String() + "/scheduled-prices",
+		"/product/" + product.ID.String() + "/stock/movements",
+	}
+	// Requests without the products:write scope can't see drafts.
+	for _, target := range targets {
+		assert.Equal(t, http.StatusNotFound, serve(r, "GET", target, "").Code, target)
+	}
+
+	product.Status = entity.ProductPublished
+	assert.NoError(t, productDB.Update(product))
+	for _, target := range targets {
+		assert.Equal(t, http.StatusOK, serve(r, "GET", target, "").Code, target)
+	}
+
+	// Products in the trash are hidden as well.
+	assert.NoError(t, productDB.Delete(product.ID.String(), "", 0))
+	for _, target := range targets {
+		assert.Equal(t, http.StatusNotFound, serve(r, "GET", target, "").Code, target)
+	}
+}
+
+func TestGetProducts_DisplayPriceWithoutRate(t *testing.T) {
+	db := createTestDB(t)
+	productDB := database.NewProduct(db)
+	handler := NewProductHandler(productDB, database.NewCategory(db), database.NewExchangeRate(db), database.NewAttributeDefinition(db), nil, false)
+	mouse, _ := entity.NewProduct("Mouse", pkgEntity.Money{Amount: 3999, Currency: "BRL"})
+	mouse.Status = entity.ProductPublished
+	assert.NoError(t, mouse.SetPriceOverride(pkgEntity.Money{Amount: 799, Currency: "USD"}))
+	keyboard, _ := entity.NewProduct("Keyboard", pkgEntity.Money{Amount: 9990, Currency: "BRL"})
+	keyboard.Status = entity.ProductPublished
+	assert.NoError(t, productDB.Create(mouse))
+	assert.NoError(t, productDB.Create(keyboard))
+
+	// Without a USD rate the keyboard is listed without display_price.
+	res := serve(http.HandlerFunc(handler.GetProducts), "GET", "/product?currency=USD&sort=asc", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	var products []entity.Product
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&products))
+	assert.Len(t, products, 2)
+	assert.Equal(t, &pkgEntity.Money{Amount: 799, Currency: "USD"}, products[0].DisplayPrice)
+	assert.Nil(t, products[1].DisplayPrice)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/events"
+)
+
+const (
+	// streamQueueSize is how many events can wait to be written to a
+	// stream before the client is considered too slow and disconnected.
+	streamQueueSize = 64
+	// streamHeartbeat is how often a comment is written to idle streams so
+	// proxies don't close them.
+	streamHeartbeat = 15 * time.Second
+)
+
+var errStreamingUnsupported = errors.New("streaming is not supported")
+
+// ProductStreamHandler streams the changes made to products as Server-Sent
+// Events.
+type ProductStreamHandler struct {
+	// Products reads the listing filters.
+	Products *ProductHandler
+	// Events keeps the last product events, for clients to resume from.
+	Events *events.Buffer
+}
+
+func NewProductStreamHandler(products *ProductHandler, productEvents *events.Buffer) *ProductStreamHandler {
+	return &ProductStreamHandler{
+		Products: products,
+		Events:   productEvents,
+	}
+}
+
+// Stream products godoc
+// @Summary     Stream product changes
+// @Description Push product.created, product.updated and product.deleted events as Server-Sent Events, each with the product as it was after the change.
+// @Description Only the changes to products matching the filters of the listing at that point are sent. Changes to other products are sent as a removed
+// @Description event with only the id of the product, so clients drop the products that stopped matching. Clients reconnecting with the Last-Event-ID header,
+// @Description or last_event_id for clients that can't send it, get the events they missed since, as long as the server still keeps them. Otherwise
+// @Description a reset event is sent first, telling the client to load the products again. Clients too slow to read the events are disconnected.
+// @Tags        products
+// @Produce     text/event-stream
+// @Param       Last-Event-ID header      string   false    "Id of the last event received"
+// @Param       last_event_id query       string   false    "Id of the last event received, when the header can't be sent"
+// @Param       status        query       []string false    "Only products in these statuses (draft, published or archived), ignored for viewers" collectionFormat(multi)
+// @Param       category      query       string   false    "Only products in this category"
+// @Param       include_descendants  query  bool   false    "Also match products in subcategories of category"
+// @Param       tag           query       []string false    "Only products with these tags" collectionFormat(multi)
+// @Param       tag_match     query       string   false    "any (default) or all of the tags"
+// @Param       in_stock      query       bool     false    "Only products with (true) or without (false) available stock"
+// @Param       low_stock     query       bool     false    "Only products at or below their low stock threshold"
+// @Param       attr.{code}   query       string   false    "Only products whose attribute has this value, repeat for any of several values"
+// @Param       attr.{code}.min  query    number   false    "Only products whose number attribute is at least this value"
+// @Param       attr.{code}.max  query    number   false    "Only products whose number attribute is at most this value"
+// @Success     200           {object}    entity.Product
+// @Failure     400           {object}    Error
+// @Failure     422           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/stream   [get]
+// @Security    ApiKeyAuth
+func (handler *ProductStreamHandler) StreamProducts(w http.ResponseWriter, r *http.Request) {
+	filter, err := handler.Products.parseProductFilter(r)
+	if err != nil {
+		w.WriteHeader(attributeErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if filter.Statuses, err = productStatuses(r); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	matches, err := handler.Products.ProductDB.Matcher(filter)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: errStreamingUnsupported.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	missed, resumed, queue, unsubscribe := handler.Events.Subscribe(lastEventID, streamQueueSize)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep reverse proxies such as nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if !resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		writeProductEvent(w, event, matches)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-queue:
+			if !ok {
+				// The client fell behind, it resumes when it reconnects.
+				return
+			}
+			writeProductEvent(w, event, matches)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeProductEvent writes the event when its product matches. Otherwise
+// the change may have made the product stop matching, so a removed event
+// with only the id of the product is written instead, which clients that
+// don't show the product ignore.
+func writeProductEvent(w http.ResponseWriter, event entity.Event, matches func(product *entity.Product) bool) {
+	var product entity.Product
+	if err := json.Unmarshal(event.Payload, &product); err != nil {
+		log.Println(err)
+		return
+	}
+	if !matches(&product) {
+		removed, _ := json.Marshal(dto.ProductRemovedEvent{ID: product.ID.String()})
+		fmt.Fprintf(w, "id: %s\nevent: removed\ndata: %s\n\n", event.ID, removed)
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteProductEvent(t *testing.T) {
+	product, _ := entity.NewProduct("Mouse", pkgEntity.Money{Amount: 3999, Currency: "BRL"})
+	published := func(p *entity.Product) bool { return p.Status == entity.ProductPublished }
+
+	product.Status = entity.ProductPublished
+	event, _ := entity.NewEvent(entity.EventProductUpdated, product.ID.String(), product)
+	w := httptest.NewRecorder()
+	writeProductEvent(w, *event, published)
+	assert.Equal(t, "id: "+event.ID.String()+"\nevent: product.updated\ndata: "+string(event.Payload)+"\n\n", w.Body.String())
+
+	// A product that stopped matching is removed.
+	product.Status = entity.ProductArchived
+	event, _ = entity.NewEvent(entity.EventProductUpdated, product.ID.String(), product)
+	w = httptest.NewRecorder()
+	writeProductEvent(w, *event, published)
+	assert.Equal(t, "id: "+event.ID.String()+"\nevent: removed\ndata: {\"id\":\""+product.ID.String()+"\"}\n\n", w.Body.String())
+}
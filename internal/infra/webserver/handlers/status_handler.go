@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+type StatusHandler struct {
+	ProductDB             database.ProductInterface
+	ProductStatusChangeDB database.ProductStatusChangeInterface
+	Storage               storage.Storage
+}
+
+func NewStatusHandler(productDB database.ProductInterface, productStatusChangeDB database.ProductStatusChangeInterface, store storage.Storage) *StatusHandler {
+	return &StatusHandler{
+		ProductDB:             productDB,
+		ProductStatusChangeDB: productStatusChangeDB,
+		Storage:               store,
+	}
+}
+
+// Publish product godoc
+// @Summary     Publish product
+// @Description Publish a draft product, making it visible to viewers
+// @Tags        status
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Success     200           {object}    entity.Product
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/publish    [post]
+// @Security    ApiKeyAuth
+func (handler *StatusHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	handler.changeStatus(w, r, entity.ProductDraft, entity.ProductPublished)
+}
+
+// Unpublish product godoc
+// @Summary     Unpublish product
+// @Description Move a published product back to draft, hiding it from viewers
+// @Tags        status
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Success     200           {object}    entity.Product
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/unpublish    [post]
+// @Security    ApiKeyAuth
+func (handler *StatusHandler) Unpublish(w http.ResponseWriter, r *http.Request) {
+	handler.changeStatus(w, r, entity.ProductPublished, entity.ProductDraft)
+}
+
+// Archive product godoc
+// @Summary     Archive product
+// @Description Archive a draft or published product
+// @Tags        status
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Success     200           {object}    entity.Product
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/archive    [post]
+// @Security    ApiKeyAuth
+func (handler *StatusHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	handler.changeStatus(w, r, "", entity.ProductArchived)
+}
+
+// Unarchive product godoc
+// @Summary     Unarchive product
+// @Description Move an archived product back to draft
+// @Tags        status
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Success     200           {object}    entity.Product
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/unarchive    [post]
+// @Security    ApiKeyAuth
+func (handler *StatusHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	handler.changeStatus(w, r, entity.ProductArchived, entity.ProductDraft)
+}
+
+// List status history godoc
+// @Summary     List status history
+// @Description List the status changes of a product and who made them, newest first
+// @Tags        status
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true     "Product Id"
+// @Param       page          query       string   false    "Page number"
+// @Param       limit         query       string   false    "Changes per page"
+// @Success     200           {array}     entity.ProductStatusChange
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/status-history    [get]
+// @Security    ApiKeyAuth
+func (handler *StatusHandler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	changes, err := handler.ProductStatusChangeDB.FindByProductId(chi.URLParam(r, "id"), page, limit)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(changes)
+}
+
+// changeStatus moves the product from the status from, or any status when
+// it is empty, to status, recording who did it.
+func (handler *StatusHandler) changeStatus(w http.ResponseWriter, r *http.Request, from, status string) {
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if from != "" && product.Status != from {
+		err = entity.ErrInvalidStatusTransition
+	} else {
+		err = product.ChangeStatus(status)
+	}
+	if err != nil {
+		w.WriteHeader(statusErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product.UpdatedBy = actor(r)
+	if err = handler.ProductDB.As(auditSource(r)).Update(product); err != nil {
+		w.WriteHeader(updateErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	storage.SetImageURLs(handler.Storage, product.Images)
+	setProductETag(w, product)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+func statusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrInvalidStatusTransition):
+		return http.StatusConflict
+	case errors.Is(err, entity.ErrInvalidProductStatus):
+		return http.StatusBadRequest
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type StockHandler struct {
+	ProductDB       database.ProductInterface
+	StockMovementDB database.StockMovementInterface
+}
+
+func NewStockHandler(productDB database.ProductInterface, db database.StockMovementInterface) *StockHandler {
+	return &StockHandler{
+		ProductDB:       productDB,
+		StockMovementDB: db,
+	}
+}
+
+// Create stock movement godoc
+// @Summary     Post stock movement
+// @Description Record a receipt, adjustment, sale, reservation or release and update the product stock. Adjustments take a signed quantity and require a reason.
+// @Tags        stock
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string                           true    "Product Id"
+// @Param       request       body        dto.CreateStockMovementInput     true    "Stock movement payload"
+// @Success     201           {object}    entity.StockMovement
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/stock/movements    [post]
+// @Security    ApiKeyAuth
+func (handler *StockHandler) CreateMovement(w http.ResponseWriter, r *http.Request) {
+	productID, err := pkgEntity.ParseID(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	var createStockMovementInput dto.CreateStockMovementInput
+	if err := json.NewDecoder(r.Body).Decode(&createStockMovementInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	movement, err := entity.NewStockMovement(
+		productID,
+		createStockMovementInput.Type,
+		createStockMovementInput.Quantity,
+		createStockMovementInput.Reason,
+		actor(r),
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if _, err = handler.StockMovementDB.As(auditSource(r)).Apply(movement); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, entity.ErrInsufficientStock),
+			errors.Is(err, entity.ErrInsufficientReserved),
+			errors.Is(err, database.ErrStockChanged):
+			status = http.StatusConflict
+		default:
+			log.Println(err)
+		}
+		w.WriteHeader(status)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// List stock movements godoc
+// @Summary     List stock movements
+// @Description List the stock ledger of a product, newest first
+// @Tags        stock
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true     "Product Id"
+// @Param       page          query       string   false    "Page number"
+// @Param       limit         query       string   false    "Movements per page"
+// @Success     200           {array}     entity.StockMovement
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/stock/movements    [get]
+// @Security    ApiKeyAuth
+func (handler *StockHandler) GetMovements(w http.ResponseWriter, r *http.Request) {
+	if _, err := findVisibleProduct(r, handler.ProductDB, chi.URLParam(r, "id")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	movements, err := handler.StockMovementDB.FindByProductId(chi.URLParam(r, "id"), page, limit)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(movements)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+)
+
+type TagHandler struct {
+	TagDB database.TagInterface
+}
+
+func NewTagHandler(db database.TagInterface) *TagHandler {
+	return &TagHandler{
+		TagDB: db,
+	}
+}
+
+// List tags godoc
+// @Summary     List tags
+// @Description List the tags in use with the number of products carrying each of them
+// @Tags        tags
+// @Accept      json
+// @Produce     json
+// @Success     200           {array}     entity.TagUsage
+// @Failure     500           {object}    Error
+// @Router      /tags         [get]
+// @Security    ApiKeyAuth
+func (handler *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := handler.TagDB.FindAll()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tags)
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
 	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
 	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/webserver/middlewares"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth"
 )
 
@@ -55,7 +59,7 @@ func (handler *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
-	if err = handler.UserDB.Create(user); err != nil {
+	if err = handler.UserDB.As(auditSource(r)).Create(user); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		errorObj := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(errorObj)
@@ -73,6 +77,8 @@ func (handler *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 // @Produce     json
 // @Param       request       body        dto.GetJWTInput   true    "user credentials"
 // @Success     200           {object}    dto.GetJWTOutput
+// @Failure     401           {object}    Error
+// @Failure     403           {object}    Error
 // @Failure     404
 // @Failure     500           {object}    Error
 // @Router      /user/login   [post]
@@ -97,6 +103,12 @@ func (handler *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(errorObj)
 		return
 	}
+	if user.Disabled {
+		w.WriteHeader(http.StatusForbidden)
+		errorObj := Error{Message: entity.ErrUserDisabled.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
 	token, err := generateToken(user, handler)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -113,15 +125,233 @@ func (handler *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// List users godoc
+// @Summary     List users
+// @Description List users with search and pagination. The total number of matches is sent in the X-Total-Count header.
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       page          query       string   false    "Page number"
+// @Param       limit         query       string   false    "Users per page"
+// @Param       search        query       string   false    "Filter by name or email"
+// @Success     200           {array}     entity.User
+// @Failure     403           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/users  [get]
+// @Security    ApiKeyAuth
+func (handler *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	search := r.URL.Query().Get("search")
+
+	users, total, err := handler.UserDB.FindAll(page, limit, search)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+// Get user godoc
+// @Summary     Get user
+// @Description Get user by id
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       user_id       path        string   true    "User Id"
+// @Success     200           {object}    entity.User
+// @Failure     403           {object}    Error
+// @Failure     404           {object}    Error
+// @Router      /admin/users/{user_id}    [get]
+// @Security    ApiKeyAuth
+func (handler *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := handler.findUser(w, r)
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// Disable user godoc
+// @Summary     Disable user
+// @Description Disable a user account. Disabled users can't log in and their tokens are rejected.
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       user_id       path        string   true    "User Id"
+// @Success     200           {object}    entity.User
+// @Failure     400           {object}    Error
+// @Failure     403           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/users/{user_id}/disable    [post]
+// @Security    ApiKeyAuth
+func (handler *UserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	handler.updateUser(w, r, func(user *entity.User) error {
+		user.Disable()
+		return nil
+	})
+}
+
+// Enable user godoc
+// @Summary     Enable user
+// @Description Enable a previously disabled user account
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       user_id       path        string   true    "User Id"
+// @Success     200           {object}    entity.User
+// @Failure     400           {object}    Error
+// @Failure     403           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/users/{user_id}/enable     [post]
+// @Security    ApiKeyAuth
+func (handler *UserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	handler.updateUser(w, r, func(user *entity.User) error {
+		user.Enable()
+		return nil
+	})
+}
+
+// Change user role godoc
+// @Summary     Change user role
+// @Description Change the role of a user (admin, editor or viewer)
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       user_id       path        string                    true    "User Id"
+// @Param       request       body        dto.ChangeUserRoleInput   true    "New role"
+// @Success     200           {object}    entity.User
+// @Failure     400           {object}    Error
+// @Failure     403           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/users/{user_id}/role   [put]
+// @Security    ApiKeyAuth
+func (handler *UserHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	var changeUserRoleInput dto.ChangeUserRoleInput
+	if err := json.NewDecoder(r.Body).Decode(&changeUserRoleInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.updateUser(w, r, func(user *entity.User) error {
+		return user.ChangeRole(changeUserRoleInput.Role)
+	})
+}
+
+// Delete user godoc
+// @Summary     Delete user
+// @Description Delete a user account
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       user_id       path        string   true    "User Id"
+// @Success     200
+// @Failure     400           {object}    Error
+// @Failure     403           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /admin/users/{user_id}    [delete]
+// @Security    ApiKeyAuth
+func (handler *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := handler.findUser(w, r)
+	if !ok {
+		return
+	}
+	if isCurrentUser(r, user) {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: "you can't delete your own account"}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err := handler.UserDB.As(auditSource(r)).Delete(user.ID.String()); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (handler *UserHandler) findUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	id := chi.URLParam(r, "id")
+	user, err := handler.UserDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return nil, false
+	}
+	return user, true
+}
+
+// updateUser applies change to the user in the url and saves it. Admins can't
+// change their own account, so they can't lock themselves out by accident.
+func (handler *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, change func(user *entity.User) error) {
+	user, ok := handler.findUser(w, r)
+	if !ok {
+		return
+	}
+	if isCurrentUser(r, user) {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: "you can't change your own account"}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err := change(user); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err := handler.UserDB.As(auditSource(r)).Update(user); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func isCurrentUser(r *http.Request, user *entity.User) bool {
+	current := middlewares.UserFromContext(r.Context())
+	return current != nil && current.ID == user.ID
+}
+
 func generateToken(user *entity.User, handler *UserHandler) (string, error) {
 	claims := make(map[string]interface{})
-	jwtauth.SetExpiryIn(claims, time.Second*time.Duration(handler.JwtExpiresIn))
-	jwtauth.SetIssuedNow(claims)
 	claims["sub"] = user.ID.String()
 	claims["email"] = user.Email
 	claims["name"] = user.Name
+	claims["scope"] = user.Scope()
+	return signToken(handler.Jwt, handler.JwtExpiresIn, claims)
+}
+
+// signToken sets the expiration and issued at claims and signs the token, so
+// every access token the API hands out shares the same key and lifetime.
+func signToken(jwt *jwtauth.JWTAuth, expiresIn int, claims map[string]interface{}) (string, error) {
+	jwtauth.SetExpiryIn(claims, time.Second*time.Duration(expiresIn))
+	jwtauth.SetIssuedNow(claims)
 
-	_, tokenString, err := handler.Jwt.Encode(claims)
+	_, tokenString, err := jwt.Encode(claims)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type VariantHandler struct {
+	ProductDB database.ProductInterface
+	VariantDB database.VariantInterface
+}
+
+func NewVariantHandler(productDB database.ProductInterface, variantDB database.VariantInterface) *VariantHandler {
+	return &VariantHandler{
+		ProductDB: productDB,
+		VariantDB: variantDB,
+	}
+}
+
+// Create variant godoc
+// @Summary     Create variant
+// @Description Create a variant of a product with its own SKU, barcode, options and optional price
+// @Tags        variants
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string                   true    "Product Id"
+// @Param       request       body        dto.CreateVariantInput   true    "Variant request payload"
+// @Success     201           {object}    entity.Variant
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/variants    [post]
+// @Security    ApiKeyAuth
+func (handler *VariantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var createVariantInput dto.CreateVariantInput
+	if err := json.NewDecoder(r.Body).Decode(&createVariantInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	variant, err := entity.NewVariant(product, createVariantInput.SKU, createVariantInput.Barcode, createVariantInput.Options, createVariantInput.Price)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.VariantDB.As(auditSource(r)).Create(variant); err != nil {
+		w.WriteHeader(variantErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// List variants godoc
+// @Summary     List variants
+// @Description List the variants of a product
+// @Tags        variants
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Success     200           {array}     entity.Variant
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/variants    [get]
+// @Security    ApiKeyAuth
+func (handler *VariantHandler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	if _, err := findVisibleProduct(r, handler.ProductDB, chi.URLParam(r, "id")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	variants, err := handler.VariantDB.FindByProductId(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variants)
+}
+
+// Get variant godoc
+// @Summary     Get variant
+// @Description Get a variant of a product
+// @Tags        variants
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Param       variant_id    path        string   true    "Variant Id"
+// @Success     200           {object}    entity.Variant
+// @Failure     404           {object}    Error
+// @Router      /product/{product_id}/variants/{variant_id}    [get]
+// @Security    ApiKeyAuth
+func (handler *VariantHandler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	if _, err := findVisibleProduct(r, handler.ProductDB, chi.URLParam(r, "id")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	variant, err := handler.VariantDB.FindById(chi.URLParam(r, "id"), chi.URLParam(r, "variantId"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// Update variant godoc
+// @Summary     Update variant
+// @Description Replace the SKU, barcode, options and price of a variant
+// @Tags        variants
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string                   true    "Product Id"
+// @Param       variant_id    path        string                   true    "Variant Id"
+// @Param       request       body        dto.UpdateVariantInput   true    "Variant payload"
+// @Success     200           {object}    entity.Variant
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/variants/{variant_id}    [put]
+// @Security    ApiKeyAuth
+func (handler *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	var updateVariantInput dto.UpdateVariantInput
+	if err := json.NewDecoder(r.Body).Decode(&updateVariantInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	product, err := handler.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	variant, err := handler.VariantDB.FindById(product.ID.String(), chi.URLParam(r, "variantId"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	err = variant.Change(product, updateVariantInput.SKU, updateVariantInput.Barcode, updateVariantInput.Options, updateVariantInput.Price)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.VariantDB.As(auditSource(r)).Update(variant); err != nil {
+		w.WriteHeader(variantErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// Delete variant godoc
+// @Summary     Delete variant
+// @Description Delete a variant of a product
+// @Tags        variants
+// @Accept      json
+// @Produce     json
+// @Param       product_id    path        string   true    "Product Id"
+// @Param       variant_id    path        string   true    "Variant Id"
+// @Success     200
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /product/{product_id}/variants/{variant_id}    [delete]
+// @Security    ApiKeyAuth
+func (handler *VariantHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	if err := handler.VariantDB.As(auditSource(r)).Delete(chi.URLParam(r, "id"), chi.URLParam(r, "variantId")); err != nil {
+		w.WriteHeader(variantErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func variantErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrDuplicateSKU), errors.Is(err, entity.ErrDuplicateBarcode), errors.Is(err, entity.ErrDuplicateVariantOptions):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/worker"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+var errInvalidDeliveryStatus = errors.New("status must be pending, succeeded or failed")
+
+type WebhookHandler struct {
+	WebhookDB  database.WebhookInterface
+	DeliveryDB database.WebhookDeliveryInterface
+	Sender     *worker.WebhookSender
+}
+
+func NewWebhookHandler(webhookDB database.WebhookInterface, deliveryDB database.WebhookDeliveryInterface, sender *worker.WebhookSender) *WebhookHandler {
+	return &WebhookHandler{
+		WebhookDB:  webhookDB,
+		DeliveryDB: deliveryDB,
+		Sender:     sender,
+	}
+}
+
+// Create webhook godoc
+// @Summary     Create webhook
+// @Description Subscribe a URL to events. Each delivery is POSTed as JSON with the X-Webhook-Id, X-Webhook-Event, X-Webhook-Timestamp and
+// @Description X-Webhook-Signature headers, the signature being "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret.
+// @Description Failed deliveries are retried with exponential backoff. The secret is only returned once. Deliveries to loopback, private,
+// @Description link-local and other non-public addresses fail, unless the server allows private networks.
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       request       body        dto.CreateWebhookInput   true    "Webhook request payload"
+// @Success     201           {object}    dto.CreateWebhookOutput
+// @Failure     400           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /webhooks     [post]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var createWebhookInput dto.CreateWebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&createWebhookInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	webhook, err := entity.NewWebhook(createWebhookInput.URL, createWebhookInput.EventTypes, createWebhookInput.Secret, actor(r))
+	if err != nil {
+		w.WriteHeader(webhookErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if err = handler.WebhookDB.Create(webhook); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateWebhookOutput{
+		ID:         webhook.ID.String(),
+		URL:        webhook.URL,
+		EventTypes: webhook.EventTypes,
+		Secret:     webhook.Secret,
+		Active:     webhook.Active,
+		CreatedAt:  webhook.CreatedAt,
+	})
+}
+
+// List webhooks godoc
+// @Summary     List webhooks
+// @Description List the webhooks, oldest first
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       page          query       string   false    "page number"
+// @Param       limit         query       string   false    "limit"
+// @Success     200           {array}     entity.Webhook
+// @Failure     500           {object}    Error
+// @Router      /webhooks     [get]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	webhooks, err := handler.WebhookDB.FindAll(page, limit)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// Get webhook godoc
+// @Summary     Get webhook
+// @Description Get a webhook
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       id            path        string   true    "Webhook ID" Format(uuid)
+// @Success     200           {object}    entity.Webhook
+// @Failure     404           {object}    Error
+// @Router      /webhooks/{id}    [get]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, err := handler.WebhookDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(webhookErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// Update webhook godoc
+// @Summary     Update webhook
+// @Description Change the URL and event types of a webhook, pause or resume it with active, and rotate its secret by sending a new one.
+// @Description Deliveries already queued are sent with the new settings, and fail while the webhook is inactive. They can be replayed once it is active again.
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       id            path        string                   true    "Webhook ID" Format(uuid)
+// @Param       request       body        dto.UpdateWebhookInput   true    "Webhook request payload"
+// @Success     200           {object}    entity.Webhook
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /webhooks/{id}    [put]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	var updateWebhookInput dto.UpdateWebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&updateWebhookInput); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	webhook, err := handler.WebhookDB.FindById(chi.URLParam(r, "id"))
+	if err == nil {
+		err = webhook.Change(updateWebhookInput.URL, updateWebhookInput.EventTypes, updateWebhookInput.Secret)
+	}
+	if err != nil {
+		w.WriteHeader(webhookErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	if updateWebhookInput.Active != nil {
+		webhook.Active = *updateWebhookInput.Active
+	}
+	if err = handler.WebhookDB.Update(webhook); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// Delete webhook godoc
+// @Summary     Delete webhook
+// @Description Delete a webhook along with its delivery log. Deliveries still queued aren't sent.
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       id            path        string   true    "Webhook ID" Format(uuid)
+// @Success     200
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /webhooks/{id}    [delete]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := handler.WebhookDB.Delete(chi.URLParam(r, "id")); err != nil {
+		w.WriteHeader(webhookErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Ping webhook godoc
+// @Summary     Ping webhook
+// @Description Send a webhook.ping event to the webhook, whatever the events it is subscribed to, to test it receives and verifies deliveries.
+// @Description The delivery can be followed in the delivery log.
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       id            path        string   true    "Webhook ID" Format(uuid)
+// @Success     202           {object}    entity.WebhookDelivery
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /webhooks/{id}/ping    [post]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) Ping(w http.ResponseWriter, r *http.Request) {
+	webhook, err := handler.WebhookDB.FindById(chi.URLParam(r, "id"))
+	var delivery *entity.WebhookDelivery
+	if err == nil {
+		delivery, err = handler.Sender.Ping(webhook)
+	}
+	if err != nil {
+		w.WriteHeader(webhookErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// List deliveries godoc
+// @Summary     List webhook deliveries
+// @Description List the deliveries of a webhook, newest first, with the response status and body of their last attempt
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       id            path        string   true     "Webhook ID" Format(uuid)
+// @Param       status        query       string   false    "Only deliveries in this status (pending, succeeded or failed)"
+// @Param       page          query       string   false    "page number"
+// @Param       limit         query       string   false    "limit"
+// @Success     200           {array}     entity.WebhookDelivery
+// @Failure     400           {object}    Error
+// @Failure     404           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /webhooks/{id}/deliveries    [get]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil {
+		page = 0
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	status := query.Get("status")
+	switch status {
+	case "", entity.DeliveryPending, entity.DeliverySucceeded, entity.DeliveryFailed:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		errorObj := Error{Message: errInvalidDeliveryStatus.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	webhook, err := handler.WebhookDB.FindById(chi.URLParam(r, "id"))
+	var deliveries []entity.WebhookDelivery
+	if err == nil {
+		deliveries, err = handler.DeliveryDB.FindByWebhookId(webhook.ID.String(), status, page, limit)
+	}
+	if err != nil {
+		w.WriteHeader(webhookErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// Replay delivery godoc
+// @Summary     Replay webhook delivery
+// @Description Send a delivery that succeeded or failed again, with a fresh set of attempts. The event is sent as it was the first time.
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       id            path        string   true    "Webhook ID" Format(uuid)
+// @Param       deliveryId    path        string   true    "Delivery ID" Format(uuid)
+// @Success     202           {object}    entity.WebhookDelivery
+// @Failure     404           {object}    Error
+// @Failure     409           {object}    Error
+// @Failure     500           {object}    Error
+// @Router      /webhooks/{id}/deliveries/{deliveryId}/replay    [post]
+// @Security    ApiKeyAuth
+func (handler *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := handler.DeliveryDB.FindById(chi.URLParam(r, "id"), chi.URLParam(r, "deliveryId"))
+	if err == nil {
+		err = delivery.Replay()
+	}
+	if err == nil {
+		err = handler.DeliveryDB.Update(delivery)
+	}
+	if err != nil {
+		w.WriteHeader(webhookErrorStatus(err))
+		errorObj := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(errorObj)
+		return
+	}
+	handler.Sender.Notify()
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrInvalidWebhookURL), errors.Is(err, entity.ErrWebhookEventsRequired),
+		errors.Is(err, entity.ErrInvalidWebhookEvent), errors.Is(err, entity.ErrWebhookSecretTooShort):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrDeliveryNotFinished):
+		return http.StatusConflict
+	}
+	log.Println(err)
+	return http.StatusInternalServerError
+}
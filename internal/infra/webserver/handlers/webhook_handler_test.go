@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/dto"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/worker"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func webhookRouter(t *testing.T) (*chi.Mux, *WebhookHandler) {
+	db := createTestDB(t)
+	webhookDB := database.NewWebhook(db)
+	deliveryDB := database.NewWebhookDelivery(db)
+	sender := worker.NewWebhookSender(webhookDB, deliveryDB, time.Second, 3, time.Minute, time.Hour, time.Second, time.Hour, false)
+	handler := NewWebhookHandler(webhookDB, deliveryDB, sender)
+	r := chi.NewRouter()
+	r.Post("/webhooks", handler.Create)
+	r.Get("/webhooks", handler.GetWebhooks)
+	r.Get("/webhooks/{id}", handler.GetWebhook)
+	r.Put("/webhooks/{id}", handler.UpdateWebhook)
+	r.Delete("/webhooks/{id}", handler.DeleteWebhook)
+	r.Post("/webhooks/{id}/ping", handler.Ping)
+	r.Get("/webhooks/{id}/deliveries", handler.GetDeliveries)
+	r.Post("/webhooks/{id}/deliveries/{deliveryId}/replay", handler.ReplayDelivery)
+	return r, handler
+}
+
+func TestWebhookHandler(t *testing.T) {
+	r, handler := webhookRouter(t)
+
+	res := serve(r, http.MethodPost, "/webhooks", `{"url":"ftp://example.com","event_types":["product.created"]}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	res = serve(r, http.MethodPost, "/webhooks", `{"url":"https://example.com/hook","event_types":["product.created"]}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	var created dto.CreateWebhookOutput
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	assert.NotEmpty(t, created.Secret)
+	assert.True(t, created.Active)
+
+	// The secret is only returned when the webhook is created.
+	res = serve(r, http.MethodGet, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotContains(t, res.Body.String(), created.Secret)
+	res = serve(r, http.MethodGet, "/webhooks/"+pkgEntity.NewID().String(), "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	res = serve(r, http.MethodPut, "/webhooks/"+created.ID, `{"url":"https://example.com/hook","event_types":["*"],"active":false}`)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var updated entity.Webhook
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&updated))
+	assert.False(t, updated.Active)
+	assert.Equal(t, []string{"*"}, updated.EventTypes)
+
+	res = serve(r, http.MethodPost, "/webhooks/"+created.ID+"/ping", "")
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	var ping entity.WebhookDelivery
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&ping))
+	assert.Equal(t, entity.EventWebhookPing, ping.EventType)
+
+	res = serve(r, http.MethodGet, "/webhooks/"+created.ID+"/deliveries?status=lost", "")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	res = serve(r, http.MethodGet, "/webhooks/"+created.ID+"/deliveries?status=pending", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	var deliveries []entity.WebhookDelivery
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&deliveries))
+	assert.Len(t, deliveries, 1)
+
+	// Only finished deliveries can be replayed.
+	replay := "/webhooks/" + created.ID + "/deliveries/" + ping.ID.String() + "/replay"
+	res = serve(r, http.MethodPost, replay, "")
+	assert.Equal(t, http.StatusConflict, res.Code)
+	delivery, _ := handler.DeliveryDB.FindById(created.ID, ping.ID.String())
+	delivery.Fail(0, "", "webhook is inactive", time.Now(), 0)
+	assert.NoError(t, handler.DeliveryDB.Update(delivery))
+	res = serve(r, http.MethodPost, replay, "")
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&ping))
+	assert.Equal(t, entity.DeliveryPending, ping.Status)
+	assert.Zero(t, ping.Attempts)
+
+	res = serve(r, http.MethodDelete, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = serve(r, http.MethodDelete, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
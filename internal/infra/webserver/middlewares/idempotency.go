@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/jwtauth"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed for a repeated
+	// request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Idempotency makes POST and PATCH requests sent with an Idempotency-Key
+// header safe to retry: the response to the first request is saved for ttl
+// and replayed for any request of the same user with the same key, which
+// must be for the same method, URL and body. Responses with a 5xx status
+// aren't saved, so the request can be retried. Bodies are read whole to be
+// fingerprinted, so requests with a key and a body over maxBodySize bytes
+// are rejected. It must run after jwtauth.Verifier and after the scopes and
+// roles are checked, so refusals aren't saved.
+func Idempotency(keys database.IdempotencyKeyInterface, ttl time.Duration, maxBodySize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value := r.Header.Get(IdempotencyKeyHeader)
+			if value == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			owner, _ := claims["sub"].(string)
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				var maxBytesError *http.MaxBytesError
+				if errors.As(err, &maxBytesError) {
+					writeError(w, http.StatusRequestEntityTooLarge, "request body is too large to be sent with an Idempotency-Key")
+					return
+				}
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			key, err := entity.NewIdempotencyKey(owner, value, fingerprint(r, body), ttl)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			existing, err := keys.Reserve(key)
+			if err != nil {
+				log.Println(err)
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if existing != nil {
+				replay(w, key, existing)
+				return
+			}
+			recorder := &responseRecorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// The key is released when the handler panics too.
+				if !completed {
+					if err := keys.Release(key.Owner, key.Key); err != nil {
+						log.Println(err)
+					}
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+			if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			key.Complete(recorder.status, w.Header().Clone(), recorder.body.Bytes())
+			if err := keys.Complete(key); err != nil {
+				log.Println(err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replay answers a request whose key was used before with the response
+// saved for it, as long as it is the same request.
+func replay(w http.ResponseWriter, key, existing *entity.IdempotencyKey) {
+	switch {
+	case existing.Fingerprint != key.Fingerprint:
+		writeError(w, http.StatusUnprocessableEntity, entity.ErrIdempotencyKeyReused.Error())
+	case !existing.Completed():
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusConflict, entity.ErrIdempotencyKeyInUse.Error())
+	default:
+		for name, values := range existing.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(existing.Status)
+		w.Write(existing.Body)
+	}
+}
+
+// fingerprint identifies a request by its method, path, query and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the status and body written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// idempotentServer serves handler behind Idempotency, counting the requests
+// that reach it, and returns the token to send.
+func idempotentServer(t *testing.T, handler http.HandlerFunc) (http.Handler, string, *int) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.Migrate(db, "BRL"); err != nil {
+		t.Fatal(err)
+	}
+	auth := jwtauth.New("HS256", []byte("secret"), nil)
+	_, token, _ := auth.Encode(map[string]interface{}{"sub": "user-1"})
+	calls := 0
+	var next http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		handler(w, r)
+	})
+	next = Idempotency(database.NewIdempotencyKey(db), time.Hour, 64)(next)
+	return jwtauth.Verifier(auth)(next), token, &calls
+}
+
+func idempotentRequest(handler http.Handler, token, key, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	handler, token, calls := idempotentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/product/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	})
+	res := idempotentRequest(handler, token, "key-1", "/product", `{"name":"a"}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, `{"name":"a"}`, res.Body.String())
+
+	res = idempotentRequest(handler, token, "key-1", "/product", `{"name":"a"}`)
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, "true", res.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "/product/1", res.Header().Get("Location"))
+	assert.Equal(t, `{"name":"a"}`, res.Body.String())
+
+	res = idempotentRequest(handler, token, "key-2", "/product", `{"name":"a"}`)
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, res.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotency_Mismatch(t *testing.T) {
+	handler, token, calls := idempotentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	idempotentRequest(handler, token, "key-1", "/product/import?dry_run=true", `a`)
+
+	res := idempotentRequest(handler, token, "key-1", "/product/import?dry_run=true", `b`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	res = idempotentRequest(handler, token, "key-1", "/product/import?dry_run=false", `a`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Equal(t, 1, *calls)
+
+	res = idempotentRequest(handler, token, "key-2", "/product", strings.Repeat("a", 65))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestIdempotency_ServerError(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	handler, token, calls := idempotentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+	res := idempotentRequest(handler, token, "key-1", "/product", `{}`)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+
+	status = http.StatusCreated
+	res = idempotentRequest(handler, token, "key-1", "/product", `{}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Empty(t, res.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_Panic(t *testing.T) {
+	panics := true
+	handler, token, calls := idempotentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	assert.Panics(t, func() {
+		idempotentRequest(handler, token, "key-1", "/product", `{}`)
+	})
+
+	panics = false
+	res := idempotentRequest(handler, token, "key-1", "/product", `{}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, 2, *calls)
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+)
+
+const requestURIContextKey contextKey = "requestURI"
+
+// RedactQuery runs logger, such as middleware.Logger, with the values of the
+// query parameters hidden from the request URI it logs, so secrets such as
+// access tokens sent in the query don't end up in the logs. The handlers
+// after it get the request as it was sent.
+func RedactQuery(logger func(http.Handler) http.Handler, params ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		logged := logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requestURI, ok := r.Context().Value(requestURIContextKey).(string); ok {
+				r = r.WithContext(r.Context())
+				r.RequestURI = requestURI
+			}
+			next.ServeHTTP(w, r)
+		}))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			redacted := false
+			for _, param := range params {
+				if query.Has(param) {
+					query.Set(param, "REDACTED")
+					redacted = true
+				}
+			}
+			if !redacted {
+				logged.ServeHTTP(w, r)
+				return
+			}
+			requestURI := r.RequestURI
+			r = r.WithContext(context.WithValue(r.Context(), requestURIContextKey, requestURI))
+			r.RequestURI = r.URL.EscapedPath() + "?" + query.Encode()
+			logged.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactQuery(t *testing.T) {
+	var logged, served, token string
+	logger := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logged = r.RequestURI
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := RedactQuery(logger, "jwt")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r.RequestURI
+		token = r.URL.Query().Get("jwt")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws?jwt=secret&a=b", nil))
+	assert.Equal(t, "/ws?a=b&jwt=REDACTED", logged)
+	assert.Equal(t, "/ws?jwt=secret&a=b", served)
+	assert.Equal(t, "secret", token)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/product?page=2", nil))
+	assert.Equal(t, "/product?page=2", logged)
+	assert.Equal(t, "/product?page=2", served)
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/jwtauth"
+)
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// RequireScope rejects requests whose access token was not granted every one
+// of the given scopes. It must run after jwtauth.Verifier and, for user
+// tokens, after ActiveUser so the scopes of the current role are enforced.
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, err := grantedScopes(r)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			for _, scope := range scopes {
+				if !contains(granted, scope) {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+					writeError(w, http.StatusForbidden, "token does not grant the "+scope+" scope")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasScope tells whether the request is allowed the scope, for handlers that
+// only change what they return depending on it.
+func HasScope(r *http.Request, scope string) bool {
+	granted, err := grantedScopes(r)
+	return err == nil && contains(granted, scope)
+}
+
+// grantedScopes returns the scopes granted to the request by its token and,
+// for user tokens, still held by the role of the user.
+func grantedScopes(r *http.Request) ([]string, error) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	granted := TokenScopes(claims)
+	if user := UserFromContext(r.Context()); user != nil {
+		// A role change takes effect right away instead of when the token expires.
+		granted = intersect(granted, strings.Fields(user.Scope()))
+	}
+	return granted, nil
+}
+
+// TokenScopes returns the scopes granted by the "scope" claim of a token.
+func TokenScopes(claims map[string]interface{}) []string {
+	scope, _ := claims["scope"].(string)
+	return strings.Fields(scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func intersect(a, b []string) []string {
+	var result []string
+	for _, v := range a {
+		if contains(b, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Message: message})
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/go-chi/jwtauth"
+)
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// ActiveUser loads the user behind a user access token and rejects the
+// request when the account was disabled or deleted after the token was
+// issued. Tokens issued to OAuth clients are passed through untouched.
+func ActiveUser(users database.UserInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			if _, ok := claims["client_id"]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			sub, _ := claims["sub"].(string)
+			user, err := users.FindById(sub)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, "user no longer exists")
+				return
+			}
+			if user.Disabled {
+				writeError(w, http.StatusUnauthorized, entity.ErrUserDisabled.Error())
+				return
+			}
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// UserFromContext returns the user loaded by ActiveUser, or nil when the
+// request was made with a client token.
+func UserFromContext(ctx context.Context) *entity.User {
+	user, _ := ctx.Value(userContextKey).(*entity.User)
+	return user
+}
+
+// RequireRole only lets through users holding one of the given roles. It must
+// run after ActiveUser.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := UserFromContext(r.Context())
+			if user == nil || !contains(roles, user.Role) {
+				writeError(w, http.StatusForbidden, "you are not allowed to access this resource")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+)
+
+// IdempotencyKeyPurger periodically deletes the idempotency keys that
+// expired, along with the responses saved for them.
+type IdempotencyKeyPurger struct {
+	IdempotencyKeyDB database.IdempotencyKeyInterface
+	Interval         time.Duration
+}
+
+func NewIdempotencyKeyPurger(db database.IdempotencyKeyInterface, interval time.Duration) *IdempotencyKeyPurger {
+	return &IdempotencyKeyPurger{
+		IdempotencyKeyDB: db,
+		Interval:         interval,
+	}
+}
+
+// Run purges the expired keys right away and then on every tick until the
+// context is done.
+func (p *IdempotencyKeyPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		p.tick(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *IdempotencyKeyPurger) tick(now time.Time) {
+	deleted, err := p.IdempotencyKeyDB.DeleteExpired(now)
+	if err != nil {
+		log.Println(err)
+	}
+	if deleted > 0 {
+		log.Printf("purged %d expired idempotency keys", deleted)
+	}
+}
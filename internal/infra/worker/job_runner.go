@@ -0,0 +1,268 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/exporter"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/importer"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/spreadsheet"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+	"gorm.io/gorm"
+)
+
+// ImportParams are the params of product import jobs. The spreadsheet is
+// kept in the file store under InputKey until the job finishes.
+type ImportParams struct {
+	InputKey    string           `json:"input_key"`
+	ContentType string           `json:"content_type"`
+	Mapping     importer.Mapping `json:"mapping,omitempty"`
+	Currency    string           `json:"currency"`
+	DryRun      bool             `json:"dry_run"`
+}
+
+// ExportParams are the params of product export jobs.
+type ExportParams struct {
+	Format string                 `json:"format"`
+	Filter database.ProductFilter `json:"filter"`
+}
+
+// JobRunner runs queued jobs with a pool of Workers goroutines. Workers pick
+// new jobs as soon as Notify is called, and look for queued jobs every
+// Interval in case they weren't told, such as after a restart.
+type JobRunner struct {
+	JobDB     database.JobInterface
+	ProductDB database.ProductInterface
+	Files     *storage.FileStore
+	// Storage tells the URLs of product images in NDJSON exports.
+	Storage   storage.Storage
+	Workers   int
+	Interval  time.Duration
+	Retention time.Duration
+	wake      chan struct{}
+}
+
+func NewJobRunner(
+	jobDB database.JobInterface,
+	productDB database.ProductInterface,
+	files *storage.FileStore,
+	store storage.Storage,
+	workers int,
+	interval, retention time.Duration,
+) *JobRunner {
+	return &JobRunner{
+		JobDB:     jobDB,
+		ProductDB: productDB,
+		Files:     files,
+		Storage:   store,
+		Workers:   workers,
+		Interval:  interval,
+		Retention: retention,
+		wake:      make(chan struct{}, workers),
+	}
+}
+
+// Notify wakes an idle worker up to pick a job that was just queued.
+func (r *JobRunner) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run puts the jobs interrupted by the last shutdown back in the queue and
+// runs queued jobs until the context is done, deleting the jobs that
+// finished longer than Retention ago on every tick.
+func (r *JobRunner) Run(ctx context.Context) {
+	requeued, err := r.JobDB.Requeue()
+	if err != nil {
+		log.Println(err)
+	}
+	if requeued > 0 {
+		log.Printf("requeued %d interrupted jobs", requeued)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < r.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		r.purge(time.Now())
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *JobRunner) work(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			job, err := r.JobDB.Claim()
+			if err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					log.Println(err)
+				}
+				break
+			}
+			r.process(job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// process runs a claimed job and records how it went.
+func (r *JobRunner) process(job *entity.Job) {
+	var err error
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+		if err != nil {
+			job.Fail(err.Error())
+		} else {
+			job.Succeed()
+		}
+		if err := r.JobDB.Finish(job); err != nil {
+			log.Println(err)
+		}
+	}()
+	switch job.Type {
+	case entity.JobProductImport:
+		err = r.runImport(job)
+	case entity.JobProductExport:
+		err = r.runExport(job)
+	default:
+		err = entity.ErrInvalidJobType
+	}
+}
+
+func (r *JobRunner) runImport(job *entity.Job) error {
+	var params ImportParams
+	if err := json.Unmarshal([]byte(job.Params), &params); err != nil {
+		return err
+	}
+	// The spreadsheet is no longer needed once the job finished, but is kept
+	// when it gets interrupted so it can run again.
+	defer func() {
+		if err := r.Files.Delete(params.InputKey); err != nil {
+			log.Println(err)
+		}
+	}()
+	file, err := r.Files.Open(params.InputKey)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	rows, err := spreadsheet.Read(body, params.ContentType)
+	if err != nil {
+		return err
+	}
+	options := importer.Options{
+		Mapping:  params.Mapping,
+		Currency: params.Currency,
+		DryRun:   params.DryRun,
+		Source:   entity.AuditSource{Actor: job.CreatedBy},
+		Progress: func(processed, total int) error {
+			job.Processed, job.Total = processed, total
+			return r.JobDB.UpdateProgress(job.ID.String(), processed, total)
+		},
+	}
+	report, importErr := importer.NewImporter(r.ProductDB).Import(rows, options)
+	if report == nil {
+		return importErr
+	}
+	key := "jobs/" + job.ID.String() + "/report.json"
+	err = r.Files.Write(key, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(report)
+	})
+	if err != nil {
+		return err
+	}
+	job.SetResult(key, "application/json", "import-report.json")
+	return importErr
+}
+
+func (r *JobRunner) runExport(job *entity.Job) error {
+	var params ExportParams
+	if err := json.Unmarshal([]byte(job.Params), &params); err != nil {
+		return err
+	}
+	contentType, ok := exporter.ContentTypes[params.Format]
+	if !ok {
+		return exporter.ErrInvalidFormat
+	}
+	total, err := r.ProductDB.Count(params.Filter)
+	if err != nil {
+		return err
+	}
+	job.Total = int(total)
+	if err = r.JobDB.UpdateProgress(job.ID.String(), 0, job.Total); err != nil {
+		return err
+	}
+	filename := "products-" + job.CreatedAt.UTC().Format("20060102T150405Z") + "." + params.Format
+	key := "jobs/" + job.ID.String() + "/" + filename
+	err = r.Files.Write(key, func(w io.Writer) error {
+		e, err := exporter.New(w, params.Format, r.Storage)
+		if err != nil {
+			return err
+		}
+		return exporter.Export(r.ProductDB, params.Filter, e, func(written int) error {
+			// Products created since the count are exported too.
+			job.Processed = written
+			if job.Total < written {
+				job.Total = written
+			}
+			return r.JobDB.UpdateProgress(job.ID.String(), job.Processed, job.Total)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	job.SetResult(key, contentType, filename)
+	return nil
+}
+
+func (r *JobRunner) purge(now time.Time) {
+	jobs, err := r.JobDB.Purge(now.Add(-r.Retention))
+	if err != nil {
+		log.Println(err)
+	}
+	for _, job := range jobs {
+		if job.ResultKey == "" {
+			continue
+		}
+		if err = r.Files.Delete(job.ResultKey); err != nil {
+			log.Println(err)
+		}
+	}
+	if len(jobs) > 0 {
+		log.Printf("purged %d finished jobs", len(jobs))
+	}
+}
@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/events"
+)
+
+// relayBatchSize is how many events the relay reads from the outbox at a
+// time.
+const relayBatchSize = 100
+
+// OutboxRelay publishes the events saved to the outbox to each of its
+// consumers, in the order they occurred, and deletes them Retention after
+// every consumer got them. Each consumer is relayed to on its own, so one
+// that fails only falls behind while the others keep getting events.
+type OutboxRelay struct {
+	EventDB database.EventInterface
+	// Consumers are the publishers the events are relayed to, by the name
+	// their progress is saved under.
+	Consumers map[string]events.Publisher
+	Interval  time.Duration
+	Retention time.Duration
+}
+
+func NewOutboxRelay(eventDB database.EventInterface, consumers map[string]events.Publisher, interval, retention time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		EventDB:   eventDB,
+		Consumers: consumers,
+		Interval:  interval,
+		Retention: retention,
+	}
+}
+
+// Run relays the events waiting in the outbox to each consumer right away
+// and then on every tick until the context is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for consumer, publisher := range r.Consumers {
+		wg.Add(1)
+		go func(consumer string, publisher events.Publisher) {
+			defer wg.Done()
+			r.runConsumer(ctx, consumer, publisher)
+		}(consumer, publisher)
+	}
+	defer wg.Wait()
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.tick(now)
+		}
+	}
+}
+
+func (r *OutboxRelay) runConsumer(ctx context.Context, consumer string, publisher events.Publisher) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		r.relay(ctx, consumer, publisher)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay publishes the events waiting for the consumer, batch after batch.
+func (r *OutboxRelay) relay(ctx context.Context, consumer string, publisher events.Publisher) {
+	for ctx.Err() == nil {
+		published, err := r.publish(ctx, consumer, publisher)
+		if err != nil {
+			log.Printf("relaying events to %s: %v", consumer, err)
+			return
+		}
+		if published < relayBatchSize {
+			return
+		}
+	}
+}
+
+// tick marks the events every consumer got as published and deletes the
+// ones published before the retention.
+func (r *OutboxRelay) tick(now time.Time) {
+	consumers := make([]string, 0, len(r.Consumers))
+	for consumer := range r.Consumers {
+		consumers = append(consumers, consumer)
+	}
+	if _, err := r.EventDB.Complete(consumers, now); err != nil {
+		log.Println(err)
+	}
+	deleted, err := r.EventDB.DeletePublished(now.Add(-r.Retention))
+	if err != nil {
+		log.Println(err)
+	}
+	if deleted > 0 {
+		log.Printf("purged %d published events", deleted)
+	}
+}
+
+// publish publishes a batch of events to the consumer, returning how many
+// it published. It stops at the first event that fails, which is retried
+// on the next tick so events are never published out of order.
+func (r *OutboxRelay) publish(ctx context.Context, consumer string, publisher events.Publisher) (int, error) {
+	pending, err := r.EventDB.FindUnpublished(consumer, relayBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pending {
+		event := &pending[i]
+		if err = publisher.Publish(ctx, event); err != nil {
+			if markErr := r.EventDB.MarkFailed(consumer, event.ID.String(), err.Error()); markErr != nil {
+				log.Println(markErr)
+			}
+			return i, err
+		}
+		if err = r.EventDB.MarkPublished(consumer, event.ID.String(), time.Now()); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/events"
+	pkgEntity "github.com/Nimbo1999/go-apis-go-expert/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.Migrate(db, "BRL"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// recordingPublisher keeps the ids of the events it publishes, failing
+// with err while it is set: on every event, or only on those of failOn
+// when that is set too.
+type recordingPublisher struct {
+	err       error
+	failOn    string
+	published []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *entity.Event) error {
+	if p.err != nil && (p.failOn == "" || p.failOn == event.EntityID) {
+		return p.err
+	}
+	p.published = append(p.published, event.EntityID)
+	return nil
+}
+
+func createProducts(t *testing.T, db *gorm.DB, names ...string) []string {
+	productDB := database.NewProduct(db)
+	ids := []string{}
+	for _, name := range names {
+		product, _ := entity.NewProduct(name, pkgEntity.Money{Amount: 1000, Currency: "BRL"})
+		assert.NoError(t, productDB.Create(product))
+		ids = append(ids, product.ID.String())
+	}
+	return ids
+}
+
+func TestOutboxRelay_Consumers(t *testing.T) {
+	db := createTestDB(t)
+	eventDB := database.NewEvent(db)
+	ids := createProducts(t, db, "Mouse", "Keyboard")
+	bus := &recordingPublisher{}
+	nats := &recordingPublisher{err: errors.New("connection refused")}
+	relay := NewOutboxRelay(eventDB, map[string]events.Publisher{"bus": bus, "nats": nats}, time.Second, time.Hour)
+
+	// A consumer that fails doesn't hold the others up, and they don't get
+	// the events again while it is retried.
+	for i := 0; i < 2; i++ {
+		relay.relay(context.Background(), "bus", bus)
+		relay.relay(context.Background(), "nats", nats)
+	}
+	assert.Equal(t, ids, bus.published)
+	assert.Empty(t, nats.published)
+	var failed entity.EventPublication
+	assert.NoError(t, db.First(&failed, "consumer = ?", "nats").Error)
+	assert.Equal(t, 2, failed.Attempts)
+	assert.Equal(t, "connection refused", failed.LastError)
+
+	relay.tick(time.Now())
+	pending, _ := eventDB.FindUnpublished("webhooks", 10)
+	assert.Len(t, pending, 2)
+
+	nats.err = nil
+	relay.relay(context.Background(), "nats", nats)
+	assert.Equal(t, ids, nats.published)
+	assert.Equal(t, ids, bus.published)
+	relay.tick(time.Now())
+	pending, _ = eventDB.FindUnpublished("webhooks", 10)
+	assert.Empty(t, pending)
+}
+
+func TestOutboxRelay_Order(t *testing.T) {
+	db := createTestDB(t)
+	names := make([]string, relayBatchSize+20)
+	for i := range names {
+		names[i] = fmt.Sprintf("Product %d", i)
+	}
+	ids := createProducts(t, db, names...)
+	bus := &recordingPublisher{}
+	relay := NewOutboxRelay(database.NewEvent(db), map[string]events.Publisher{"bus": bus}, time.Second, time.Hour)
+
+	// Every batch is relayed in one go, in the order the events occurred.
+	relay.relay(context.Background(), "bus", bus)
+	assert.Equal(t, ids, bus.published)
+}
+
+func TestOutboxRelay_StopsAtFailure(t *testing.T) {
+	db := createTestDB(t)
+	ids := createProducts(t, db, "Mouse", "Keyboard", "Monitor")
+	bus := &recordingPublisher{err: errors.New("connection refused"), failOn: ids[1]}
+	relay := NewOutboxRelay(database.NewEvent(db), map[string]events.Publisher{"bus": bus}, time.Second, time.Hour)
+
+	// The events after the one that failed wait for it, so they are never
+	// published out of order.
+	relay.relay(context.Background(), "bus", bus)
+	assert.Equal(t, ids[:1], bus.published)
+	relay.relay(context.Background(), "bus", bus)
+	assert.Equal(t, ids[:1], bus.published)
+
+	bus.err = nil
+	relay.relay(context.Background(), "bus", bus)
+	assert.Equal(t, ids, bus.published)
+}
+
+func TestOutboxRelay_Retention(t *testing.T) {
+	db := createTestDB(t)
+	eventDB := database.NewEvent(db)
+	createProducts(t, db, "Mouse", "Keyboard")
+	bus := &recordingPublisher{}
+	nats := &recordingPublisher{}
+	relay := NewOutboxRelay(eventDB, map[string]events.Publisher{"bus": bus, "nats": nats}, time.Second, time.Hour)
+	countEvents := func() int64 {
+		var count int64
+		assert.NoError(t, db.Model(&entity.Event{}).Count(&count).Error)
+		return count
+	}
+
+	// Events are kept until every consumer got them.
+	now := time.Now()
+	relay.relay(context.Background(), "bus", bus)
+	relay.tick(now.Add(2 * time.Hour))
+	assert.Equal(t, int64(2), countEvents())
+
+	// And then for as long as the retention.
+	relay.relay(context.Background(), "nats", nats)
+	relay.tick(now)
+	assert.Equal(t, int64(2), countEvents())
+	relay.tick(now.Add(30 * time.Minute))
+	assert.Equal(t, int64(2), countEvents())
+	relay.tick(now.Add(2 * time.Hour))
+	assert.Equal(t, int64(0), countEvents())
+	var publications int64
+	assert.NoError(t, db.Model(&entity.EventPublication{}).Count(&publications).Error)
+	assert.Zero(t, publications)
+}
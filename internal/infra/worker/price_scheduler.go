@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+)
+
+// PriceScheduler periodically applies the scheduled prices that took effect
+// or expired.
+type PriceScheduler struct {
+	ScheduledPriceDB database.ScheduledPriceInterface
+	Interval         time.Duration
+}
+
+func NewPriceScheduler(db database.ScheduledPriceInterface, interval time.Duration) *PriceScheduler {
+	return &PriceScheduler{
+		ScheduledPriceDB: db,
+		Interval:         interval,
+	}
+}
+
+// Run applies the due scheduled prices right away and then on every tick
+// until the context is done.
+func (s *PriceScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		s.tick(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *PriceScheduler) tick(now time.Time) {
+	applied, err := s.ScheduledPriceDB.ApplyDue(now)
+	if err != nil {
+		log.Println(err)
+	}
+	if applied > 0 {
+		log.Printf("applied %d scheduled prices", applied)
+	}
+}
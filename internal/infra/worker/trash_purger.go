@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/storage"
+)
+
+// TrashPurger periodically deletes for good the products that have been in
+// the trash for longer than Retention, along with the files of their images.
+// The purges are audited as made by the system.
+type TrashPurger struct {
+	ProductDB database.ProductInterface
+	Storage   storage.Storage
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewTrashPurger(db database.ProductInterface, store storage.Storage, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{
+		ProductDB: db.As(entity.AuditSource{Actor: entity.AuditSystem}),
+		Storage:   store,
+		Retention: retention,
+		Interval:  interval,
+	}
+}
+
+// Run purges the expired products right away and then on every tick until
+// the context is done.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		p.tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) tick(ctx context.Context, now time.Time) {
+	products, err := p.ProductDB.Purge(now.Add(-p.Retention))
+	if err != nil {
+		log.Println(err)
+	}
+	for _, product := range products {
+		for _, image := range product.Images {
+			if err = storage.DeleteAll(ctx, p.Storage, image.Keys()); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	if len(products) > 0 {
+		log.Printf("purged %d deleted products", len(products))
+	}
+}
@@ -0,0 +1,332 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+const (
+	// webhookBatchSize is how many due deliveries are read at a time, and
+	// webhookConcurrency how many of them are sent at once.
+	webhookBatchSize   = 100
+	webhookConcurrency = 8
+	// webhookResponseLimit is how much of the response to each attempt is
+	// kept in the delivery log, in bytes.
+	webhookResponseLimit = 1 << 10
+)
+
+var errNonPublicAddress = errors.New("webhooks can only be sent to public addresses")
+
+// nonPublicNetworks are the special-purpose ranges of global unicast
+// addresses that aren't reachable on the internet or, like NAT64 and 6to4,
+// embed an IPv4 address that may be a private one.
+var nonPublicNetworks = parseCIDRs(
+	"0.0.0.0/8",       // this network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved
+	"::/96",           // IPv4-compatible
+	"64:ff9b::/96",    // NAT64
+	"64:ff9b:1::/48",  // local NAT64
+	"100::/64",        // discard
+	"2001::/23",       // IETF protocol assignments, Teredo included
+	"2001:db8::/32",   // documentation
+	"2002::/16",       // 6to4
+)
+
+// WebhookSender sends events to the webhooks subscribed to them. As a
+// publisher it queues a delivery of each event for every webhook, and it
+// sends the deliveries that are due as soon as Notify is called and every
+// Interval, retrying the failed ones with exponential backoff until
+// MaxAttempts were made.
+type WebhookSender struct {
+	WebhookDB  database.WebhookInterface
+	DeliveryDB database.WebhookDeliveryInterface
+	Client     *http.Client
+	// MaxAttempts is how many times a delivery is tried before it fails
+	// for good, waiting Backoff after the first failure and twice as long
+	// after each of the next ones, up to MaxBackoff.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Interval    time.Duration
+	Retention   time.Duration
+	wake        chan struct{}
+}
+
+func NewWebhookSender(
+	webhookDB database.WebhookInterface,
+	deliveryDB database.WebhookDeliveryInterface,
+	timeout time.Duration,
+	maxAttempts int,
+	backoff, maxBackoff time.Duration,
+	interval, retention time.Duration,
+	allowPrivateNetworks bool,
+) *WebhookSender {
+	return &WebhookSender{
+		WebhookDB:  webhookDB,
+		DeliveryDB: deliveryDB,
+		Client: &http.Client{
+			Timeout:   timeout,
+			Transport: webhookTransport(allowPrivateNetworks),
+			// Receivers are expected to answer at the URL they were given,
+			// so redirects count as failed attempts.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		MaxBackoff:  maxBackoff,
+		Interval:    interval,
+		Retention:   retention,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// webhookTransport connects to the addresses webhook URLs resolve to, unless
+// they are on the internal network and allowPrivateNetworks is false. The
+// address is checked once resolved, when dialing, so names resolving to
+// internal addresses are refused too. Proxies aren't used, since they would
+// connect on the behalf of the sender without that check.
+func webhookTransport(allowPrivateNetworks bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", errNonPublicAddress, host)
+			}
+			return nil
+		}
+	}
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// isPublicIP tells whether the address is a global unicast one outside the
+// private and other special-purpose ranges.
+func isPublicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// Publish queues the event to be sent to every webhook subscribed to it.
+func (s *WebhookSender) Publish(ctx context.Context, event *entity.Event) error {
+	webhooks, err := s.WebhookDB.FindSubscribed(event.Type)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	deliveries := make([]*entity.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = entity.NewWebhookDelivery(webhook.ID, event.ID.String(), event.Type, body)
+	}
+	if err = s.DeliveryDB.Enqueue(deliveries); err != nil {
+		return err
+	}
+	s.Notify()
+	return nil
+}
+
+// Ping queues a webhook.ping event to be sent to the webhook, whatever the
+// event types it is subscribed to.
+func (s *WebhookSender) Ping(webhook *entity.Webhook) (*entity.WebhookDelivery, error) {
+	event := webhook.NewPingEvent()
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	delivery := entity.NewWebhookDelivery(webhook.ID, event.ID.String(), event.Type, body)
+	if err = s.DeliveryDB.Enqueue([]*entity.WebhookDelivery{delivery}); err != nil {
+		return nil, err
+	}
+	s.Notify()
+	return delivery, nil
+}
+
+// Notify wakes the sender up to send a delivery that was just queued.
+func (s *WebhookSender) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends the due deliveries right away and then whenever it is notified
+// or on every tick until the context is done, deleting the deliveries that
+// finished longer than Retention ago.
+func (s *WebhookSender) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookSender) tick(ctx context.Context, now time.Time) {
+	webhooks := map[string]*entity.Webhook{}
+	for ctx.Err() == nil {
+		due, err := s.DeliveryDB.FindDue(time.Now(), webhookBatchSize)
+		if err != nil {
+			log.Println(err)
+			break
+		}
+		s.sendAll(ctx, due, webhooks)
+		if len(due) < webhookBatchSize {
+			break
+		}
+	}
+	deleted, err := s.DeliveryDB.DeleteFinished(now.Add(-s.Retention))
+	if err != nil {
+		log.Println(err)
+	}
+	if deleted > 0 {
+		log.Printf("purged %d finished webhook deliveries", deleted)
+	}
+}
+
+// sendAll sends a batch of deliveries, webhookConcurrency at a time,
+// looking their webhooks up once per tick.
+func (s *WebhookSender) sendAll(ctx context.Context, deliveries []entity.WebhookDelivery, webhooks map[string]*entity.Webhook) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, webhookConcurrency)
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		id := delivery.WebhookID.String()
+		webhook, ok := webhooks[id]
+		if !ok {
+			var err error
+			webhook, err = s.WebhookDB.FindById(id)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Println(err)
+				continue
+			}
+			webhooks[id] = webhook
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			s.send(ctx, webhook, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+// send makes an attempt to deliver to the webhook, or fails the delivery
+// for good when the webhook is gone or was deactivated, and records how it
+// went. Deliveries failed that way can be replayed once the webhook is
+// active again.
+func (s *WebhookSender) send(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) {
+	now := time.Now()
+	switch {
+	case webhook == nil:
+		delivery.Fail(0, "", "webhook was deleted", now, 0)
+	case !webhook.Active:
+		delivery.Fail(0, "", "webhook is inactive", now, 0)
+	default:
+		status, body, err := s.post(ctx, webhook, delivery, now)
+		switch {
+		case err != nil:
+			delivery.Fail(0, "", err.Error(), now, s.backoff(delivery))
+		case status >= 200 && status < 300:
+			delivery.Succeed(status, body, now)
+		default:
+			delivery.Fail(status, body, "", now, s.backoff(delivery))
+		}
+	}
+	if err := s.DeliveryDB.Update(delivery); err != nil {
+		log.Println(err)
+	}
+}
+
+// backoff tells how long to wait before trying the delivery again after
+// the attempt being recorded.
+func (s *WebhookSender) backoff(delivery *entity.WebhookDelivery) time.Duration {
+	return entity.WebhookBackoff(delivery.Attempts+1, s.MaxAttempts, s.Backoff, s.MaxBackoff)
+}
+
+// post sends the delivery signed with the secret of the webhook, returning
+// the status and the start of the body of the response.
+func (s *WebhookSender) post(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery, now time.Time) (int, string, error) {
+	body := []byte(delivery.Body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-apis-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", delivery.ID.String())
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("X-Webhook-Signature", webhook.Sign(now, body))
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	response, err := io.ReadAll(io.LimitReader(res.Body, webhookResponseLimit))
+	if err != nil {
+		return 0, "", err
+	}
+	return res.StatusCode, string(response), nil
+}
@@ -0,0 +1,208 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nimbo1999/go-apis-go-expert/internal/entity"
+	"github.com/Nimbo1999/go-apis-go-expert/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookReceiver answers with the statuses in turn, then with 200, checking
+// the signature of every request.
+func webhookReceiver(t *testing.T, secret string, statuses ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		assert.NoError(t, err)
+		webhook := entity.Webhook{Secret: secret}
+		assert.Equal(t, webhook.Sign(time.Unix(timestamp, 0), body), r.Header.Get("X-Webhook-Signature"))
+		attempt := int(atomic.AddInt32(&requests, 1))
+		if attempt <= len(statuses) {
+			w.WriteHeader(statuses[attempt-1])
+		}
+		w.Write([]byte("received " + r.Header.Get("X-Webhook-Event")))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func createWebhookSender(t *testing.T, url string, maxAttempts int, allowPrivateNetworks bool) (*WebhookSender, *entity.Webhook) {
+	db := createTestDB(t)
+	webhookDB := database.NewWebhook(db)
+	webhook, err := entity.NewWebhook(url, []string{entity.EventProductCreated}, "0123456789abcdef", "admin")
+	assert.NoError(t, err)
+	assert.NoError(t, webhookDB.Create(webhook))
+	sender := NewWebhookSender(webhookDB, database.NewWebhookDelivery(db), time.Second, maxAttempts,
+		time.Millisecond, 4*time.Millisecond, time.Second, time.Hour, allowPrivateNetworks)
+	return sender, webhook
+}
+
+func publishEvent(t *testing.T, sender *WebhookSender, eventType string) *entity.WebhookDelivery {
+	event, _ := entity.NewEvent(eventType, "product-1", map[string]string{"name": "Mouse"})
+	assert.NoError(t, sender.Publish(context.Background(), event))
+	deliveries, err := sender.DeliveryDB.FindDue(time.Now(), 10)
+	assert.NoError(t, err)
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return &deliveries[0]
+}
+
+func findDelivery(t *testing.T, sender *WebhookSender, webhook *entity.Webhook, id string) *entity.WebhookDelivery {
+	delivery, err := sender.DeliveryDB.FindById(webhook.ID.String(), id)
+	assert.NoError(t, err)
+	return delivery
+}
+
+func TestWebhookSender_Retry(t *testing.T) {
+	server, requests := webhookReceiver(t, "0123456789abcdef", http.StatusInternalServerError, http.StatusMovedPermanently)
+	sender, webhook := createWebhookSender(t, server.URL, 5, true)
+	assert.Nil(t, publishEvent(t, sender, entity.EventUserRegistered))
+	delivery := publishEvent(t, sender, entity.EventProductCreated)
+
+	sender.tick(context.Background(), time.Now())
+	delivery = findDelivery(t, sender, webhook, delivery.ID.String())
+	assert.Equal(t, entity.DeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+	assert.NotNil(t, delivery.NextAttemptAt)
+
+	// Redirects aren't followed and count as failures.
+	time.Sleep(5 * time.Millisecond)
+	sender.tick(context.Background(), time.Now())
+	delivery = findDelivery(t, sender, webhook, delivery.ID.String())
+	assert.Equal(t, entity.DeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusMovedPermanently, delivery.ResponseStatus)
+
+	time.Sleep(5 * time.Millisecond)
+	sender.tick(context.Background(), time.Now())
+	delivery = findDelivery(t, sender, webhook, delivery.ID.String())
+	assert.Equal(t, entity.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, "received product.created", delivery.ResponseBody)
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+
+	// Deliveries are deleted once finished for longer than the retention.
+	sender.tick(context.Background(), time.Now().Add(2*time.Hour))
+	_, err := sender.DeliveryDB.FindById(webhook.ID.String(), delivery.ID.String())
+	assert.Error(t, err)
+}
+
+func TestWebhookSender_MaxAttempts(t *testing.T) {
+	server, requests := webhookReceiver(t, "0123456789abcdef", http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	sender, webhook := createWebhookSender(t, server.URL, 2, true)
+	delivery := publishEvent(t, sender, entity.EventProductCreated)
+
+	for i := 0; i < 3; i++ {
+		sender.tick(context.Background(), time.Now())
+		time.Sleep(5 * time.Millisecond)
+	}
+	delivery = findDelivery(t, sender, webhook, delivery.ID.String())
+	assert.Equal(t, entity.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestWebhookSender_InactiveAndDeleted(t *testing.T) {
+	server, requests := webhookReceiver(t, "0123456789abcdef")
+	sender, webhook := createWebhookSender(t, server.URL, 5, true)
+	delivery := publishEvent(t, sender, entity.EventProductCreated)
+	webhook.Active = false
+	assert.NoError(t, sender.WebhookDB.Update(webhook))
+
+	sender.tick(context.Background(), time.Now())
+	delivery = findDelivery(t, sender, webhook, delivery.ID.String())
+	assert.Equal(t, entity.DeliveryFailed, delivery.Status)
+	assert.Equal(t, "webhook is inactive", delivery.Error)
+
+	webhook.Active = true
+	assert.NoError(t, sender.WebhookDB.Update(webhook))
+	assert.NoError(t, delivery.Replay())
+	assert.NoError(t, sender.DeliveryDB.Update(delivery))
+	// The webhook is deleted after the delivery was read, before it is sent.
+	due, _ := sender.DeliveryDB.FindDue(time.Now(), 10)
+	assert.Len(t, due, 1)
+	assert.NoError(t, sender.WebhookDB.Delete(webhook.ID.String()))
+	sender.sendAll(context.Background(), due, map[string]*entity.Webhook{})
+	assert.Equal(t, entity.DeliveryFailed, due[0].Status)
+	assert.Equal(t, "webhook was deleted", due[0].Error)
+	assert.Zero(t, atomic.LoadInt32(requests))
+}
+
+func TestWebhookSender_Ping(t *testing.T) {
+	server, requests := webhookReceiver(t, "0123456789abcdef")
+	sender, webhook := createWebhookSender(t, server.URL, 5, true)
+
+	delivery, err := sender.Ping(webhook)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.EventWebhookPing, delivery.EventType)
+	sender.tick(context.Background(), time.Now())
+	delivery = findDelivery(t, sender, webhook, delivery.ID.String())
+	assert.Equal(t, entity.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, "received webhook.ping", delivery.ResponseBody)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestWebhookSender_PrivateNetworks(t *testing.T) {
+	server, requests := webhookReceiver(t, "0123456789abcdef")
+	sender, webhook := createWebhookSender(t, server.URL, 1, false)
+	delivery := publishEvent(t, sender, entity.EventProductCreated)
+
+	sender.tick(context.Background(), time.Now())
+	delivery = findDelivery(t, sender, webhook, delivery.ID.String())
+	assert.Equal(t, entity.DeliveryFailed, delivery.Status)
+	assert.Contains(t, delivery.Error, errNonPublicAddress.Error())
+	assert.Zero(t, atomic.LoadInt32(requests))
+}
+
+func TestIsPublicIP(t *testing.T) {
+	refused := []string{
+		"127.0.0.1",
+		"10.0.0.1",
+		"172.16.0.1",
+		"192.168.1.1",
+		"169.254.169.254",
+		"0.0.0.0",
+		"255.255.255.255",
+		"224.0.0.1",
+		"100.64.0.1",
+		"100.127.255.254",
+		"198.18.0.1",
+		"198.19.255.254",
+		"192.0.0.8",
+		"192.0.2.1",
+		"203.0.113.1",
+		"240.0.0.1",
+		"::1",
+		"::",
+		"fe80::1",
+		"fc00::1",
+		"ff02::1",
+		"::ffff:10.0.0.1",
+		"::10.0.0.1",
+		"64:ff9b::a00:1",
+		"64:ff9b::7f00:1",
+		"64:ff9b:1::a00:1",
+		"100::1",
+		"2001::a00:1",
+		"2001:db8::1",
+		"2002:a00:1::1",
+	}
+	for _, address := range refused {
+		assert.False(t, isPublicIP(net.ParseIP(address)), address)
+	}
+	for _, address := range []string{"8.8.8.8", "100.128.0.1", "198.20.0.1", "::ffff:8.8.8.8", "2606:4700::1111"} {
+		assert.True(t, isPublicIP(net.ParseIP(address)), address)
+	}
+}
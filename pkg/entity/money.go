@@ -0,0 +1,161 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidCurrency = errors.New("invalid ISO 4217 currency code")
+	ErrInvalidAmount   = errors.New("invalid amount")
+)
+
+// Money is an exact amount of a currency, stored as an integer number of
+// minor units (cents for BRL, USD or EUR). In JSON the amount is encoded as
+// a decimal string, e.g. {"amount": "10.99", "currency": "BRL"}.
+type Money struct {
+	Amount   int64  `json:"amount" swaggertype:"string" example:"10.99"`
+	Currency string `json:"currency" example:"BRL"`
+}
+
+// NewMoney builds an amount from its minor units.
+func NewMoney(amount int64, currency string) (Money, error) {
+	if !IsValidCurrency(currency) {
+		return Money{}, ErrInvalidCurrency
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// ParseMoney parses a decimal amount such as "10.99" or "-3". Amounts with
+// more decimal places than the currency allows are rejected instead of
+// being rounded.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, ErrInvalidCurrency
+	}
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+	units, fraction, hasSeparator := strings.Cut(amount, ".")
+	if units == "" || (hasSeparator && fraction == "") || len(fraction) > exponent {
+		return Money{}, ErrInvalidAmount
+	}
+	digits := units + fraction + strings.Repeat("0", exponent-len(fraction))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Money{}, ErrInvalidAmount
+		}
+	}
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Decimal formats the amount with the decimal places of its currency.
+func (m Money) Decimal() string {
+	exponent, _ := CurrencyExponent(m.Currency)
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// Convert multiplies the amount by rate, giving an amount of currency rounded
+// half away from zero to its minor unit.
+func (m Money) Convert(currency string, rate *big.Rat) (Money, error) {
+	to, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, ErrInvalidCurrency
+	}
+	from, ok := CurrencyExponent(m.Currency)
+	if !ok {
+		return Money{}, ErrInvalidCurrency
+	}
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(to-from))), nil))
+	if to > from {
+		value.Mul(value, scale)
+	} else {
+		value.Quo(value, scale)
+	}
+	numerator := new(big.Int).Abs(value.Num())
+	quotient, remainder := new(big.Int).QuoRem(numerator, value.Denom(), new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(value.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	if !quotient.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+	return Money{Amount: quotient.Int64(), Currency: currency}, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Validate checks the currency code of the amount.
+func (m Money) Validate() error {
+	if !IsValidCurrency(m.Currency) {
+		return ErrInvalidCurrency
+	}
+	return nil
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value moneyJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(value.Amount, strings.ToUpper(value.Currency))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
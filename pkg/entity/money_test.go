@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	for _, tc := range []struct {
+		amount   string
+		currency string
+		minor    int64
+	}{
+		{"10.99", "BRL", 1099},
+		{"10.9", "BRL", 1090},
+		{"10", "USD", 1000},
+		{"0.01", "EUR", 1},
+		{"-3.50", "EUR", -350},
+		{"1500", "JPY", 1500},
+		{"1.234", "KWD", 1234},
+	} {
+		m, err := ParseMoney(tc.amount, tc.currency)
+		assert.Nil(t, err, tc.amount)
+		assert.Equal(t, Money{Amount: tc.minor, Currency: tc.currency}, m)
+	}
+}
+
+func TestParseMoney_Invalid(t *testing.T) {
+	_, err := ParseMoney("10.99", "XYZ")
+	assert.Equal(t, ErrInvalidCurrency, err)
+	_, err = ParseMoney("10.99", "brl")
+	assert.Equal(t, ErrInvalidCurrency, err)
+	for _, amount := range []string{"", "abc", "1.999", "1.2.3", ".5", "1e3", "10.", "1,50", "1.-5", "99999999999999999999"} {
+		_, err = ParseMoney(amount, "BRL")
+		assert.Equal(t, ErrInvalidAmount, err, amount)
+	}
+	_, err = ParseMoney("10.5", "JPY")
+	assert.Equal(t, ErrInvalidAmount, err)
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	assert.Equal(t, "10.99", Money{Amount: 1099, Currency: "BRL"}.Decimal())
+	assert.Equal(t, "0.05", Money{Amount: 5, Currency: "USD"}.Decimal())
+	assert.Equal(t, "-0.50", Money{Amount: -50, Currency: "EUR"}.Decimal())
+	assert.Equal(t, "1500", Money{Amount: 1500, Currency: "JPY"}.Decimal())
+	assert.Equal(t, "0.001", Money{Amount: 1, Currency: "BHD"}.Decimal())
+	assert.Equal(t, "10.99 BRL", Money{Amount: 1099, Currency: "BRL"}.String())
+}
+
+func TestMoney_Convert(t *testing.T) {
+	rate, _ := new(big.Rat).SetString("0.1987")
+	m, err := Money{Amount: 1099, Currency: "BRL"}.Convert("USD", rate)
+	assert.Nil(t, err)
+	// 10.99 * 0.1987 = 2.183713
+	assert.Equal(t, Money{Amount: 218, Currency: "USD"}, m)
+
+	rate, _ = new(big.Rat).SetString("0.125")
+	m, _ = Money{Amount: 1000, Currency: "EUR"}.Convert("USD", rate)
+	assert.Equal(t, int64(125), m.Amount)
+	m, _ = Money{Amount: -1004, Currency: "EUR"}.Convert("USD", rate)
+	assert.Equal(t, int64(-126), m.Amount)
+
+	rate, _ = new(big.Rat).SetString("161.5")
+	m, _ = Money{Amount: 1099, Currency: "USD"}.Convert("JPY", rate)
+	assert.Equal(t, Money{Amount: 1775, Currency: "JPY"}, m)
+	m, _ = Money{Amount: 1775, Currency: "JPY"}.Convert("USD", new(big.Rat).Inv(rate))
+	assert.Equal(t, Money{Amount: 1099, Currency: "USD"}, m)
+
+	_, err = Money{Amount: 1099, Currency: "BRL"}.Convert("XYZ", rate)
+	assert.Equal(t, ErrInvalidCurrency, err)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(Money{Amount: 1099, Currency: "BRL"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"amount":"10.99","currency":"BRL"}`, string(data))
+
+	var m Money
+	assert.Nil(t, json.Unmarshal([]byte(`{"amount":"4.5","currency":"usd"}`), &m))
+	assert.Equal(t, Money{Amount: 450, Currency: "USD"}, m)
+
+	assert.Equal(t, ErrInvalidCurrency, json.Unmarshal([]byte(`{"amount":"4.5","currency":"ABC"}`), &m))
+	assert.Equal(t, ErrInvalidAmount, json.Unmarshal([]byte(`{"amount":"4.555","currency":"USD"}`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":4.5,"currency":"USD"}`), &m))
+}